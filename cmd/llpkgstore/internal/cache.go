@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/goplus/llpkgstore/upstream/cache"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the install cache",
+	Long: `Manage the cache of installed upstream packages used by generate, verification, release and install.
+
+Installs are cached by installer, installer config, package, linkage, platform and lockfile,
+under --cache-dir, {LLGOCACHE}/llpkgstore/install by default.`,
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the cached installs",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheLsCmd,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove unused cached installs",
+	Long: `Remove the cached installs which haven't been used for --older-than,
+as well as unreadable entries and the leftovers of interrupted installs.`,
+	Args: cobra.NoArgs,
+	RunE: runCachePruneCmd,
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the cached installs against their checksums",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheVerifyCmd,
+}
+
+// installCache returns the cache set up by the --cache-dir and --no-cache flags, nil if disabled.
+func installCache(cmd *cobra.Command) (*cache.Cache, error) {
+	if noCache, _ := cmd.Flags().GetBool("no-cache"); noCache {
+		return nil, nil
+	}
+	root, _ := cmd.Flags().GetString("cache-dir")
+	if root == "" {
+		var err error
+		if root, err = cache.DefaultRoot(); err != nil {
+			return nil, err
+		}
+	}
+	return cache.New(root), nil
+}
+
+// managedCache is installCache for the cache subcommands, which need a cache.
+func managedCache(cmd *cobra.Command) (*cache.Cache, error) {
+	c, err := installCache(cmd)
+	if err == nil && c == nil {
+		err = fmt.Errorf("the cache is disabled by --no-cache")
+	}
+	return c, err
+}
+
+func runCacheLsCmd(cmd *cobra.Command, _ []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	c, err := managedCache(cmd)
+	if err != nil {
+		return err
+	}
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		type jsonEntry struct {
+			Hash string `json:"hash"`
+			*cache.Entry
+			LastUsed time.Time `json:"lastUsed"`
+			Error    string    `json:"error,omitempty"`
+		}
+		out := make([]jsonEntry, 0, len(entries))
+		for _, entry := range entries {
+			e := jsonEntry{Hash: entry.Hash, Entry: entry, LastUsed: entry.LastUsed}
+			if entry.Err != nil {
+				e.Error = entry.Err.Error()
+			}
+			out = append(out, e)
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HASH\tINSTALLER\tPACKAGE\tLINKAGE\tPLATFORM\tSIZE\tLAST USED")
+	for _, entry := range entries {
+		if entry.Err != nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\t%v\n", entry.Hash[:min(12, len(entry.Hash))], entry.Err)
+			continue
+		}
+		key := entry.Key
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Hash[:12], key.Installer,
+			key.Package.Name+"/"+key.Package.Version, key.Package.Linkage, key.Platform,
+			formatSize(entry.Size), entry.LastUsed.Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+// formatSize formats a size in bytes with a binary unit.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func runCachePruneCmd(cmd *cobra.Command, _ []string) error {
+	olderThan, _ := cmd.Flags().GetDuration("older-than")
+	all, _ := cmd.Flags().GetBool("all")
+	c, err := managedCache(cmd)
+	if err != nil {
+		return err
+	}
+	before := time.Now().Add(-olderThan)
+	if all {
+		before = time.Now()
+	}
+	removed, err := c.Prune(cmd.Context(), before)
+	var size int64
+	for _, entry := range removed {
+		size += entry.Size
+		cmd.Println("Removed", entry.Hash)
+	}
+	cmd.Printf("Removed %d entries, %s\n", len(removed), formatSize(size))
+	return err
+}
+
+func runCacheVerifyCmd(cmd *cobra.Command, _ []string) error {
+	remove, _ := cmd.Flags().GetBool("remove")
+	c, err := managedCache(cmd)
+	if err != nil {
+		return err
+	}
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+	corrupt := 0
+	for _, entry := range entries {
+		err := c.Verify(cmd.Context(), entry)
+		if err == nil {
+			continue
+		}
+		corrupt++
+		cmd.PrintErrln(err)
+		if remove {
+			if err := c.Remove(cmd.Context(), entry.Hash); err != nil {
+				return err
+			}
+			cmd.PrintErrln("Removed", entry.Hash)
+		}
+	}
+	if corrupt > 0 && !remove {
+		return fmt.Errorf("%d of %d entries are corrupt, remove them with --remove", corrupt, len(entries))
+	}
+	cmd.Printf("Verified %d entries\n", len(entries))
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("cache-dir", "", "Directory of the install cache (default {LLGOCACHE}/llpkgstore/install)")
+	rootCmd.PersistentFlags().Bool("no-cache", false, "Always install packages from the upstream")
+
+	cacheLsCmd.Flags().Bool("json", false, "Print the entries as JSON")
+	cachePruneCmd.Flags().Duration("older-than", 30*24*time.Hour, "Remove the entries unused for this duration")
+	cachePruneCmd.Flags().Bool("all", false, "Remove every entry")
+	cacheVerifyCmd.Flags().Bool("remove", false, "Remove the corrupt entries")
+
+	cacheCmd.AddCommand(cacheLsCmd, cachePruneCmd, cacheVerifyCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/goplus/llpkgstore/config"
+	"github.com/goplus/llpkgstore/internal/textdiff"
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check and migrate llpkg.cfg files",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [dir...]",
+	Short: "Validate the llpkg.cfg of packages",
+	Long: `Strictly parse and validate the llpkg.cfg in each dir, the current dir by default.
+
+Unknown fields are reported with their line and column. The package version must be a stable
+semantic version, and the installer config must only use the keys supported by the installer.
+If llcppg.cfg exists, its name must be the package name, and it must list headers.`,
+	RunE: runConfigValidateCmd,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of llpkg.cfg",
+	Long: `Print the JSON Schema of llpkg.cfg, which is published as docs/llpkg.schema.json.
+Editors can use it by adding "$schema" to llpkg.cfg.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigSchemaCmd,
+}
+
+func runConfigValidateCmd(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{currentDir()}
+	}
+	invalid := 0
+	for _, dir := range args {
+		path := filepath.Join(dir, LLGOModuleIdentifyFile)
+		cfg, err := config.ParseLLPkgConfig(path)
+		if err == nil {
+			err = errors.Join(config.ValidateLLPkgConfig(cfg), validateLLCppgConfig(dir, cfg))
+		}
+		if err != nil {
+			invalid++
+			// parse errors already point at path:line:column.
+			var parseErr *config.ParseError
+			if errors.As(err, &parseErr) {
+				cmd.PrintErrln(parseErr)
+				continue
+			}
+			for _, err := range unwrapJoined(err) {
+				cmd.PrintErrf("%s: %v\n", path, err)
+			}
+			continue
+		}
+		cmd.Println(path + ": ok")
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d configs are invalid", invalid, len(args))
+	}
+	return nil
+}
+
+// validateLLCppgConfig validates the llcppg.cfg in dir against cfg if it exists.
+func validateLLCppgConfig(dir string, cfg config.LLPkgConfig) error {
+	llcppgCfg, err := config.ParseLLCppgConfig(filepath.Join(dir, config.LLCppgConfigFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return config.ValidateLLCppgConfig(llcppgCfg, cfg)
+}
+
+// checkLLCppgConfig checks the llcppg.cfg in dir against cfg and what has been installed,
+// so that mistakes are reported before the long llcppg run.
+func checkLLCppgConfig(dir string, cfg config.LLPkgConfig, result *upstream.InstallResult) error {
+	llcppgCfg, err := config.ParseLLCppgConfig(filepath.Join(dir, config.LLCppgConfigFile))
+	if err != nil {
+		return err
+	}
+	return errors.Join(config.ValidateLLCppgConfig(llcppgCfg, cfg), config.CheckLLCppgInstall(llcppgCfg, result))
+}
+
+// unwrapJoined returns the errors joined by errors.Join in err, or err itself.
+func unwrapJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [dir...]",
+	Short: "Upgrade llpkg.cfg to the current schema version",
+	Long: `Upgrade the llpkg.cfg in each dir, the current dir by default, to the current schema version
+and rewrite it in place, printing the diff of every change first. With --dry-run, only the diffs are printed.
+
+llpkgstore reads older files by migrating them in memory, the files only need to be rewritten
+to stop relying on that.`,
+	RunE: runConfigMigrateCmd,
+}
+
+func runConfigMigrateCmd(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if len(args) == 0 {
+		args = []string{currentDir()}
+	}
+	for _, dir := range args {
+		path := filepath.Join(dir, LLGOModuleIdentifyFile)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		result, err := config.MigrateLLPkgConfig(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if !result.Changed() {
+			cmd.Printf("%s: up to date\n", path)
+			continue
+		}
+		cmd.Printf("%s: schema version %d to %d\n", path, result.From, config.CurrentSchemaVersion)
+		for _, applied := range result.Applied {
+			cmd.Printf("  %s\n", applied)
+		}
+		cmd.Print(textdiff.Unified(path, path+" (migrated)", string(data), string(result.Data)))
+		if dryRun {
+			continue
+		}
+		if err := os.WriteFile(path, result.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runConfigSchemaCmd(cmd *cobra.Command, _ []string) error {
+	schema, err := config.Schema()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+	return err
+}
+
+func init() {
+	configMigrateCmd.Flags().Bool("dry-run", false, "Print the diffs without rewriting the files")
+	configCmd.AddCommand(configValidateCmd, configSchemaCmd, configMigrateCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/config"
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/spf13/cobra"
+)
+
+var depsCmd = &cobra.Command{
+	Use:   "deps [LLPkgConfigFilePath]",
+	Short: "Print the dependency tree of a package",
+	Long:  `Resolve and print the transitive dependency tree of the upstream package of a cfg file.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDepsCmd,
+}
+
+func runDepsCmd(cmd *cobra.Command, args []string) error {
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+	cfg, err := config.ParseLLPkgConfig(args[0])
+	if err != nil {
+		return fmt.Errorf("parse config error: %w", err)
+	}
+	uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
+	if err != nil {
+		return err
+	}
+	defer uc.Close()
+	if err := uc.Prepare(cmd.Context()); err != nil {
+		return err
+	}
+	tree, err := uc.Dependencies(cmd.Context())
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(tree)
+	}
+	writeDependencyTree(cmd.OutOrStdout(), tree, "", "")
+	return nil
+}
+
+// dependencyLine formats a node of the dependency tree as
+// name/version#revision (pkg-config: name) [option=value ...]
+func dependencyLine(dep *upstream.Dependency) string {
+	var sb strings.Builder
+	sb.WriteString(dep.Name + "/" + dep.Version)
+	if dep.Revision != "" {
+		sb.WriteString("#" + dep.Revision)
+	}
+	if dep.PkgConfigName != "" {
+		sb.WriteString(" (pkg-config: " + dep.PkgConfigName + ")")
+	}
+	if len(dep.Options) > 0 {
+		var options []string
+		for k, v := range dep.Options {
+			options = append(options, k+"="+v)
+		}
+		slices.Sort(options)
+		sb.WriteString(" [" + strings.Join(options, " ") + "]")
+	}
+	return sb.String()
+}
+
+// writeDependencyTree writes the dependency tree in the style of the tree command.
+func writeDependencyTree(w io.Writer, dep *upstream.Dependency, prefix, childPrefix string) {
+	fmt.Fprintln(w, prefix+dependencyLine(dep))
+	for i, child := range dep.Dependencies {
+		if i == len(dep.Dependencies)-1 {
+			writeDependencyTree(w, child, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			writeDependencyTree(w, child, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+func init() {
+	depsCmd.Flags().Bool("json", false, "Print the dependency tree as JSON")
+	rootCmd.AddCommand(depsCmd)
+}
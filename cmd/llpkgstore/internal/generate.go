@@ -1,15 +1,19 @@
 package internal
 
 import (
+	"context"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/goplus/llpkgstore/config"
+	"github.com/goplus/llpkgstore/internal/actions"
 	"github.com/goplus/llpkgstore/internal/actions/file"
 	"github.com/goplus/llpkgstore/internal/actions/generator/llcppg"
 	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/internal/cmdbuilder"
+	"github.com/goplus/llpkgstore/upstream/cache"
 	"github.com/spf13/cobra"
 )
 
@@ -28,7 +32,7 @@ func currentDir() string {
 	return dir
 }
 
-func runLLCppgGenerateWithDir(dir string) {
+func runLLCppgGenerateWithDir(ctx context.Context, installCache *cache.Cache, dir string) {
 	cfg, err := config.ParseLLPkgConfig(filepath.Join(dir, LLGOModuleIdentifyFile))
 	if err != nil {
 		log.Fatalf("parse config error: %v", err)
@@ -37,6 +41,7 @@ func runLLCppgGenerateWithDir(dir string) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer uc.Close()
 	log.Printf("Start to generate %s", uc.Pkg.Name)
 
 	tempDir, err := os.MkdirTemp("", "llpkg-tool")
@@ -44,37 +49,73 @@ func runLLCppgGenerateWithDir(dir string) {
 		log.Fatal(err)
 	}
 	defer os.RemoveAll(tempDir)
-	pcName, err := uc.Installer.Install(uc.Pkg, tempDir)
+	if err := uc.Prepare(ctx); err != nil {
+		log.Fatal(err)
+	}
+	lockfile, err := uc.UseLockfileIn(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := installCache.Install(ctx, uc, lockfile, tempDir)
 	if err != nil {
 		log.Fatal(err)
 	}
 	// copy file for debugging.
-	file.CopyFilePattern(tempDir, dir, "*.pc")
+	for _, pcFile := range result.PCFiles {
+		file.CopyFile(pcFile, filepath.Join(dir, filepath.Base(pcFile)))
+	}
+	pcPath, err := dependencyPCPath(ctx, installCache, dir, cfg, tempDir, filepath.Join(tempDir, "deps"))
+	if err != nil {
+		log.Fatal(err)
+	}
 	// try llcppcfg if llcppg.cfg dones't exist
 	if _, err := os.Stat(filepath.Join(dir, "llcppg.cfg")); os.IsNotExist(err) {
-		cmd := exec.Command("llcppcfg", pcName)
+		cmd := cmdbuilder.CommandContext(ctx, "llcppcfg", result.PkgConfigName)
 		cmd.Dir = dir
-		pc.SetPath(cmd, tempDir)
+		pc.SetPath(cmd, pcPath)
 		ret, err := cmd.CombinedOutput()
 		if err != nil {
 			log.Fatalf("llcppcfg execute fail: %s", string(ret))
 		}
 	}
+	// llcppg imports the Go packages of the dependencies instead of generating their bindings.
+	if err := config.FillLLCppgDeps(filepath.Join(dir, config.LLCppgConfigFile), cfg.Dependencies); err != nil {
+		log.Fatal(err)
+	}
 
-	generator := llcppg.New(dir, cfg.Upstream.Package.Name, tempDir)
+	if err := checkLLCppgConfig(dir, cfg, result); err != nil {
+		log.Fatal(err)
+	}
+
+	generator := llcppg.New(dir, cfg.Upstream.Package.Name, pcPath)
 
 	if err := generator.Generate(dir); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func runLLCppgGenerate(_ *cobra.Command, args []string) {
-	exec.Command("conan", "profile", "detect").Run()
+// dependencyPCPath installs the llpkg dependencies of cfg, the llpkg in dir, into depsDir and returns
+// PKG_CONFIG_PATH with prefix, where the package itself is installed, followed by their directories.
+func dependencyPCPath(ctx context.Context, installCache *cache.Cache, dir string, cfg config.LLPkgConfig, prefix, depsDir string) (string, error) {
+	// llpkgs are directories of the llpkg repository.
+	depDirs, err := actions.InstallDependencies(ctx, installCache, filepath.Dir(dir), cfg, depsDir)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append([]string{prefix}, depDirs...), string(filepath.ListSeparator)), nil
+}
+
+func runLLCppgGenerate(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	installCache, err := installCache(cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	path := currentDir()
 	// by default, use current dir
 	if len(args) == 0 {
-		runLLCppgGenerateWithDir(path)
+		runLLCppgGenerateWithDir(ctx, installCache, path)
 		return
 	}
 	for _, argPath := range args {
@@ -82,7 +123,7 @@ func runLLCppgGenerate(_ *cobra.Command, args []string) {
 		if err != nil {
 			continue
 		}
-		runLLCppgGenerateWithDir(absPath)
+		runLLCppgGenerateWithDir(ctx, installCache, absPath)
 	}
 
 }
@@ -1,9 +1,12 @@
 package internal
 
 import (
+	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/goplus/llpkgstore/config"
+	"github.com/goplus/llpkgstore/upstream"
 	"github.com/spf13/cobra"
 )
 
@@ -11,9 +14,13 @@ import (
 var installCmd = &cobra.Command{
 	Use:   "install [LLPkgConfigFilePath]",
 	Short: "Manually install a package",
-	Long:  `Manually install a package from cfg file.`,
-	Args:  cobra.ExactArgs(1),
-	Run:   manuallyInstall,
+	Long: `Manually install a package from cfg file.
+
+With --platform, the overrides of upstream.platforms for that GOOS/GOARCH are applied
+instead of those of the host, e.g. to check the config of linux/arm64 on linux/amd64.
+The installer itself still runs on the host.`,
+	Args: cobra.ExactArgs(1),
+	Run:  manuallyInstall,
 }
 
 func manuallyInstall(cmd *cobra.Command, args []string) {
@@ -28,16 +35,60 @@ func manuallyInstall(cmd *cobra.Command, args []string) {
 		cmd.PrintErrln("Error parsing LLPkgConfig:", err)
 		return
 	}
-	upstream, err := config.NewUpstreamFromConfig(LLPkgConfig.Upstream)
+	platform, err := targetPlatform(cmd)
+	if err != nil {
+		cmd.PrintErrln(err)
+		return
+	}
+	upstream, err := config.NewUpstreamForPlatform(LLPkgConfig.Upstream, platform)
+	if err != nil {
+		cmd.PrintErrln(err)
+		return
+	}
+	defer upstream.Close()
+	if err := upstream.Prepare(cmd.Context()); err != nil {
+		cmd.PrintErrln("Error preparing installer:", err)
+		return
+	}
+	// the lockfile is locked for the host, it doesn't pin a package overridden for platform.
+	shared, err := LLPkgConfig.Upstream.SharesLockfile(platform)
 	if err != nil {
 		cmd.PrintErrln(err)
 		return
 	}
-	upstream.Installer.Install(upstream.Pkg, output)
+	var lockfile string
+	if shared {
+		if lockfile, err = upstream.UseLockfileIn(filepath.Dir(cfgPath)); err != nil {
+			cmd.PrintErrln("Error using lockfile:", err)
+			return
+		}
+	}
+	installCache, err := installCache(cmd)
+	if err != nil {
+		cmd.PrintErrln(err)
+		return
+	}
+	if _, err := installCache.Install(cmd.Context(), upstream, lockfile, output); err != nil {
+		cmd.PrintErrln("Error installing package:", err)
+	}
+}
+
+// targetPlatform returns the platform selected by the --platform flag, the host if it's empty.
+func targetPlatform(cmd *cobra.Command) (upstream.Platform, error) {
+	flag, _ := cmd.Flags().GetString("platform")
+	if flag == "" {
+		return upstream.HostPlatform(), nil
+	}
+	platform, err := upstream.ParsePlatform(flag)
+	if err == nil && platform.GOARCH == "" {
+		err = fmt.Errorf("invalid platform %q: must be GOOS/GOARCH", flag)
+	}
+	return platform, err
 }
 
 func init() {
 	installCmd.Flags().StringP("output", "o", "", "Path to the output file")
+	installCmd.Flags().String("platform", "", "GOOS/GOARCH to apply the overrides of (default the host)")
 	installCmd.MarkFlagRequired("output")
 	rootCmd.AddCommand(installCmd)
 }
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/goplus/llpkgstore/config"
+	"github.com/spf13/cobra"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock [dir...]",
+	Short: "Create or refresh the lockfile of packages",
+	Long: `Resolve the upstream package of the llpkg.cfg in each dir, the current dir by default,
+and write the lockfile of its installer, e.g. conan.lock, next to llpkg.cfg.
+PRs are checked against the lockfile, so it must be refreshed whenever llpkg.cfg changes.`,
+	RunE: runLockCmd,
+}
+
+func runLockCmd(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{currentDir()}
+	}
+	for _, dir := range args {
+		cfg, err := config.ParseLLPkgConfig(filepath.Join(dir, LLGOModuleIdentifyFile))
+		if err != nil {
+			return fmt.Errorf("parse config error: %w", err)
+		}
+		uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
+		if err != nil {
+			return err
+		}
+		var path string
+		if err = uc.Prepare(cmd.Context()); err == nil {
+			path, err = uc.Lock(cmd.Context(), dir)
+		}
+		uc.Close()
+		if err != nil {
+			return err
+		}
+		cmd.Println("Locked", uc.Pkg.Name+"/"+uc.Pkg.Version, "in", path)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+}
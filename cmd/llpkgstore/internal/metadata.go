@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/goplus/llpkgstore/internal/actions"
+	"github.com/goplus/llpkgstore/metadata"
+	"github.com/spf13/cobra"
+)
+
+var metadataCmd = &cobra.Command{
+	Use:   "metadata",
+	Short: "Manage llpkgstore.json",
+}
+
+var metadataConvertCmd = &cobra.Command{
+	Use:   "convert [file]",
+	Short: "Convert llpkgstore.json between the array and the legacy form",
+	Long: `Convert the version mappings of llpkgstore.json, the one in the current dir by default,
+to the documented array form, or to the legacy object form read by older llgo releases with --to legacy,
+and rewrite it in place, or write the result to --output.
+
+The post-processing keeps the form llpkgstore.json is in when it records a new version,
+so the published file only changes form when it's converted. Converting invalidates its signature,
+sign it again with metadata sign.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMetadataConvertCmd,
+}
+
+func runMetadataConvertCmd(cmd *cobra.Command, args []string) error {
+	to, _ := cmd.Flags().GetString("to")
+	output, _ := cmd.Flags().GetString("output")
+	path := metadataPath(args)
+	if output == "" {
+		output = path
+	}
+	format, err := metadata.ParseFormat(to)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	from, err := metadata.DetectFormat(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	m := metadata.MetadataMap{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	converted, err := m.Encode(format)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, converted, 0644); err != nil {
+		return err
+	}
+	if from == format {
+		cmd.Printf("%s: already in the %s form, %d llpkgs written to %s\n", path, format, len(m), output)
+		return nil
+	}
+	cmd.Printf("%s: %d llpkgs converted from the %s to the %s form, written to %s\n", path, len(m), from, format, output)
+	return nil
+}
+
+var metadataKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate an ed25519 key pair to sign llpkgstore.json with",
+	Long: `Generate an ed25519 key pair and print its private key, to set in ` + actions.SigningKeysEnv + `,
+its public key, to trust in metadata.WithTrustedKeys, and its key ID.`,
+	Args: cobra.NoArgs,
+	RunE: runMetadataKeygenCmd,
+}
+
+var metadataSignCmd = &cobra.Command{
+	Use:   "sign [file]",
+	Short: "Sign llpkgstore.json",
+	Long: `Write the detached signature of llpkgstore.json, the one in the current dir by default,
+to the file with the .sig suffix, signed by the private keys in ` + actions.SigningKeysEnv + `
+and timestamped now, like the post-processing does. Clients reject data signed before the data they have cached.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMetadataSignCmd,
+}
+
+var metadataVerifyCmd = &cobra.Command{
+	Use:   "verify [file]",
+	Short: "Verify the signature of llpkgstore.json",
+	Long: `Verify llpkgstore.json, the one in the current dir by default, against its detached signature
+in the file with the .sig suffix, and print the timestamp of the signature.
+It must be signed by one of the public keys given with --key.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMetadataVerifyCmd,
+}
+
+// metadataPath returns the llpkgstore.json in args, the one in the current dir by default.
+func metadataPath(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return "llpkgstore.json"
+}
+
+func runMetadataKeygenCmd(cmd *cobra.Command, _ []string) error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "private key: %s\n", base64.StdEncoding.EncodeToString(private.Seed()))
+	fmt.Fprintf(out, "public key:  %s\n", base64.StdEncoding.EncodeToString(public))
+	_, err = fmt.Fprintf(out, "key ID:      %s\n", metadata.KeyID(public))
+	return err
+}
+
+func runMetadataSignCmd(cmd *cobra.Command, args []string) error {
+	path := metadataPath(args)
+	if os.Getenv(actions.SigningKeysEnv) == "" {
+		return fmt.Errorf("%s is not set", actions.SigningKeysEnv)
+	}
+	if err := actions.SignMetadata(path); err != nil {
+		return err
+	}
+	cmd.Printf("%s: signed to %s\n", path, path+metadata.SignatureSuffix)
+	return nil
+}
+
+func runMetadataVerifyCmd(cmd *cobra.Command, args []string) error {
+	encodedKeys, _ := cmd.Flags().GetStringSlice("key")
+	if len(encodedKeys) == 0 {
+		return errors.New("no trusted key, use --key")
+	}
+	var keys []ed25519.PublicKey
+	for _, encoded := range encodedKeys {
+		key, err := metadata.ParsePublicKey(encoded)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	path := metadataPath(args)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	signature, err := os.ReadFile(path + metadata.SignatureSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	signedAt, err := metadata.Verify(data, signature, keys)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	cmd.Printf("%s: ok, signed at %s\n", path, signedAt.Format(time.RFC3339))
+	return nil
+}
+
+func init() {
+	metadataConvertCmd.Flags().String("to", metadata.FormatArray.String(), "Form to convert to, array or legacy")
+	metadataConvertCmd.Flags().StringP("output", "o", "", "Path to write the converted file to, the input file by default")
+	metadataVerifyCmd.Flags().StringSlice("key", nil, "Trusted base64 ed25519 public key, can be repeated")
+	metadataCmd.AddCommand(metadataConvertCmd, metadataKeygenCmd, metadataSignCmd, metadataVerifyCmd)
+	rootCmd.AddCommand(metadataCmd)
+}
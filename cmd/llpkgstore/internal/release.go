@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"log"
+
 	"github.com/goplus/llpkgstore/internal/actions"
 	"github.com/spf13/cobra"
 )
@@ -12,10 +14,19 @@ var releaseCmd = &cobra.Command{
 	Run:   runReleaseCmd,
 }
 
-func runReleaseCmd(_ *cobra.Command, _ []string) {
-	actions.NewDefaultClient().Release()
+func runReleaseCmd(cmd *cobra.Command, _ []string) {
+	installCache, err := installCache(cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	platform, err := targetPlatform(cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	actions.NewDefaultClient().Release(cmd.Context(), installCache, platform)
 }
 
 func init() {
+	releaseCmd.Flags().String("platform", "", "GOOS/GOARCH to release the package for (default the host)")
 	rootCmd.AddCommand(releaseCmd)
 }
@@ -1,11 +1,17 @@
 package internal
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
 
+// cancelTimeout releases the deadline set up by the --timeout flag.
+var cancelTimeout context.CancelFunc = func() {}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "llpkgstore",
@@ -13,12 +19,28 @@ var rootCmd = &cobra.Command{
 	Long: `This application is a tool that integrates llpkg-related functionality,
 such as binary installation, configuration file parsing, etc.
 `,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			return err
+		}
+		if timeout > 0 {
+			var ctx context.Context
+			ctx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+		}
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// SIGINT and SIGTERM cancel the context of the running command, which kills any installer processes.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
 	if err != nil {
 		os.Exit(1)
 	}
@@ -26,4 +48,5 @@ func Execute() {
 
 func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Abort the command after the given duration, e.g. 30m (0 or a negative duration means no limit)")
 }
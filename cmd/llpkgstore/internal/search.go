@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/goplus/llpkgstore/config"
+	"github.com/goplus/llpkgstore/metadata"
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <clib>",
+	Short: "Search the available versions of a C library",
+	Long: `Search the upstream for the available versions of a C library, along with the Go versions
+already mapped to them in llpkgstore.json.
+
+If <clib>/llpkg.cfg exists, its upstream installer and config are used, otherwise the installer given by --installer.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSearchCmd,
+}
+
+// searchEntry is a search result with its mapped Go versions.
+type searchEntry struct {
+	upstream.SearchResult
+	GoVersions []string `json:"goVersions,omitempty"`
+}
+
+// searchUpstream returns the upstream to search clib with.
+func searchUpstream(clib, installer string) (*upstream.Upstream, error) {
+	upstreamConfig := config.UpstreamConfig{
+		Installer: config.InstallerConfig{Name: installer},
+	}
+	cfg, err := config.ParseLLPkgConfig(filepath.Join(clib, LLGOModuleIdentifyFile))
+	switch {
+	case err == nil:
+		upstreamConfig = cfg.Upstream
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("parse config error: %w", err)
+	}
+	upstreamConfig.Package.Name = filepath.Base(clib)
+	return config.NewUpstreamFromConfig(upstreamConfig)
+}
+
+// readMetadata reads the version mappings of llpkgstore.json, a missing file has no mappings.
+func readMetadata(path string) (metadata.MetadataMap, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return metadata.MetadataMap{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := metadata.MetadataMap{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+func runSearchCmd(cmd *cobra.Command, args []string) error {
+	installer, _ := cmd.Flags().GetString("installer")
+	metadataPath, _ := cmd.Flags().GetString("metadata")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	uc, err := searchUpstream(args[0], installer)
+	if err != nil {
+		return err
+	}
+	defer uc.Close()
+	mappings, err := readMetadata(metadataPath)
+	if err != nil {
+		return err
+	}
+	if err := uc.Prepare(cmd.Context()); err != nil {
+		return err
+	}
+	results, err := uc.Search(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	entries := make([]searchEntry, 0, len(results))
+	for _, result := range results {
+		entry := searchEntry{SearchResult: result}
+		entry.GoVersions = mappings[result.Name].GoVersions(result.Version)
+		semver.Sort(entry.GoVersions)
+		entries = append(entries, entry)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tREVISION\tREMOTE\tGO VERSIONS")
+	for _, entry := range entries {
+		goVersions := "-"
+		if len(entry.GoVersions) > 0 {
+			goVersions = strings.Join(entry.GoVersions, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Reference(), orDash(entry.Revision), orDash(entry.Remote), goVersions)
+	}
+	return w.Flush()
+}
+
+// orDash returns s, or "-" if s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	searchCmd.Flags().String("installer", config.DefaultInstaller, "Installer to search with when <clib>/llpkg.cfg doesn't exist")
+	searchCmd.Flags().String("metadata", "llpkgstore.json", "Path to llpkgstore.json with the mapped Go versions")
+	searchCmd.Flags().Bool("json", false, "Print the results as JSON")
+	rootCmd.AddCommand(searchCmd)
+}
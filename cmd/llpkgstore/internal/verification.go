@@ -1,15 +1,17 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"github.com/goplus/llpkgstore/config"
 	"github.com/goplus/llpkgstore/internal/actions"
 	"github.com/goplus/llpkgstore/internal/actions/generator/llcppg"
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/goplus/llpkgstore/upstream/cache"
 	"github.com/spf13/cobra"
 )
 
@@ -22,7 +24,7 @@ var verificationCmd = &cobra.Command{
 	Run:   runLLCppgVerification,
 }
 
-func runLLCppgVerificationWithDir(dir string) {
+func runLLCppgVerificationWithDir(ctx context.Context, installCache *cache.Cache, dir string) {
 	cfg, err := config.ParseLLPkgConfig(filepath.Join(dir, LLGOModuleIdentifyFile))
 	if err != nil {
 		log.Fatalf("parse config error: %v", err)
@@ -31,11 +33,44 @@ func runLLCppgVerificationWithDir(dir string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	_, err = uc.Installer.Install(uc.Pkg, dir)
+	defer uc.Close()
+	if err := uc.Prepare(ctx); err != nil {
+		log.Fatal(err)
+	}
+	lockfile, err := uc.UseLockfileIn(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the PR is verified with what the lockfile pins, which a fallback candidate isn't.
+	if fallback, ok := uc.Installer.(*upstream.Fallback); ok {
+		fallback.DisableFallback()
+	}
+	// install out of the llpkg dir, the installed files aren't part of the llpkg.
+	installDir, err := os.MkdirTemp("", "llpkg-tool")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(installDir)
+	result, err := installCache.Install(ctx, uc, lockfile, installDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := result.CheckLinkage(uc.Pkg.Linkage); err != nil {
+		log.Fatal(err)
+	}
+	if err := checkLLCppgConfig(dir, cfg, result); err != nil {
+		log.Fatal(err)
+	}
+	depsDir, err := os.MkdirTemp("", "llpkg-deps")
 	if err != nil {
 		log.Fatal(err)
 	}
-	generator := llcppg.New(dir, cfg.Upstream.Package.Name, dir)
+	defer os.RemoveAll(depsDir)
+	pcPath, err := dependencyPCPath(ctx, installCache, dir, cfg, installDir, depsDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	generator := llcppg.New(dir, cfg.Upstream.Package.Name, pcPath)
 
 	generated := filepath.Join(dir, ".generated")
 	os.Mkdir(generated, 0777)
@@ -50,14 +85,18 @@ func runLLCppgVerificationWithDir(dir string) {
 	}
 }
 
-func runLLCppgVerification(_ *cobra.Command, _ []string) {
-	exec.Command("conan", "profile", "detect").Run()
+func runLLCppgVerification(cmd *cobra.Command, _ []string) {
+	ctx := cmd.Context()
+	installCache, err := installCache(cmd)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	paths := actions.NewDefaultClient().CheckPR()
+	paths := actions.NewDefaultClient().CheckPR(ctx)
 
 	for _, path := range paths {
 		absPath, _ := filepath.Abs(path)
-		runLLCppgVerificationWithDir(absPath)
+		runLLCppgVerificationWithDir(ctx, installCache, absPath)
 	}
 	// output parsed path to Github Env for demotest
 	b, _ := json.Marshal(&paths)
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/goplus/llpkgstore/internal/actions"
+	"github.com/goplus/llpkgstore/internal/actions/versions"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [dir...]",
+	Short: "Propose new llpkg versions for upstream updates",
+	Long: `Search the upstream of every llpkg for C versions which aren't mapped in llpkgstore.json yet,
+classify them as latest or legacy versions following the branch maintenance strategy
+and propose their mapped versions.
+
+Without arguments, every directory containing an llpkg.cfg in the current directory is watched.
+With --prepare, a branch with the bumped llpkg.cfg and the Release-as trailer is committed for every proposal.`,
+
+	RunE: runWatchCmd,
+}
+
+func runWatchCmd(cmd *cobra.Command, args []string) error {
+	metadataPath, _ := cmd.Flags().GetString("metadata")
+	prepare, _ := cmd.Flags().GetBool("prepare")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	dirs := args
+	if len(dirs) == 0 {
+		var err error
+		if dirs, err = actions.LLPkgDirs("."); err != nil {
+			return err
+		}
+	}
+	ver, err := versions.Load(metadataPath)
+	if err != nil {
+		return err
+	}
+	proposals, watchErr := actions.Watch(cmd.Context(), ver, dirs)
+	if watchErr != nil {
+		cmd.PrintErrln(watchErr)
+	}
+	if prepare {
+		for i := range proposals {
+			p := &proposals[i]
+			if p.MappedVersion == "" {
+				continue
+			}
+			if err := actions.PrepareProposal(cmd.Context(), p); err != nil {
+				p.Note = err.Error()
+			}
+		}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(proposals); err != nil {
+			return err
+		}
+		return watchErr
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CLIB\tVERSION\tKIND\tRELEASE-AS\tBASE\tBRANCH/NOTE")
+	for _, p := range proposals {
+		releaseAs, base, note := "-", "-", p.Note
+		if p.MappedVersion != "" {
+			releaseAs, base = p.MappedVersion, p.Base
+		}
+		if p.Branch != "" {
+			note = p.Branch
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Clib, p.CVersion, p.Kind, releaseAs, base, note)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return watchErr
+}
+
+func init() {
+	watchCmd.Flags().String("metadata", "llpkgstore.json", "Path to llpkgstore.json")
+	watchCmd.Flags().Bool("prepare", false, "Commit a branch with the bumped llpkg.cfg for every proposal")
+	watchCmd.Flags().Bool("json", false, "Print the report as JSON")
+	rootCmd.AddCommand(watchCmd)
+}
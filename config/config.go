@@ -1,52 +1,121 @@
 package config
 
 import (
-	"errors"
+	"slices"
 
 	"github.com/goplus/llpkgstore/upstream"
-	"github.com/goplus/llpkgstore/upstream/installer/conan"
+	"github.com/goplus/llpkgstore/upstream/installer/plugin"
+	"golang.org/x/mod/semver"
+
+	// register the builtin installers
+	_ "github.com/goplus/llpkgstore/upstream/installer/conan"
+	_ "github.com/goplus/llpkgstore/upstream/installer/local"
+	_ "github.com/goplus/llpkgstore/upstream/installer/source"
+	_ "github.com/goplus/llpkgstore/upstream/installer/vcpkg"
 )
 
-var ValidInstallers = []string{"conan"}
+// DefaultInstaller is used when upstream.installer.name is not specified.
+const DefaultInstaller = "conan"
+
+// ValidInstallers returns the names of all installers registered in the upstream registry,
+// followed by the installer plugins found on PATH.
+// Builtin installers are always available, other installers can be linked in by importing
+// a package which calls upstream.Register.
+func ValidInstallers() []string {
+	names := upstream.List()
+	for _, name := range plugin.List() {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// lookupInstaller finds the installer factory by name.
+// Registered installers take precedence over plugins with the same name.
+func lookupInstaller(name string) (upstream.Factory, bool) {
+	if factory, ok := upstream.Lookup(name); ok {
+		return factory, true
+	}
+	return plugin.Lookup(name)
+}
 
 // LLPkgConfig represents the configuration structure parsed from llpkg.cfg files.
+// The jsonschema tags are used by Schema.
 type LLPkgConfig struct {
-	Upstream UpstreamConfig `json:"upstream"`
+	// Schema is the optional JSON Schema of the file for editors, see Schema.
+	Schema string `json:"$schema,omitempty" jsonschema_description:"JSON Schema of llpkg.cfg"`
+	// SchemaVersion is the version of the format of the file as recorded in it, 0 meaning 1.
+	// ParseLLPkgConfig migrates older files in memory, see MigrateLLPkgConfig.
+	SchemaVersion int            `json:"schemaVersion,omitempty" jsonschema_description:"version of the llpkg.cfg format, 1 if omitted, see llpkgstore config migrate"`
+	Upstream      UpstreamConfig `json:"upstream" jsonschema:"required" jsonschema_description:"upstream binary package of the llpkg"`
+	// Dependencies are the llpkgs the llpkg depends on.
+	Dependencies []DependencyConfig `json:"dependencies,omitempty" jsonschema_description:"llpkgs the llpkg depends on"`
+}
+
+// ModulePathPrefix is the module path prefix of llpkgs, followed by their name.
+const ModulePathPrefix = "github.com/goplus/llpkg/"
+
+// DependencyConfig declares an llpkg another llpkg depends on, e.g. zlib for libxml2.
+type DependencyConfig struct {
+	// Name is the name of the llpkg, i.e. its directory in the llpkg repository.
+	Name string `json:"name" jsonschema:"required,minLength=1" jsonschema_description:"name of the llpkg"`
+	// Version is a mapped version of the llpkg recorded in llpkgstore.json, e.g. v1.0.0.
+	Version string `json:"version" jsonschema:"required,minLength=1" jsonschema_description:"mapped version of the llpkg, e.g. v1.0.0"`
+}
+
+// ModulePath returns the Go module path of the dependency,
+// with the major version suffix from v2 on, e.g. github.com/goplus/llpkg/zlib/v2.
+func (d DependencyConfig) ModulePath() string {
+	path := ModulePathPrefix + d.Name
+	if major := semver.Major(d.Version); major != "v0" && major != "v1" && major != "" {
+		path += "/" + major
+	}
+	return path
+}
+
+// Module returns the module path and version of the dependency as module@version.
+func (d DependencyConfig) Module() string {
+	return d.ModulePath() + "@" + d.Version
 }
 
 // UpstreamConfig defines the upstream configuration containing installer settings and package metadata.
 type UpstreamConfig struct {
-	Installer InstallerConfig `json:"installer"`
-	Package   PackageConfig   `json:"package"`
+	Installer InstallerConfig `json:"installer" jsonschema_description:"installer providing the package"`
+	Package   PackageConfig   `json:"package" jsonschema:"required" jsonschema_description:"package to install"`
+	// Platforms overrides Installer and Package on some platforms, keyed by GOOS or GOOS/GOARCH.
+	// See Resolve.
+	Platforms map[string]PlatformConfig `json:"platforms,omitempty" jsonschema_description:"overrides keyed by GOOS or GOOS/GOARCH, e.g. linux or linux/arm64"`
+	// Fallbacks are tried in order when installing from Installer fails, see upstream.Fallback.
+	Fallbacks []FallbackConfig `json:"fallbacks,omitempty" jsonschema_description:"upstreams tried in order when the installer fails"`
+}
+
+// FallbackConfig is an upstream of the same package version tried when the ones before it fail.
+type FallbackConfig struct {
+	Installer InstallerConfig `json:"installer" jsonschema:"required" jsonschema_description:"installer providing the package"`
+	// Package is the package name in the fallback upstream, defaulting to package.name.
+	Package string `json:"package,omitempty" jsonschema_description:"package name in the upstream, defaults to package.name"`
 }
 
 // InstallerConfig specifies the installer type and its configuration options.
-// "name" field must match supported installers (e.g., "conan").
+// "name" field must match a registered installer (e.g., "conan", "vcpkg").
 // "config" holds installer-specific parameters (optional).
 type InstallerConfig struct {
-	Name   string            `json:"name"`
-	Config map[string]string `json:"config,omitempty"`
+	Name   string            `json:"name" jsonschema:"default=conan" jsonschema_description:"registered installer or installer plugin"`
+	Config map[string]string `json:"config,omitempty" jsonschema_description:"installer-specific config"`
 }
 
 // PackageConfig defines the target library package's identifier and version requirements.
 type PackageConfig struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
+	Name    string `json:"name" jsonschema:"required,minLength=1" jsonschema_description:"package name in the upstream"`
+	Version string `json:"version" jsonschema:"required,minLength=1" jsonschema_description:"original package version, a semantic version like 1.7.18"`
+	// Linkage is either "shared" (default) or "static".
+	Linkage string `json:"linkage,omitempty" jsonschema:"default=shared,enum=shared,enum=static" jsonschema_description:"kind of libraries to install and release"`
 }
 
-// NewUpstreamFromConfig creates an Upstream instance from configuration data.
+// NewUpstreamFromConfig creates an Upstream instance from configuration data
+// for the host platform, see NewUpstreamForPlatform.
 // Returns error if unsupported installer type is specified.
 func NewUpstreamFromConfig(upstreamConfig UpstreamConfig) (*upstream.Upstream, error) {
-	switch upstreamConfig.Installer.Name {
-	case "conan":
-		return &upstream.Upstream{
-			Installer: conan.NewConanInstaller(upstreamConfig.Installer.Config),
-			Pkg: upstream.Package{
-				Name:    upstreamConfig.Package.Name,
-				Version: upstreamConfig.Package.Version,
-			},
-		}, nil
-	default:
-		return nil, errors.New("unknown upstream installer: " + upstreamConfig.Installer.Name)
-	}
+	return NewUpstreamForPlatform(upstreamConfig, upstream.HostPlatform())
 }
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ParseError is an error at a position of a config file.
+type ParseError struct {
+	File string
+	// Line and Column are 1-based, Column counts bytes.
+	Line, Column int
+	Err          error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnknownField is wrapped by the errors of fields which don't exist in the config structure.
+var ErrUnknownField = errors.New("unknown field")
+
+// decodeJSON decodes the JSON data of file into v, which must be a pointer to a struct.
+// Every error is a *ParseError pointing at the offending token.
+// If strict is set, unlike json.Unmarshal, field names are case-sensitive and unknown fields are errors.
+func decodeJSON(file string, data []byte, v any, strict bool) error {
+	w := &walker{file: file, data: data, dec: json.NewDecoder(bytes.NewReader(data)), strict: strict}
+	w.dec.UseNumber()
+	if err := w.value(reflect.TypeOf(v), ""); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return w.errorAt(syntaxOffset(syntaxErr), err)
+		case errors.As(err, &typeErr):
+			return w.errorAt(int(typeErr.Offset), err)
+		}
+		return &ParseError{File: file, Line: 1, Column: 1, Err: err}
+	}
+	return nil
+}
+
+// syntaxOffset returns the offset of the invalid character of err,
+// whose Offset is the number of bytes read including it.
+func syntaxOffset(err *json.SyntaxError) int {
+	return max(int(err.Offset)-1, 0)
+}
+
+// walker checks a JSON document token by token against a Go type.
+type walker struct {
+	file   string
+	data   []byte
+	dec    *json.Decoder
+	strict bool
+}
+
+// errorAt returns a *ParseError of err at the byte offset off of data.
+func (w *walker) errorAt(off int, err error) error {
+	off = min(off, len(w.data))
+	line := 1 + bytes.Count(w.data[:off], []byte("\n"))
+	col := off + 1
+	if i := bytes.LastIndexByte(w.data[:off], '\n'); i >= 0 {
+		col = off - i
+	}
+	return &ParseError{File: w.file, Line: line, Column: col, Err: err}
+}
+
+// next returns the offset of the next token.
+func (w *walker) next() int {
+	off := int(w.dec.InputOffset())
+	for off < len(w.data) && strings.IndexByte(" \t\r\n,:", w.data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+// token reads the next token and returns its offset.
+func (w *walker) token() (json.Token, int, error) {
+	off := w.next()
+	tok, err := w.dec.Token()
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			off = syntaxOffset(syntaxErr)
+		}
+		return nil, off, w.errorAt(off, err)
+	}
+	return tok, off, nil
+}
+
+// fieldName returns the name of path for error messages.
+func fieldName(path string) string {
+	if path == "" {
+		return "the top level"
+	}
+	return path
+}
+
+// jsonKind returns the JSON type name of the Go type t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Interface:
+		return "any value"
+	}
+	return "number"
+}
+
+// value checks the next value against t, nil meaning any value.
+func (w *walker) value(t reflect.Type, path string) error {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Interface {
+		t = nil
+	}
+	tok, off, err := w.token()
+	if err != nil {
+		return err
+	}
+	got := ""
+	switch tok := tok.(type) {
+	case nil:
+		// null leaves the value untouched.
+		return nil
+	case json.Delim:
+		if tok == '{' {
+			if t == nil || t.Kind() == reflect.Struct || t.Kind() == reflect.Map {
+				return w.object(t, path)
+			}
+			got = "object"
+		} else {
+			if t == nil || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+				return w.array(t, path)
+			}
+			got = "array"
+		}
+	case string:
+		got = "string"
+	case json.Number:
+		got = "number"
+	case bool:
+		got = "boolean"
+	}
+	if t == nil || got == jsonKind(t) {
+		return nil
+	}
+	return w.errorAt(off, fmt.Errorf("%s: expected %s, got %s", fieldName(path), jsonKind(t), got))
+}
+
+// object checks the members of an object against t, the opening brace has been read.
+func (w *walker) object(t reflect.Type, path string) error {
+	for w.dec.More() {
+		tok, off, err := w.token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		var elem reflect.Type
+		switch {
+		case t == nil:
+		case t.Kind() == reflect.Map:
+			elem = t.Elem()
+		default:
+			field, ok := fieldByJSONName(t, key, w.strict)
+			if !ok && !w.strict {
+				// left to json.Unmarshal, which ignores it.
+				break
+			}
+			if !ok {
+				err := fmt.Errorf("%w %q in %s", ErrUnknownField, key, fieldName(path))
+				if suggestion := suggest(key, jsonNames(t)); suggestion != "" {
+					err = fmt.Errorf("%w (did you mean %q?)", err, suggestion)
+				}
+				return w.errorAt(off, err)
+			}
+			elem = field.Type
+		}
+		sub := key
+		if path != "" {
+			sub = path + "." + key
+		}
+		if err := w.value(elem, sub); err != nil {
+			return err
+		}
+	}
+	_, _, err := w.token()
+	return err
+}
+
+// array checks the elements of an array against the element type of t, the opening bracket has been read.
+func (w *walker) array(t reflect.Type, path string) error {
+	var elem reflect.Type
+	if t != nil {
+		elem = t.Elem()
+	}
+	for i := 0; w.dec.More(); i++ {
+		if err := w.value(elem, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	_, _, err := w.token()
+	return err
+}
+
+// jsonName returns the JSON name of a struct field, or "" if it isn't encoded.
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// jsonNames returns the JSON names of the fields of the struct t.
+func jsonNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// fieldByJSONName returns the field of the struct t whose JSON name is exactly name,
+// or equal to it under case-folding like json.Unmarshal does unless exact is set.
+func fieldByJSONName(t reflect.Type, name string, exact bool) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); jsonName(field) == name {
+			return field, true
+		}
+	}
+	for i := 0; i < t.NumField() && !exact; i++ {
+		if field := t.Field(i); strings.EqualFold(jsonName(field), name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// suggest returns the candidate closest to name if it's a likely typo of it.
+func suggest(name string, candidates []string) string {
+	best, bestDist := "", 0
+	for _, candidate := range candidates {
+		if strings.EqualFold(name, candidate) {
+			return candidate
+		}
+		dist := editDistance(name, candidate)
+		if dist <= max(1, len(candidate)/3) && (best == "" || dist < bestDist) {
+			best, bestDist = candidate, dist
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseStrict(t *testing.T) {
+	cases := []struct {
+		name, content string
+		line, column  int
+		want          string
+	}{
+		{
+			name: "unknown field",
+			content: `{
+  "upstream": {
+    "instaler": {"name": "conan"}
+  }
+}`,
+			line: 3, column: 5,
+			want: `unknown field "instaler" in upstream (did you mean "installer"?)`,
+		},
+		{
+			name:    "case-sensitive",
+			content: `{"Upstream": {}}`,
+			line:    1, column: 2,
+			want: `unknown field "Upstream" in the top level (did you mean "upstream"?)`,
+		},
+		{
+			name: "wrong type",
+			content: `{
+  "upstream": {
+    "package": {"name": "cjson", "version": 1.7}
+  }
+}`,
+			line: 3, column: 45,
+			want: "upstream.package.version: expected string, got number",
+		},
+		{
+			name: "syntax error",
+			content: `{
+  "upstream": {,}
+}`,
+			line: 2, column: 16,
+			want: "invalid character ','",
+		},
+		{
+			name:    "trailing data",
+			content: "{}\n}",
+			line:    2, column: 1,
+			want: "after top-level value",
+		},
+	}
+	dir := t.TempDir()
+	for _, c := range cases {
+		path := filepath.Join(dir, "llpkg.cfg")
+		os.WriteFile(path, []byte(c.content), 0644)
+		_, err := ParseLLPkgConfig(path)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if parseErr.File != path || parseErr.Line != c.line || parseErr.Column != c.column || !strings.Contains(parseErr.Error(), c.want) {
+			t.Errorf("%s: unexpected error: %v (line %d, column %d)", c.name, parseErr, parseErr.Line, parseErr.Column)
+		}
+	}
+
+	// $schema and unknown installer config keys are accepted by the parser
+	os.WriteFile(filepath.Join(dir, "llpkg.cfg"), []byte(`{
+  "$schema": "../docs/llpkg.schema.json",
+  "upstream": {
+    "installer": {"name": "conan", "config": {"anything": "x"}},
+    "package": {"name": "cjson", "version": "1.7.18"}
+  }
+}`), 0644)
+	if _, err := ParseLLPkgConfig(filepath.Join(dir, "llpkg.cfg")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	names := []string{"installer", "package"}
+	for name, want := range map[string]string{
+		"instaler":  "installer",
+		"INSTALLER": "installer",
+		"pakage":    "package",
+		"version":   "",
+	} {
+		if got := suggest(name, names); got != want {
+			t.Errorf("suggest(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
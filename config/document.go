@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// document is a JSON object which keeps the order of its members,
+// so that rewritten config files only change where they're modified.
+type document struct {
+	fields []field
+}
+
+// field is a member of a JSON object.
+type field struct {
+	name  string
+	value json.RawMessage
+}
+
+// parseDocument parses data, a JSON object.
+func parseDocument(data []byte) (*document, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	d := &document{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		f := field{name: tok.(string)}
+		if err := dec.Decode(&f.value); err != nil {
+			return nil, err
+		}
+		d.fields = append(d.fields, f)
+	}
+	return d, nil
+}
+
+// index returns the index of the member name, or -1.
+func (d *document) index(name string) int {
+	return slices.IndexFunc(d.fields, func(f field) bool { return f.name == name })
+}
+
+// get decodes the member name into v and reports whether it exists.
+func (d *document) get(name string, v any) (bool, error) {
+	i := d.index(name)
+	if i < 0 {
+		return false, nil
+	}
+	if err := json.Unmarshal(d.fields[i].value, v); err != nil {
+		return true, fmt.Errorf("%s: %w", name, err)
+	}
+	return true, nil
+}
+
+// set sets the member name to v, appending it if it doesn't exist.
+func (d *document) set(name string, v any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// keep & < > of conan options and flags readable.
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	raw := json.RawMessage(bytes.TrimSpace(buf.Bytes()))
+	if i := d.index(name); i >= 0 {
+		d.fields[i].value = raw
+	} else {
+		d.fields = append(d.fields, field{name: name, value: raw})
+	}
+	return nil
+}
+
+// remove removes the member name if it exists.
+func (d *document) remove(name string) {
+	if i := d.index(name); i >= 0 {
+		d.fields = slices.Delete(d.fields, i, i+1)
+	}
+}
+
+// object returns the member name, a JSON object, or nil if it doesn't exist or isn't an object.
+// A nil document has no members, so lookups can be chained.
+func (d *document) object(name string) *document {
+	if d == nil {
+		return nil
+	}
+	i := d.index(name)
+	if i < 0 {
+		return nil
+	}
+	o, err := parseDocument(d.fields[i].value)
+	if err != nil {
+		return nil
+	}
+	return o
+}
+
+// MarshalJSON encodes d compactly, with its members in order.
+func (d *document) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range d.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		if err := json.Compact(&buf, f.value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// format encodes d indented by two spaces like the config files in the llpkg repository.
+func (d *document) format() ([]byte, error) {
+	b, err := d.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// LLCppgConfigFile is the name of the config file of llcppg.
+const LLCppgConfigFile = "llcppg.cfg"
+
+// LLCppgConfig represents the configuration structure parsed from llcppg.cfg files.
+// Only the fields checked by llpkgstore are decoded, the others are left to llcppg.
+type LLCppgConfig struct {
+	// Name is the name of the generated Go package, which must be the package name of llpkg.cfg.
+	Name string `json:"name"`
+	// CFlags and Libs are usually pkg-config invocations like $(pkg-config --cflags cjson).
+	CFlags string `json:"cflags"`
+	Libs   string `json:"libs"`
+	// Include lists the headers to generate bindings for, relative to the include directories.
+	Include      []string `json:"include"`
+	Deps         []string `json:"deps"`
+	TrimPrefixes []string `json:"trimPrefixes"`
+	Cplusplus    bool     `json:"cplusplus"`
+	// StaticLib makes llcppg read the symbols from static libraries.
+	StaticLib bool `json:"staticLib"`
+}
+
+// ParseLLCppgConfig reads and parses the llcppg.cfg configuration file.
+// Decoding errors are reported as a *ParseError with their line and column.
+func ParseLLCppgConfig(configPath string) (LLCppgConfig, error) {
+	var config LLCppgConfig
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return config, fmt.Errorf("failed to open config file: %w", err)
+	}
+	if err := decodeJSON(configPath, data, &config, false); err != nil {
+		return config, fmt.Errorf("failed to decode config file: %w", err)
+	}
+	return config, nil
+}
+
+var pkgConfigCall = regexp.MustCompile("\\$\\(\\s*pkg-config\\s+([^)]*)\\)|`\\s*pkg-config\\s+([^`]*)`")
+
+// PkgConfigNames returns the pkg-config names referenced by $(pkg-config ...) in CFlags and Libs.
+func (c LLCppgConfig) PkgConfigNames() []string {
+	var names []string
+	for _, match := range pkgConfigCall.FindAllStringSubmatch(c.CFlags+" "+c.Libs, -1) {
+		for _, arg := range strings.Fields(match[1] + match[2]) {
+			if !strings.HasPrefix(arg, "-") && !slices.Contains(names, arg) {
+				names = append(names, arg)
+			}
+		}
+	}
+	return names
+}
+
+// ValidateLLCppgConfig checks llcppg.cfg against llpkg.cfg without installing anything,
+// and returns every problem found joined.
+func ValidateLLCppgConfig(config LLCppgConfig, llpkg LLPkgConfig) error {
+	var errs []error
+	pkg := llpkg.Upstream.Package
+	if config.Name == "" {
+		errs = append(errs, fmt.Errorf("missing required name: llcppg.cfg name cannot be empty"))
+	} else if config.Name != pkg.Name {
+		errs = append(errs, fmt.Errorf("name mismatch: llcppg.cfg name %s must be the package name %s of llpkg.cfg", config.Name, pkg.Name))
+	}
+	if len(config.Include) == 0 {
+		errs = append(errs, fmt.Errorf("missing headers: llcppg.cfg include cannot be empty"))
+	}
+	if pkg.Linkage == upstream.LinkageStatic && !config.StaticLib {
+		errs = append(errs, fmt.Errorf("linkage mismatch: llcppg.cfg staticLib must be true for static linkage"))
+	}
+	for _, dep := range llpkg.Dependencies {
+		if !slices.Contains(config.Deps, dep.Module()) {
+			errs = append(errs, fmt.Errorf("missing dependency: llcppg.cfg deps must contain %s of the dependency %s, see FillLLCppgDeps",
+				dep.Module(), dep.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckLLCppgInstall checks that the pkg-config names and headers referenced by llcppg.cfg exist
+// in what has been installed, so that problems are reported before llcppg runs.
+// The headers are looked up in the include directories of result and in the -I flags
+// of the referenced .pc files and their requirements.
+func CheckLLCppgInstall(config LLCppgConfig, result *upstream.InstallResult) error {
+	var errs []error
+	pcFiles := map[string]string{}
+	for _, pcFile := range result.PCFiles {
+		pcFiles[strings.TrimSuffix(filepath.Base(pcFile), ".pc")] = pcFile
+	}
+	available := func() string {
+		var names []string
+		for name := range pcFiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return strings.Join(names, ", ")
+	}
+
+	includeDirs := slices.Clone(result.IncludeDirs)
+	visited := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		content, err := os.ReadFile(pcFiles[name])
+		if err != nil {
+			return
+		}
+		info := pc.Parse(content)
+		includeDirs = append(includeDirs, pc.IncludeDirs(info.Cflags)...)
+		for _, require := range info.Requires {
+			visit(require)
+		}
+	}
+	for _, name := range config.PkgConfigNames() {
+		if _, ok := pcFiles[name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown pkg-config name: %s referenced by llcppg.cfg isn't installed (installed: %s)", name, available()))
+			continue
+		}
+		visit(name)
+	}
+
+	for _, header := range config.Include {
+		if !headerExists(header, includeDirs) {
+			errs = append(errs, fmt.Errorf("missing header: %s of llcppg.cfg include isn't found in %v", header, includeDirs))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// headerExists reports whether header is found in one of includeDirs, or exists if it's absolute.
+func headerExists(header string, includeDirs []string) bool {
+	if filepath.IsAbs(header) {
+		_, err := os.Stat(header)
+		return err == nil
+	}
+	for _, dir := range includeDirs {
+		if _, err := os.Stat(filepath.Join(dir, header)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeDeps returns the llcppg deps with the modules of dependencies, e.g. github.com/goplus/llpkg/zlib@v1.0.0,
+// replacing the entries of the same module paths and appending the others.
+func MergeDeps(deps []string, dependencies []DependencyConfig) []string {
+	merged := slices.Clone(deps)
+	for _, dep := range dependencies {
+		i := slices.IndexFunc(merged, func(entry string) bool {
+			path, _, _ := strings.Cut(entry, "@")
+			return path == dep.ModulePath()
+		})
+		if i < 0 {
+			merged = append(merged, dep.Module())
+		} else {
+			merged[i] = dep.Module()
+		}
+	}
+	return merged
+}
+
+// FillLLCppgDeps rewrites the deps of the llcppg.cfg at configPath with MergeDeps,
+// keeping the other fields and their order. The file isn't touched if deps are up to date.
+func FillLLCppgDeps(configPath string, dependencies []DependencyConfig) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	config, err := ParseLLCppgConfig(configPath)
+	if err != nil {
+		return err
+	}
+	deps := MergeDeps(config.Deps, dependencies)
+	if slices.Equal(deps, config.Deps) {
+		return nil
+	}
+	doc, err := parseDocument(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configPath, err)
+	}
+	if err := doc.set("deps", deps); err != nil {
+		return err
+	}
+	out, err := doc.format()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, out, 0644)
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+func TestParseLLCppgConfig(t *testing.T) {
+	cfg, err := ParseLLCppgConfig("../_demo/llcppg.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "cjson" || !reflect.DeepEqual(cfg.Include, []string{"cjson/cJSON.h"}) || cfg.Cplusplus {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if names := cfg.PkgConfigNames(); !reflect.DeepEqual(names, []string{"cjson"}) {
+		t.Errorf("unexpected pkg-config names: %v", names)
+	}
+
+	// unknown fields are left to llcppg, but types are checked
+	path := filepath.Join(t.TempDir(), "llcppg.cfg")
+	os.WriteFile(path, []byte(`{"name": "cjson", "symMap": {}, "include": "cjson/cJSON.h"}`), 0644)
+	_, err = ParseLLCppgConfig(path)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Column != 44 || !strings.Contains(err.Error(), "include: expected array, got string") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPkgConfigNames(t *testing.T) {
+	cfg := LLCppgConfig{
+		CFlags: "$(pkg-config --cflags libcurl openssl) -DFOO",
+		Libs:   "`pkg-config --libs --static libcurl` -lm",
+	}
+	if names := cfg.PkgConfigNames(); !reflect.DeepEqual(names, []string{"libcurl", "openssl"}) {
+		t.Errorf("unexpected pkg-config names: %v", names)
+	}
+}
+
+func TestValidateLLCppgConfig(t *testing.T) {
+	llpkg := LLPkgConfig{Upstream: UpstreamConfig{Package: PackageConfig{Name: "cjson", Version: "1.7.18"}}}
+	cfg := LLCppgConfig{Name: "cjson", Include: []string{"cjson/cJSON.h"}}
+	if err := ValidateLLCppgConfig(cfg, llpkg); err != nil {
+		t.Error(err)
+	}
+
+	llpkg.Upstream.Package.Linkage = upstream.LinkageStatic
+	err := ValidateLLCppgConfig(LLCppgConfig{Name: "cJSON"}, llpkg)
+	for _, want := range []string{"name mismatch", "include cannot be empty", "staticLib"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q not found in %v", want, err)
+		}
+	}
+}
+
+func TestCheckLLCppgInstall(t *testing.T) {
+	dir := t.TempDir()
+	// conan layout: .pc files in the root, the headers of a dependency outside of the output directory
+	depPrefix := filepath.Join(dir, "zlib")
+	os.MkdirAll(filepath.Join(dir, "include", "cjson"), 0777)
+	os.MkdirAll(filepath.Join(depPrefix, "include"), 0777)
+	os.WriteFile(filepath.Join(dir, "include", "cjson", "cJSON.h"), nil, 0644)
+	os.WriteFile(filepath.Join(depPrefix, "include", "zlib.h"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "cjson.pc"), []byte("prefix="+dir+"\nName: cjson\nRequires: zlib\nCflags: -I${prefix}/include\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "zlib.pc"), []byte("prefix="+depPrefix+"\nName: zlib\nCflags: -I\"${prefix}/include\"\n"), 0644)
+	result := &upstream.InstallResult{
+		PkgConfigName: "cjson",
+		PCFiles:       []string{filepath.Join(dir, "cjson.pc"), filepath.Join(dir, "zlib.pc")},
+	}
+
+	cfg := LLCppgConfig{
+		Name:    "cjson",
+		CFlags:  "$(pkg-config --cflags cjson)",
+		Libs:    "$(pkg-config --libs cjson)",
+		Include: []string{"cjson/cJSON.h", "zlib.h"},
+	}
+	if err := CheckLLCppgInstall(cfg, result); err != nil {
+		t.Error(err)
+	}
+
+	cfg.CFlags = "$(pkg-config --cflags libcjson)"
+	cfg.Include = []string{"cJSON.h"}
+	err := CheckLLCppgInstall(cfg, result)
+	for _, want := range []string{"unknown pkg-config name: libcjson", "installed: cjson, zlib", "missing header: cJSON.h"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q not found in %v", want, err)
+		}
+	}
+}
+
+func TestFillLLCppgDeps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llcppg.cfg")
+	os.WriteFile(path, []byte(`{
+	"name": "libxml2",
+	"cflags": "$(pkg-config --cflags libxml-2.0)",
+	"include": ["libxml/tree.h"],
+	"deps": ["c/os", "github.com/goplus/llpkg/zlib@v1.0.0"],
+	"symMap": {"xmlFree": "Free"}
+}`), 0644)
+	deps := []DependencyConfig{{Name: "zlib", Version: "v1.1.0"}, {Name: "libiconv", Version: "v1.0.0"}}
+	if err := FillLLCppgDeps(path, deps); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path)
+	want := `{
+  "name": "libxml2",
+  "cflags": "$(pkg-config --cflags libxml-2.0)",
+  "include": [
+    "libxml/tree.h"
+  ],
+  "deps": [
+    "c/os",
+    "github.com/goplus/llpkg/zlib@v1.1.0",
+    "github.com/goplus/llpkg/libiconv@v1.0.0"
+  ],
+  "symMap": {
+    "xmlFree": "Free"
+  }
+}
+`
+	if string(b) != want {
+		t.Errorf("unexpected llcppg.cfg:\n%s", b)
+	}
+
+	cfg, _ := ParseLLCppgConfig(path)
+	llpkg := LLPkgConfig{Upstream: UpstreamConfig{Package: PackageConfig{Name: "libxml2"}}, Dependencies: deps}
+	if err := ValidateLLCppgConfig(cfg, llpkg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	llpkg.Dependencies = append(llpkg.Dependencies, DependencyConfig{Name: "xz", Version: "v1.0.0"})
+	if err := ValidateLLCppgConfig(cfg, llpkg); err == nil || !strings.Contains(err.Error(), "github.com/goplus/llpkg/xz@v1.0.0") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// up-to-date deps leave the file untouched
+	os.WriteFile(path, []byte(`{"name": "libxml2", "deps": ["github.com/goplus/llpkg/zlib@v1.1.0"]}`), 0644)
+	if err := FillLLCppgDeps(path, deps[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != `{"name": "libxml2", "deps": ["github.com/goplus/llpkg/zlib@v1.1.0"]}` {
+		t.Errorf("unexpected llcppg.cfg: %s", b)
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CurrentSchemaVersion is the schema version of llpkg.cfg this package decodes.
+// Documents without schemaVersion are version 1, older documents are upgraded by MigrateLLPkgConfig.
+const CurrentSchemaVersion = 2
+
+// migration upgrades an llpkg.cfg document from schema version From to From+1.
+type migration struct {
+	From int
+	// Description tells what the migration changes, e.g. in the output of config migrate.
+	Description string
+	// Migrate modifies doc in place and reports whether it changed anything.
+	Migrate func(doc *document) (bool, error)
+}
+
+// migrations upgrade llpkg.cfg documents step by step, ordered by From.
+// A change to LLPkgConfig which older documents don't decode to the same config
+// bumps CurrentSchemaVersion and adds a migration here.
+var migrations = []migration{
+	{
+		From:        1,
+		Description: "replace the conan option *:shared=False by package.linkage static",
+		Migrate:     migrateSharedOption,
+	},
+}
+
+// MigrationResult is the result of MigrateLLPkgConfig.
+type MigrationResult struct {
+	// From is the schema version of the original document.
+	From int
+	// Applied lists the descriptions of the migrations which changed the document.
+	Applied []string
+	// Data is the migrated document, formatted like the config files of the llpkg repository.
+	// It's the original document if From is CurrentSchemaVersion.
+	Data []byte
+}
+
+// Changed reports whether the document has been rewritten, if only to record the schema version.
+func (r *MigrationResult) Changed() bool {
+	return r.From != CurrentSchemaVersion
+}
+
+// schemaVersionOf returns the schema version of doc, 1 if it isn't recorded.
+func schemaVersionOf(doc *document) (int, error) {
+	version := 1
+	if _, err := doc.get("schemaVersion", &version); err != nil {
+		return 0, err
+	}
+	switch {
+	case version < 1:
+		return 0, fmt.Errorf("invalid schemaVersion %d", version)
+	case version > CurrentSchemaVersion:
+		return 0, fmt.Errorf("schemaVersion %d is newer than %d, the latest supported by this llpkgstore, please upgrade it",
+			version, CurrentSchemaVersion)
+	}
+	return version, nil
+}
+
+// MigrateLLPkgConfig upgrades data, the content of an llpkg.cfg, to CurrentSchemaVersion
+// by applying the migrations of every version in between, and records the version in schemaVersion.
+// The members of the document keep their order.
+func MigrateLLPkgConfig(data []byte) (*MigrationResult, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	version, err := schemaVersionOf(doc)
+	if err != nil {
+		return nil, err
+	}
+	result := &MigrationResult{From: version, Data: data}
+	if version == CurrentSchemaVersion {
+		return result, nil
+	}
+	for _, m := range migrations {
+		if m.From < version {
+			continue
+		}
+		changed, err := m.Migrate(doc)
+		if err != nil {
+			return nil, fmt.Errorf("migrating from schema version %d: %w", m.From, err)
+		}
+		if changed {
+			result.Applied = append(result.Applied, m.Description)
+		}
+	}
+	// schemaVersion goes right after $schema, or first.
+	doc.remove("schemaVersion")
+	at := 0
+	if i := doc.index("$schema"); i >= 0 {
+		at = i + 1
+	}
+	doc.fields = slices.Insert(doc.fields, at, field{name: "schemaVersion", value: []byte(fmt.Sprint(CurrentSchemaVersion))})
+	if result.Data, err = doc.format(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetPackageVersion sets upstream.package.version in data, the content of an llpkg.cfg, to version.
+// The version fields of dependencies and platform overrides are left alone,
+// and the members of the document keep their order.
+func SetPackageVersion(data []byte, version string) ([]byte, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	upstream := doc.object("upstream")
+	pkg := upstream.object("package")
+	if pkg == nil {
+		return nil, fmt.Errorf("no upstream.package")
+	}
+	if err := pkg.set("version", version); err != nil {
+		return nil, err
+	}
+	if err := upstream.set("package", pkg); err != nil {
+		return nil, err
+	}
+	if err := doc.set("upstream", upstream); err != nil {
+		return nil, err
+	}
+	return doc.format()
+}
+
+// migrateSharedOption migrates static linkage from schema version 1, where it could only be selected
+// with *:shared=False in the conan options, to package.linkage. *:shared=True is dropped as well,
+// the conan installer sets the shared option by the linkage.
+func migrateSharedOption(doc *document) (bool, error) {
+	upstream := doc.object("upstream")
+	installer := upstream.object("installer")
+	config := installer.object("config")
+	if config == nil {
+		return false, nil
+	}
+	var name string
+	if _, err := installer.get("name", &name); err != nil {
+		return false, err
+	}
+	if name != "" && name != "conan" {
+		return false, nil
+	}
+	var options string
+	if ok, err := config.get("options", &options); !ok || err != nil {
+		return false, err
+	}
+	var kept []string
+	static := false
+	for _, opt := range strings.Fields(options) {
+		switch opt {
+		case "*:shared=False":
+			static = true
+		case "*:shared=True":
+			// the last shared option wins like in conan.
+			static = false
+		default:
+			kept = append(kept, opt)
+		}
+	}
+	if len(kept) == len(strings.Fields(options)) {
+		return false, nil
+	}
+	if err := config.set("options", strings.Join(kept, " ")); err != nil {
+		return false, err
+	}
+	if err := installer.set("config", config); err != nil {
+		return false, err
+	}
+	if err := upstream.set("installer", installer); err != nil {
+		return false, err
+	}
+	if static {
+		pkg := upstream.object("package")
+		if pkg == nil {
+			pkg = &document{}
+		}
+		if err := pkg.set("linkage", "static"); err != nil {
+			return false, err
+		}
+		if err := upstream.set("package", pkg); err != nil {
+			return false, err
+		}
+	}
+	return true, doc.set("upstream", upstream)
+}
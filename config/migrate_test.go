@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateLLPkgConfig(t *testing.T) {
+	result, err := MigrateLLPkgConfig([]byte(`{
+  "$schema": "../docs/llpkg.schema.json",
+  "upstream": {
+    "installer": {"config": {"options": "*:shared=True cjson/*:utils=True *:shared=False"}},
+    "package": {"name": "cjson", "version": "1.7.18"}
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "$schema": "../docs/llpkg.schema.json",
+  "schemaVersion": 2,
+  "upstream": {
+    "installer": {
+      "config": {
+        "options": "cjson/*:utils=True"
+      }
+    },
+    "package": {
+      "name": "cjson",
+      "version": "1.7.18",
+      "linkage": "static"
+    }
+  }
+}
+`
+	if !result.Changed() || result.From != 1 || len(result.Applied) != 1 || string(result.Data) != want {
+		t.Errorf("unexpected migration: %+v\n%s", result, result.Data)
+	}
+
+	// a migrated document is up to date.
+	again, err := MigrateLLPkgConfig(result.Data)
+	if err != nil || again.Changed() || string(again.Data) != want {
+		t.Errorf("unexpected migration of a migrated document: %+v %v", again, err)
+	}
+
+	// other installers keep their options, only the version is recorded.
+	result, err = MigrateLLPkgConfig([]byte(`{"upstream": {"installer": {"name": "plugin", "config": {"options": "*:shared=False"}}, "package": {"name": "cjson", "version": "1.7.18"}}}`))
+	if err != nil || !result.Changed() || len(result.Applied) != 0 || !strings.Contains(string(result.Data), `"options": "*:shared=False"`) {
+		t.Errorf("unexpected migration: %+v %v", result, err)
+	}
+
+	if _, err := MigrateLLPkgConfig([]byte(`{"schemaVersion": 3}`)); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseMigratedLLPkgConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llpkg.cfg")
+	os.WriteFile(path, []byte(`{"upstream": {"installer": {"config": {"options": "*:shared=False"}}, "package": {"name": "cjson", "version": "1.7.18"}}}`), 0644)
+	cfg, err := ParseLLPkgConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Upstream.Package.Linkage != "static" || cfg.Upstream.Installer.Config["options"] != "" || cfg.SchemaVersion != CurrentSchemaVersion {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	os.WriteFile(path, []byte(`{"schemaVersion": 3, "upstream": {"package": {"name": "cjson", "version": "1.7.18"}}}`), 0644)
+	if _, err := ParseLLPkgConfig(path); err == nil || !strings.Contains(err.Error(), "please upgrade") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetPackageVersion(t *testing.T) {
+	b, err := SetPackageVersion([]byte(`{
+  "upstream": {
+    "package": {"name": "cjson", "version": "1.7.18"},
+    "platforms": {"linux": {"package": {"version": "1.7.17"}}}
+  },
+  "dependencies": [{"name": "zlib", "version": "v1.0.0"}]
+}`), "1.7.19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "upstream": {
+    "package": {
+      "name": "cjson",
+      "version": "1.7.19"
+    },
+    "platforms": {
+      "linux": {
+        "package": {
+          "version": "1.7.17"
+        }
+      }
+    }
+  },
+  "dependencies": [
+    {
+      "name": "zlib",
+      "version": "v1.0.0"
+    }
+  ]
+}
+`
+	if string(b) != want {
+		t.Errorf("unexpected config:\nwant %s\ngot  %s", want, string(b))
+	}
+
+	if _, err := SetPackageVersion([]byte(`{"upstream": {}}`), "1.7.19"); err == nil {
+		t.Error("unexpected success without upstream.package")
+	}
+}
@@ -4,41 +4,104 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+
+	"github.com/goplus/llpkgstore/upstream"
 )
 
 // ParseLLPkgConfig reads and parses the llpkg.cfg configuration file
 // Performs the following operations:
 //
 // 1. Opens and reads the configuration file.
-// 2. Deserializes JSON content into LLPkgConfig struct.
-// 3. Applies default values for missing parameters.
-// 4. Returns parsed config or I/O/decoding errors.
+// 2. Migrates documents of an older schema version in memory, see MigrateLLPkgConfig.
+// 3. Strictly deserializes JSON content into LLPkgConfig struct,
+// unknown fields are reported as a *ParseError with their line and column.
+// 4. Applies default values for missing parameters.
+// 5. Resolves relative paths in installer configs against the directory of the file, see resolvePaths.
+// 6. Returns parsed config or I/O/decoding errors.
 func ParseLLPkgConfig(configPath string) (LLPkgConfig, error) {
 	var config LLPkgConfig
-	file, err := os.Open(configPath)
+	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return config, fmt.Errorf("failed to open config file: %w", err)
 	}
-	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&config)
+	migrated, err := MigrateLLPkgConfig(data)
+	if err != nil {
+		// leave syntax errors to decodeJSON, which reports their position.
+		if !json.Valid(data) {
+			migrated = &MigrationResult{From: CurrentSchemaVersion, Data: data}
+		} else {
+			return config, fmt.Errorf("failed to migrate config file %s: %w", configPath, err)
+		}
+	}
+	// positions are only those of the file if nothing but the version has been migrated.
+	if len(migrated.Applied) > 0 {
+		data = migrated.Data
+	}
+	err = decodeJSON(configPath, data, &config, true)
 	if err != nil {
+		if len(migrated.Applied) > 0 {
+			return config, fmt.Errorf("failed to decode config file migrated from schema version %d: %w", migrated.From, err)
+		}
 		return config, fmt.Errorf("failed to decode config file: %w", err)
 	}
 
 	// set default values
 	config = fillDefaults(config)
 
+	dir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return config, err
+	}
+	resolvePaths(config, dir)
+
 	return config, nil
 }
 
+// resolvePaths resolves the relative paths in the installer configs of config against dir in place,
+// so that they don't depend on the working directory.
+// Paths are the config keys the installers describe with Path set, see upstream.ConfigKey.
+func resolvePaths(config LLPkgConfig, dir string) {
+	resolve := func(installer InstallerConfig) {
+		factory, ok := lookupInstaller(installer.Name)
+		if !ok {
+			return
+		}
+		describer, ok := factory(installer.Config).(upstream.ConfigDescriber)
+		if !ok {
+			return
+		}
+		for _, key := range describer.ConfigKeys() {
+			if value := installer.Config[key.Name]; key.Path && value != "" && !filepath.IsAbs(value) {
+				installer.Config[key.Name] = filepath.Join(dir, value)
+			}
+		}
+	}
+	resolve(config.Upstream.Installer)
+	for _, fallback := range config.Upstream.Fallbacks {
+		resolve(fallback.Installer)
+	}
+	for _, platform := range config.Upstream.Platforms {
+		// the config of an override without a name is merged into the installer.
+		if platform.Installer.Name == "" {
+			platform.Installer.Name = config.Upstream.Installer.Name
+		}
+		resolve(platform.Installer)
+	}
+}
+
 // fillDefaults applies default configuration values when parameters are missing.
 // Current defaults:
-// - installer.name: Uses first valid installer type if unspecified.
+// - installer.name: Uses DefaultInstaller if unspecified, also for fallbacks.
 func fillDefaults(config LLPkgConfig) LLPkgConfig {
 	if config.Upstream.Installer.Name == "" {
-		config.Upstream.Installer.Name = ValidInstallers[0]
+		config.Upstream.Installer.Name = DefaultInstaller
+	}
+	for i := range config.Upstream.Fallbacks {
+		if config.Upstream.Fallbacks[i].Installer.Name == "" {
+			config.Upstream.Fallbacks[i].Installer.Name = DefaultInstaller
+		}
 	}
 	return config
 }
@@ -1,7 +1,17 @@
 package config
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -20,3 +30,118 @@ func TestParseLLPkgConfig(t *testing.T) {
 		t.Errorf("Unexpected config: %s", string(json))
 	}
 }
+
+func TestParseLLPkgConfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	cfg := `{"upstream": {
+	"installer": {"name": "local", "config": {"path": "prefix", "libs": "foo"}},
+	"package": {"name": "foo", "version": "1.0.0"},
+	"platforms": {"linux": {"installer": {"config": {"path": "linux/prefix"}}}},
+	"fallbacks": [{"installer": {"name": "local", "config": {"path": "/mirror/foo.tar.gz", "sha256": "00"}}}]
+}}`
+	if err := os.WriteFile(filepath.Join(dir, "llpkg.cfg"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseLLPkgConfig(filepath.Join(dir, "llpkg.cfg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// relative paths are relative to llpkg.cfg, not the working directory
+	if path := config.Upstream.Installer.Config["path"]; path != filepath.Join(dir, "prefix") {
+		t.Errorf("unexpected path: %s", path)
+	}
+	if path := config.Upstream.Platforms["linux"].Installer.Config["path"]; path != filepath.Join(dir, "linux", "prefix") {
+		t.Errorf("unexpected platform path: %s", path)
+	}
+	if path := config.Upstream.Fallbacks[0].Installer.Config["path"]; path != "/mirror/foo.tar.gz" {
+		t.Errorf("unexpected fallback path: %s", path)
+	}
+	if libs := config.Upstream.Installer.Config["libs"]; libs != "foo" {
+		t.Errorf("unexpected libs: %s", libs)
+	}
+}
+
+// writeSourceArchive writes a source archive of an autotools-like project
+// which only installs prebuilt files, and returns its sha256.
+func writeSourceArchive(t *testing.T, fileName string) string {
+	files := []struct {
+		name, content string
+		mode          int64
+	}{
+		{"hello-1.0.0/configure", `#!/bin/sh
+for arg; do
+	case "$arg" in
+	--prefix=*) prefix="${arg#--prefix=}" ;;
+	esac
+done
+printf 'all:\ninstall:\n\tmkdir -p %s/lib %s/include\n\tcp libhello.so %s/lib/\n\tcp hello.h %s/include/\n' "$prefix" "$prefix" "$prefix" "$prefix" > Makefile
+`, 0755},
+		{"hello-1.0.0/hello.h", "int hello(void);\n", 0644},
+		{"hello-1.0.0/libhello.so", "", 0644},
+	}
+	f, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	gw := gzip.NewWriter(io.MultiWriter(f, h))
+	tw := tar.NewWriter(gw)
+	for _, file := range files {
+		tw.WriteHeader(&tar.Header{Name: file.name, Mode: file.mode, Size: int64(len(file.content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(file.content))
+	}
+	tw.Close()
+	gw.Close()
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestParseLLPkgConfigSourceArchive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the source archive is built by a shell script")
+	}
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not found")
+	}
+	dir := t.TempDir()
+	sum := writeSourceArchive(t, filepath.Join(dir, "hello-1.0.0.tar.gz"))
+	cfg := `{"upstream": {
+	"installer": {"name": "source", "config": {"archive": "hello-1.0.0.tar.gz", "sha256": "` + sum + `", "build": "autotools"}},
+	"package": {"name": "hello", "version": "1.0.0"}
+}}`
+	if err := os.WriteFile(filepath.Join(dir, "llpkg.cfg"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseLLPkgConfig(filepath.Join(dir, "llpkg.cfg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archive := config.Upstream.Installer.Config["archive"]; archive != filepath.Join(dir, "hello-1.0.0.tar.gz") {
+		t.Errorf("unexpected archive: %s", archive)
+	}
+
+	// install from another working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	u, err := NewUpstreamFromConfig(config.Upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	result, err := u.Install(context.Background(), outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PCFile() == "" {
+		t.Errorf("unexpected install result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "include", "hello.h")); err != nil {
+		t.Errorf("header not installed: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// PlatformConfig overrides UpstreamConfig on the platforms of its key in UpstreamConfig.Platforms.
+// Empty fields don't override anything.
+type PlatformConfig struct {
+	Installer InstallerConfig `json:"installer,omitempty" jsonschema_description:"installer overrides, a different name replaces the installer and its config, otherwise config keys are merged"`
+	Package   PackageConfig   `json:"package,omitempty" jsonschema:"partial" jsonschema_description:"package field overrides"`
+}
+
+// platformKeys returns the keys of platforms which apply to target,
+// a GOOS key before a GOOS/GOARCH key so the more specific one wins.
+func platformKeys(platforms map[string]PlatformConfig, target upstream.Platform) ([]string, error) {
+	var keys []string
+	for key := range platforms {
+		p, err := upstream.ParsePlatform(key)
+		if err != nil {
+			return nil, err
+		}
+		if p.Matches(target) {
+			keys = append(keys, key)
+		}
+	}
+	// "linux" sorts before "linux/arm64".
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// Resolve returns the upstream config for target with the matching overrides of Platforms applied,
+// first the GOOS one, then the GOOS/GOARCH one. Platforms of the result is nil.
+func (c UpstreamConfig) Resolve(target upstream.Platform) (UpstreamConfig, error) {
+	keys, err := platformKeys(c.Platforms, target)
+	if err != nil {
+		return UpstreamConfig{}, err
+	}
+	resolved := c
+	resolved.Platforms = nil
+	resolved.Installer.Config = maps.Clone(c.Installer.Config)
+	for _, key := range keys {
+		override := c.Platforms[key]
+		if name := override.Installer.Name; name != "" && name != resolved.Installer.Name {
+			resolved.Installer = InstallerConfig{Name: name}
+		}
+		if len(override.Installer.Config) > 0 {
+			if resolved.Installer.Config == nil {
+				resolved.Installer.Config = map[string]string{}
+			}
+			maps.Copy(resolved.Installer.Config, override.Installer.Config)
+		}
+		if override.Package.Name != "" {
+			resolved.Package.Name = override.Package.Name
+		}
+		if override.Package.Version != "" {
+			resolved.Package.Version = override.Package.Version
+		}
+		if override.Package.Linkage != "" {
+			resolved.Package.Linkage = override.Package.Linkage
+		}
+	}
+	return resolved, nil
+}
+
+// SharesLockfile reports whether the upstream config resolved for target is the one resolved for the host,
+// so that the lockfile next to llpkg.cfg, which is locked on the host, pins the package installed for target.
+// A lockfile of another package or installer config would fail the install or pin the wrong graph.
+func (c UpstreamConfig) SharesLockfile(target upstream.Platform) (bool, error) {
+	host := upstream.HostPlatform()
+	if target == host {
+		return true, nil
+	}
+	resolved, err := c.Resolve(target)
+	if err != nil {
+		return false, err
+	}
+	hostResolved, err := c.Resolve(host)
+	if err != nil {
+		return false, err
+	}
+	return resolved.Package == hostResolved.Package &&
+		resolved.Installer.Name == hostResolved.Installer.Name &&
+		maps.Equal(resolved.Installer.Config, hostResolved.Installer.Config), nil
+}
+
+// NewUpstreamForPlatform creates an Upstream instance installing the package for target,
+// from the configuration resolved by UpstreamConfig.Resolve.
+// With fallbacks, the installer is an *upstream.Fallback trying the installer first.
+// The installer is configured for target by Upstream.Target.
+// Returns error if the platforms are invalid, unsupported installer type is specified
+// or the installer can't install for target.
+func NewUpstreamForPlatform(upstreamConfig UpstreamConfig, target upstream.Platform) (*upstream.Upstream, error) {
+	resolved, err := upstreamConfig.Resolve(target)
+	if err != nil {
+		return nil, err
+	}
+	installer, err := newInstaller(resolved.Installer)
+	if err != nil {
+		return nil, err
+	}
+	if len(resolved.Fallbacks) > 0 {
+		candidates := []upstream.Candidate{{Installer: installer}}
+		for _, fallback := range resolved.Fallbacks {
+			installer, err := newInstaller(fallback.Installer)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, upstream.Candidate{Installer: installer, Package: fallback.Package})
+		}
+		installer = upstream.NewFallback(candidates...)
+	}
+	u := &upstream.Upstream{
+		Installer: installer,
+		Pkg: upstream.Package{
+			Name:    resolved.Package.Name,
+			Version: resolved.Package.Version,
+			Linkage: resolved.Package.Linkage,
+		},
+		Platform: target,
+	}
+	if err := u.Target(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// newInstaller creates the installer of config.
+func newInstaller(config InstallerConfig) (upstream.Installer, error) {
+	factory, ok := lookupInstaller(config.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown upstream installer: %s (valid options: %v)",
+			config.Name, ValidInstallers())
+	}
+	return factory(config.Config), nil
+}
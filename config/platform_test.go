@@ -0,0 +1,238 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// fakeInstaller installs nothing, it records its name and config for resolution tests.
+type fakeInstaller struct {
+	name   string
+	config map[string]string
+}
+
+func (f *fakeInstaller) Name() string              { return f.name }
+func (f *fakeInstaller) Config() map[string]string { return f.config }
+func (f *fakeInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	return &upstream.InstallResult{}, nil
+}
+func (f *fakeInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	return nil, nil
+}
+
+// targetFakeInstaller is a fakeInstaller which can install for other platforms.
+type targetFakeInstaller struct {
+	fakeInstaller
+	platform upstream.Platform
+}
+
+func (f *targetFakeInstaller) Target(platform upstream.Platform) error {
+	f.platform = platform
+	return nil
+}
+
+func init() {
+	for _, name := range []string{"fake-a", "fake-b"} {
+		upstream.Register(name, func(config map[string]string) upstream.Installer {
+			return &targetFakeInstaller{fakeInstaller: fakeInstaller{name: name, config: config}}
+		})
+	}
+	upstream.Register("fake-host", func(config map[string]string) upstream.Installer {
+		return &fakeInstaller{name: "fake-host", config: config}
+	})
+}
+
+func platformTestConfig() UpstreamConfig {
+	return UpstreamConfig{
+		Installer: InstallerConfig{Name: "fake-a", Config: map[string]string{"options": "shared=True", "remote": "center"}},
+		Package:   PackageConfig{Name: "zlib", Version: "1.3.1"},
+		Platforms: map[string]PlatformConfig{
+			"linux": {
+				Installer: InstallerConfig{Config: map[string]string{"options": "fPIC=True"}},
+			},
+			"linux/arm64": {
+				Installer: InstallerConfig{Config: map[string]string{"remote": "mirror"}},
+				Package:   PackageConfig{Linkage: upstream.LinkageStatic},
+			},
+			"darwin": {
+				Installer: InstallerConfig{Name: "fake-b", Config: map[string]string{"formula": "zlib"}},
+				Package:   PackageConfig{Name: "zlib-ng"},
+			},
+		},
+	}
+}
+
+func TestNewUpstreamForPlatform(t *testing.T) {
+	cfg := platformTestConfig()
+	tests := []struct {
+		platform  string
+		installer string
+		config    map[string]string
+		pkg       upstream.Package
+	}{
+		{"windows/amd64", "fake-a", map[string]string{"options": "shared=True", "remote": "center"},
+			upstream.Package{Name: "zlib", Version: "1.3.1"}},
+		{"linux/amd64", "fake-a", map[string]string{"options": "fPIC=True", "remote": "center"},
+			upstream.Package{Name: "zlib", Version: "1.3.1"}},
+		{"linux/arm64", "fake-a", map[string]string{"options": "fPIC=True", "remote": "mirror"},
+			upstream.Package{Name: "zlib", Version: "1.3.1", Linkage: upstream.LinkageStatic}},
+		{"darwin/arm64", "fake-b", map[string]string{"formula": "zlib"},
+			upstream.Package{Name: "zlib-ng", Version: "1.3.1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			platform, err := upstream.ParsePlatform(tt.platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := NewUpstreamForPlatform(cfg, platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Installer.Name() != tt.installer || !maps.Equal(u.Installer.Config(), tt.config) {
+				t.Errorf("unexpected installer: %s %v", u.Installer.Name(), u.Installer.Config())
+			}
+			if u.Pkg != tt.pkg {
+				t.Errorf("unexpected package: %+v", u.Pkg)
+			}
+			if u.TargetPlatform() != platform {
+				t.Errorf("unexpected platform: %v", u.TargetPlatform())
+			}
+			// the installer is configured for the target unless it's the host
+			if installer := u.Installer.(*targetFakeInstaller); platform != upstream.HostPlatform() && installer.platform != platform {
+				t.Errorf("installer not configured for %v: %v", platform, installer.platform)
+			}
+		})
+	}
+	// resolving must not modify the base config.
+	if cfg.Installer.Config["options"] != "shared=True" {
+		t.Errorf("base config modified: %v", cfg.Installer.Config)
+	}
+}
+
+func TestNewUpstreamForUnsupportedPlatform(t *testing.T) {
+	cfg := UpstreamConfig{
+		Installer: InstallerConfig{Name: "fake-host"},
+		Package:   PackageConfig{Name: "zlib", Version: "1.3.1"},
+	}
+	if _, err := NewUpstreamForPlatform(cfg, upstream.HostPlatform()); err != nil {
+		t.Fatal(err)
+	}
+	target := upstream.Platform{GOOS: "linux", GOARCH: "arm64"}
+	if target == upstream.HostPlatform() {
+		target.GOARCH = "amd64"
+	}
+	if _, err := NewUpstreamForPlatform(cfg, target); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSharesLockfile(t *testing.T) {
+	overridden := upstream.Platform{GOOS: "freebsd", GOARCH: "arm64"}
+	other := upstream.Platform{GOOS: "netbsd", GOARCH: "arm64"}
+	if host := upstream.HostPlatform(); host == overridden || host == other {
+		t.Skip("the test platforms must not be the host")
+	}
+	cfg := UpstreamConfig{
+		Installer: InstallerConfig{Name: "fake-a", Config: map[string]string{"options": "shared=True"}},
+		Package:   PackageConfig{Name: "zlib", Version: "1.3.1"},
+		Platforms: map[string]PlatformConfig{
+			"freebsd/arm64": {Package: PackageConfig{Linkage: upstream.LinkageStatic}},
+			// overriding a key with the same value resolves the same config
+			"netbsd": {Installer: InstallerConfig{Config: map[string]string{"options": "shared=True"}}},
+		},
+	}
+	tests := []struct {
+		platform upstream.Platform
+		shared   bool
+	}{
+		{upstream.HostPlatform(), true},
+		{other, true},
+		{overridden, false},
+	}
+	for _, tt := range tests {
+		shared, err := cfg.SharesLockfile(tt.platform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shared != tt.shared {
+			t.Errorf("%v: unexpected result: %v", tt.platform, shared)
+		}
+	}
+
+	// an installer config override for the platform
+	cfg.Platforms["freebsd/arm64"] = PlatformConfig{Installer: InstallerConfig{Config: map[string]string{"remote": "mirror"}}}
+	if shared, err := cfg.SharesLockfile(overridden); err != nil || shared {
+		t.Errorf("unexpected result: %v %v", shared, err)
+	}
+}
+
+func TestResolveInvalidPlatform(t *testing.T) {
+	cfg := platformTestConfig()
+	cfg.Platforms["linux/amd46"] = PlatformConfig{}
+	if _, err := NewUpstreamForPlatform(cfg, upstream.HostPlatform()); err == nil || !strings.Contains(err.Error(), "amd46") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidatePlatforms(t *testing.T) {
+	cfg := LLPkgConfig{Upstream: platformTestConfig()}
+	if err := ValidateLLPkgConfig(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Upstream.Platforms["freebsd"] = PlatformConfig{Package: PackageConfig{Linkage: "dynamic"}}
+	cfg.Upstream.Platforms["windows/arm64"] = PlatformConfig{Installer: InstallerConfig{Name: "fake-c"}}
+	err := ValidateLLPkgConfig(cfg)
+	if err == nil {
+		t.Fatal("unexpected success")
+	}
+	for _, want := range []string{"upstream.platforms[freebsd]: unsupported linkage: dynamic", "upstream.platforms[windows/arm64]: unsupported installer type: fake-c"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+
+	cfg.Upstream.Platforms = map[string]PlatformConfig{"macos": {}}
+	if err := ValidateLLPkgConfig(cfg); err == nil || !strings.Contains(err.Error(), `unknown GOOS "macos"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llpkg.cfg")
+	os.WriteFile(path, []byte(`{
+  "upstream": {
+    "installer": {"config": {"options": "zlib/*:shared=True"}},
+    "package": {"name": "zlib", "version": "1.3.1"},
+    "platforms": {
+      "linux/arm64": {"installer": {"config": {"options": "zlib/*:shared=True,zlib/*:fPIC=False"}}}
+    }
+  }
+}`), 0644)
+	cfg, err := ParseLLPkgConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arm64, _ := upstream.ParsePlatform("linux/arm64")
+	resolved, err := cfg.Upstream.Resolve(arm64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Installer.Config["options"] != "zlib/*:shared=True,zlib/*:fPIC=False" || resolved.Platforms != nil {
+		t.Errorf("unexpected resolved config: %+v", resolved)
+	}
+
+	// unknown fields of overrides are rejected like any other.
+	os.WriteFile(path, []byte(`{"upstream": {"package": {"name": "zlib", "version": "1.3.1"}, "platforms": {"linux": {"pakcage": {}}}}}`), 0644)
+	if _, err := ParseLLPkgConfig(path); err == nil || !strings.Contains(err.Error(), "pakcage") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
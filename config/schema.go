@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// SchemaDraft is the JSON Schema dialect of Schema.
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns the JSON Schema of llpkg.cfg, generated from LLPkgConfig.
+//
+// Fields are described by their jsonschema tag, a comma-separated list of
+// required, default=value, enum=value (repeatable), minLength=n and partial,
+// which makes the fields of a struct optional, and their
+// jsonschema_description tag. The config keys of the registered installers
+// which implement upstream.ConfigDescriber are checked depending on installer.name.
+func Schema() ([]byte, error) {
+	schema := schemaOf(reflect.TypeOf(LLPkgConfig{}))
+	schema["$schema"] = SchemaDraft
+	schema["title"] = "llpkg.cfg"
+	schema["description"] = "Config file of an llpkg"
+
+	installer := schema["properties"].(map[string]any)["upstream"].(map[string]any)["properties"].(map[string]any)["installer"].(map[string]any)
+	var conditions []any
+	for _, name := range upstream.List() {
+		factory, _ := upstream.Lookup(name)
+		describer, ok := factory(nil).(upstream.ConfigDescriber)
+		if !ok {
+			continue
+		}
+		properties := map[string]any{}
+		var required []string
+		for _, key := range describer.ConfigKeys() {
+			properties[key.Name] = map[string]any{"type": "string", "description": key.Description}
+			if key.Required {
+				required = append(required, key.Name)
+			}
+		}
+		config := map[string]any{
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		then := map[string]any{
+			"properties": map[string]any{"config": config},
+		}
+		if len(required) > 0 {
+			config["required"] = required
+			then["required"] = []string{"config"}
+		}
+		cond := map[string]any{
+			"properties": map[string]any{"name": map[string]any{"const": name}},
+		}
+		// the default installer also applies when installer.name is missing.
+		if name != DefaultInstaller {
+			cond["required"] = []string{"name"}
+		}
+		conditions = append(conditions, map[string]any{"if": cond, "then": then})
+	}
+	if len(conditions) > 0 {
+		installer["allOf"] = conditions
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaOf returns the JSON Schema of the Go type t.
+func schemaOf(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := jsonName(field)
+			if name == "" {
+				continue
+			}
+			prop := schemaOf(field.Type)
+			if desc := field.Tag.Get("jsonschema_description"); desc != "" {
+				prop["description"] = desc
+			}
+			var enum []string
+			for _, opt := range strings.Split(field.Tag.Get("jsonschema"), ",") {
+				key, value, _ := strings.Cut(opt, "=")
+				switch key {
+				case "required":
+					required = append(required, name)
+				case "default":
+					prop["default"] = value
+				case "enum":
+					enum = append(enum, value)
+				case "minLength":
+					prop["minLength"], _ = strconv.Atoi(value)
+				case "partial":
+					delete(prop, "required")
+				}
+			}
+			if enum != nil {
+				prop["enum"] = enum
+			}
+			properties[name] = prop
+		}
+		schema := map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if required != nil {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.Interface:
+		return map[string]any{}
+	}
+	return map[string]any{"type": jsonKind(t)}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Properties struct {
+			Upstream struct {
+				Properties struct {
+					Installer struct {
+						AllOf []any `json:"allOf"`
+					} `json:"installer"`
+					Package struct {
+						Required   []string `json:"required"`
+						Properties struct {
+							Linkage struct {
+								Enum []string `json:"enum"`
+							} `json:"linkage"`
+						} `json:"properties"`
+					} `json:"package"`
+				} `json:"properties"`
+			} `json:"upstream"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		t.Fatal(err)
+	}
+	upstream := s.Properties.Upstream.Properties
+	if len(upstream.Installer.AllOf) != 4 {
+		t.Errorf("expected a condition per builtin installer, got %v", upstream.Installer.AllOf)
+	}
+	if pkg := upstream.Package; len(pkg.Required) != 2 || len(pkg.Properties.Linkage.Enum) != 2 {
+		t.Errorf("unexpected package schema: %+v", pkg)
+	}
+
+	// the published schema must be regenerated when the config changes
+	published, err := os.ReadFile("../docs/llpkg.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(published), schema) {
+		t.Error("docs/llpkg.schema.json is out of date, run: go run ./cmd/llpkgstore config schema > docs/llpkg.schema.json")
+	}
+}
@@ -1,33 +1,198 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/actions/versions"
+	"github.com/goplus/llpkgstore/upstream"
+	"golang.org/x/mod/semver"
 )
 
 // ValidateLLPkgConfig performs structural validation of the configuration.
-// Validates upstream installer and package metadata requirements.
+// Validates upstream installer and package metadata requirements,
+// also as resolved for every platform of upstream.platforms,
+// and returns every problem found joined.
 func ValidateLLPkgConfig(config LLPkgConfig) error {
-	return validateUpstreamConfig(config.Upstream)
+	err := validateUpstreamConfig(config.Upstream)
+	errs := []error{err, validateDependencies(config)}
+	platforms := map[string]upstream.Platform{}
+	for _, key := range sortedKeys(config.Upstream.Platforms) {
+		platform, err := upstream.ParsePlatform(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unsupported platform: upstream.platforms: %w", err))
+			continue
+		}
+		platforms[key] = platform
+	}
+	if len(platforms) < len(config.Upstream.Platforms) {
+		// overrides can't be resolved with invalid keys.
+		return errors.Join(errs...)
+	}
+	baseErrs := unwrapErrors(err)
+	for _, key := range sortedKeys(platforms) {
+		resolved, err := config.Upstream.Resolve(platforms[key])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, err := range unwrapErrors(validateUpstreamConfig(resolved)) {
+			// the problems of the base config aren't repeated for every platform.
+			if !slices.ContainsFunc(baseErrs, func(base error) bool { return base.Error() == err.Error() }) {
+				errs = append(errs, fmt.Errorf("upstream.platforms[%s]: %w", key, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateDependencies checks the declared llpkg dependencies without looking them up,
+// see the CheckPR of the llpkg repository for that.
+func validateDependencies(config LLPkgConfig) error {
+	var errs []error
+	var names []string
+	for i, dep := range config.Dependencies {
+		switch {
+		case dep.Name == "":
+			errs = append(errs, fmt.Errorf("missing required dependency name: dependencies[%d].name cannot be empty", i))
+		case dep.Name == config.Upstream.Package.Name:
+			errs = append(errs, fmt.Errorf("invalid dependency: dependencies[%d] is the llpkg %s itself", i, dep.Name))
+		case slices.Contains(names, dep.Name):
+			errs = append(errs, fmt.Errorf("duplicate dependency: dependencies[%d] %s is declared more than once", i, dep.Name))
+		}
+		names = append(names, dep.Name)
+		if !semver.IsValid(dep.Version) || semver.Canonical(dep.Version) != dep.Version {
+			errs = append(errs, fmt.Errorf("invalid dependency version: dependencies[%d].version %q isn't a mapped version like v1.0.0", i, dep.Version))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unwrapErrors returns the errors joined in err.
+func unwrapErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	if err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
 }
 
 // validateUpstreamConfig performs detailed validation of upstream configuration parameters.
 func validateUpstreamConfig(config UpstreamConfig) error {
+	var errs []error
 	// 1. check if upstream installer is valid
+	factory, ok := lookupInstaller(config.Installer.Name)
 	if config.Installer.Name == "" {
-		return fmt.Errorf("missing required installer type: upstream.installer.name must be specified")
-	}
-	if !slices.Contains(ValidInstallers, config.Installer.Name) {
-		return fmt.Errorf("unsupported installer type: %s (valid options: %v)", config.Installer.Name, ValidInstallers)
+		errs = append(errs, fmt.Errorf("missing required installer type: upstream.installer.name must be specified"))
+	} else if !ok {
+		errs = append(errs, fmt.Errorf("unsupported installer type: %s (valid options: %v)", config.Installer.Name, ValidInstallers()))
 	}
 
 	// 2. check if package is valid
 	if config.Package.Name == "" {
-		return fmt.Errorf("missing required package identifier: upstream.package.name cannot be empty")
+		errs = append(errs, fmt.Errorf("missing required package identifier: upstream.package.name cannot be empty"))
 	}
 	if config.Package.Version == "" {
-		return fmt.Errorf("missing required version specification: upstream.package.version cannot be empty")
+		errs = append(errs, fmt.Errorf("missing required version specification: upstream.package.version cannot be empty"))
+	} else if err := validateVersion(config.Package.Version); err != nil {
+		errs = append(errs, err)
+	}
+	switch config.Package.Linkage {
+	case "", upstream.LinkageShared, upstream.LinkageStatic:
+	default:
+		errs = append(errs, fmt.Errorf("unsupported linkage: %s (valid options: %s, %s)",
+			config.Package.Linkage, upstream.LinkageShared, upstream.LinkageStatic))
+	}
+
+	// 3. check the config of the installer
+	if ok {
+		pkg := upstream.Package{
+			Name:    config.Package.Name,
+			Version: config.Package.Version,
+			Linkage: config.Package.Linkage,
+		}
+		errs = append(errs, validateInstallerConfig(factory(config.Installer.Config), pkg)...)
+	}
+
+	// 4. check the fallbacks like the installer
+	for i, fallback := range config.Fallbacks {
+		factory, ok := lookupInstaller(fallback.Installer.Name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unsupported installer type: upstream.fallbacks[%d]: %s (valid options: %v)",
+				i, fallback.Installer.Name, ValidInstallers()))
+			continue
+		}
+		pkg := upstream.Package{
+			Name:    config.Package.Name,
+			Version: config.Package.Version,
+			Linkage: config.Package.Linkage,
+		}
+		if fallback.Package != "" {
+			pkg.Name = fallback.Package
+		}
+		for _, err := range validateInstallerConfig(factory(fallback.Installer.Config), pkg) {
+			errs = append(errs, fmt.Errorf("upstream.fallbacks[%d]: %w", i, err))
+		}
 	}
+	return errors.Join(errs...)
+}
 
+// validateVersion checks that version is a stable semantic version, which version mapping relies on.
+func validateVersion(version string) error {
+	v := versions.ToSemVer(version)
+	if !semver.IsValid(v) {
+		return fmt.Errorf("invalid version: upstream.package.version %s isn't a semantic version like 1.7.18", version)
+	}
+	if semver.Prerelease(v) != "" {
+		return fmt.Errorf("invalid version: upstream.package.version %s is a pre-release version", version)
+	}
 	return nil
 }
+
+// validateInstallerConfig checks the config of installer with the help of upstream.ConfigDescriber
+// and upstream.ConfigValidator, installers implementing neither accept any config.
+func validateInstallerConfig(installer upstream.Installer, pkg upstream.Package) []error {
+	var errs []error
+	config := installer.Config()
+	if describer, ok := installer.(upstream.ConfigDescriber); ok {
+		var names []string
+		for _, key := range describer.ConfigKeys() {
+			names = append(names, key.Name)
+			if key.Required && config[key.Name] == "" {
+				errs = append(errs, fmt.Errorf("missing required config: upstream.installer.config.%s must be specified for installer %s",
+					key.Name, installer.Name()))
+			}
+		}
+		for _, key := range sortedKeys(config) {
+			if slices.Contains(names, key) {
+				continue
+			}
+			err := fmt.Errorf("unknown config key: upstream.installer.config.%s of installer %s (valid keys: %s)",
+				key, installer.Name(), strings.Join(names, ", "))
+			if suggestion := suggest(key, names); suggestion != "" {
+				err = fmt.Errorf("%w (did you mean %q?)", err, suggestion)
+			}
+			errs = append(errs, err)
+		}
+	}
+	if validator, ok := installer.(upstream.ConfigValidator); ok {
+		if err := validator.ValidateConfig(pkg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
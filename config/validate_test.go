@@ -1,6 +1,12 @@
 package config
 
-import "testing"
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
 
 func TestValidateLLPkgConfig(t *testing.T) {
 	config, err := ParseLLPkgConfig("../_demo/llpkg.cfg")
@@ -12,3 +18,199 @@ func TestValidateLLPkgConfig(t *testing.T) {
 		t.Errorf("Error validating config: %v", err)
 	}
 }
+
+type registeredInstaller struct {
+	config map[string]string
+}
+
+func (r *registeredInstaller) Name() string              { return "registered" }
+func (r *registeredInstaller) Config() map[string]string { return r.config }
+func (r *registeredInstaller) Install(pkg upstream.Package, outputDir string) (string, error) {
+	return pkg.Name, nil
+}
+func (r *registeredInstaller) Search(pkg upstream.Package) ([]string, error) { return nil, nil }
+
+func TestValidateRegisteredInstaller(t *testing.T) {
+	config := LLPkgConfig{
+		Upstream: UpstreamConfig{
+			Installer: InstallerConfig{Name: "registered-test"},
+			Package:   PackageConfig{Name: "cjson", Version: "1.7.18"},
+		},
+	}
+	err := ValidateLLPkgConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "conan") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := NewUpstreamFromConfig(config.Upstream); err == nil {
+		t.Error("unexpected upstream for unregistered installer")
+	}
+
+	upstream.Register("registered-test", func(config map[string]string) upstream.Installer {
+		return upstream.FromLegacy(&registeredInstaller{config: config})
+	})
+
+	if !slices.Contains(ValidInstallers(), "registered-test") {
+		t.Errorf("unexpected valid installers: %v", ValidInstallers())
+	}
+	if err := ValidateLLPkgConfig(config); err != nil {
+		t.Errorf("Error validating config: %v", err)
+	}
+	u, err := NewUpstreamFromConfig(config.Upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Installer.Name() != "registered" || u.Pkg.Name != "cjson" {
+		t.Errorf("unexpected upstream: %v", u)
+	}
+}
+
+func TestValidateLinkage(t *testing.T) {
+	config := LLPkgConfig{
+		Upstream: UpstreamConfig{
+			Installer: InstallerConfig{Name: "conan"},
+			Package:   PackageConfig{Name: "cjson", Version: "1.7.18", Linkage: upstream.LinkageStatic},
+		},
+	}
+	if err := ValidateLLPkgConfig(config); err != nil {
+		t.Errorf("Error validating config: %v", err)
+	}
+	u, err := NewUpstreamFromConfig(config.Upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.Pkg.Static() {
+		t.Errorf("unexpected package: %+v", u.Pkg)
+	}
+
+	config.Upstream.Package.Linkage = "dynamic"
+	if err := ValidateLLPkgConfig(config); err == nil || !strings.Contains(err.Error(), "linkage") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	for version, want := range map[string]string{
+		"1.7.18":      "",
+		"2.0":         "",
+		"1.7.18-beta": "pre-release",
+		"1.1.1w":      "semantic version",
+	} {
+		config := LLPkgConfig{
+			Upstream: UpstreamConfig{
+				Installer: InstallerConfig{Name: "conan"},
+				Package:   PackageConfig{Name: "cjson", Version: version},
+			},
+		}
+		err := ValidateLLPkgConfig(config)
+		if want == "" && err != nil || want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("%s: unexpected error: %v", version, err)
+		}
+	}
+}
+
+func TestValidateInstallerConfig(t *testing.T) {
+	config := LLPkgConfig{
+		Upstream: UpstreamConfig{
+			Installer: InstallerConfig{Name: "local", Config: map[string]string{
+				"pth":              "/tmp/cjson.tar.gz",
+				"strip_components": "x",
+			}},
+			Package: PackageConfig{Name: "cjson", Version: "1.7.18"},
+		},
+	}
+	err := ValidateLLPkgConfig(config)
+	if err == nil {
+		t.Fatal("unexpected success")
+	}
+	for _, want := range []string{
+		"upstream.installer.config.path must be specified",
+		`upstream.installer.config.pth of installer local`,
+		`did you mean "path"?`,
+		"invalid strip_components: x",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q not found in %v", want, err)
+		}
+	}
+
+	config.Upstream.Installer.Config = map[string]string{"path": "/tmp/cjson.tar.gz"}
+	if err := ValidateLLPkgConfig(config); err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	config.Upstream.Installer.Config["sha256"] = "00"
+	if err := ValidateLLPkgConfig(config); err != nil {
+		t.Errorf("Error validating config: %v", err)
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	cfg := LLPkgConfig{Upstream: UpstreamConfig{
+		Installer: InstallerConfig{Name: "fake-a"},
+		Package:   PackageConfig{Name: "zlib", Version: "1.3.1"},
+		Fallbacks: []FallbackConfig{{Installer: InstallerConfig{Name: "fake-b", Config: map[string]string{"dir": "/mirror"}}, Package: "libz"}},
+	}}
+	if err := ValidateLLPkgConfig(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	u, err := NewUpstreamFromConfig(cfg.Upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback, ok := u.Installer.(*upstream.Fallback)
+	if !ok {
+		t.Fatalf("unexpected installer: %T", u.Installer)
+	}
+	candidates := fallback.Candidates()
+	if len(candidates) != 2 || candidates[0].Installer.Name() != "fake-a" ||
+		candidates[1].Installer.Name() != "fake-b" || candidates[1].Package != "libz" ||
+		candidates[1].Installer.Config()["dir"] != "/mirror" {
+		t.Errorf("unexpected candidates: %+v", candidates)
+	}
+
+	cfg.Upstream.Fallbacks = append(cfg.Upstream.Fallbacks, FallbackConfig{Installer: InstallerConfig{Name: "fake-c"}})
+	if err := ValidateLLPkgConfig(cfg); err == nil || !strings.Contains(err.Error(), "upstream.fallbacks[1]: fake-c") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cfg.Upstream.Fallbacks = []FallbackConfig{{Installer: InstallerConfig{Name: "local", Config: map[string]string{"pth": "/mirror"}}}}
+	if err := ValidateLLPkgConfig(cfg); err == nil || !strings.Contains(err.Error(), "upstream.fallbacks[0]: missing required config") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	cfg := LLPkgConfig{
+		Upstream: UpstreamConfig{
+			Installer: InstallerConfig{Name: "conan"},
+			Package:   PackageConfig{Name: "libxml2", Version: "2.13.6"},
+		},
+		Dependencies: []DependencyConfig{{Name: "zlib", Version: "v1.0.0"}},
+	}
+	if err := ValidateLLPkgConfig(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cfg.Dependencies = append(cfg.Dependencies,
+		DependencyConfig{Name: "zlib", Version: "v1.1.0"},
+		DependencyConfig{Name: "libxml2", Version: "1.0.0"})
+	err := ValidateLLPkgConfig(cfg)
+	for _, want := range []string{"dependencies[1] zlib is declared more than once", "dependencies[2] is the llpkg libxml2 itself", `dependencies[2].version "1.0.0"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
+
+func TestDependencyModulePath(t *testing.T) {
+	tests := []struct {
+		dep  DependencyConfig
+		want string
+	}{
+		{DependencyConfig{Name: "zlib", Version: "v0.1.0"}, "github.com/goplus/llpkg/zlib@v0.1.0"},
+		{DependencyConfig{Name: "zlib", Version: "v1.2.0"}, "github.com/goplus/llpkg/zlib@v1.2.0"},
+		{DependencyConfig{Name: "zlib", Version: "v2.0.1"}, "github.com/goplus/llpkg/zlib/v2@v2.0.1"},
+	}
+	for _, tt := range tests {
+		if got := tt.dep.Module(); got != tt.want {
+			t.Errorf("unexpected module of %v: %s", tt.dep, got)
+		}
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"slices"
 	"sort"
 	"strings"
@@ -21,10 +20,6 @@ import (
 // GitHubEvent caches parsed GitHub event data from GITHUB_EVENT_PATH
 var GitHubEvent = sync.OnceValue(parseGitHubEvent)
 
-// In our previous design, each platform should generate *_{OS}_{Arch}.go file
-// Feb 12th, this design revoked, still keep the code.
-var currentSuffix = runtime.GOOS + "_" + runtime.GOARCH
-
 // must panics if the error is non-nil, halting execution
 func must(err error) {
 	if err != nil {
@@ -133,38 +128,63 @@ func isValidLLPkg(files []os.DirEntry) bool {
 	return hasLlcppg && hasLLPkg
 }
 
-// checkLegacyVersion validates versioning strategy for legacy package submissions
-// Ensures semantic versioning compliance and proper branch maintenance strategy
-func checkLegacyVersion(ver *versions.Versions, cfg config.LLPkgConfig, mappedVersion string, isLegacy bool) {
-	if slices.Contains(ver.GoVersions(cfg.Upstream.Package.Name), mappedVersion) {
-		panic("repeat semver")
+// VersionKind classifies a C version against the C versions already mapped in llpkgstore.json,
+// following the branch maintenance strategy.
+type VersionKind int
+
+const (
+	// VersionUnknown is a C version which doesn't follow semver, it can't be classified.
+	VersionUnknown VersionKind = iota
+	// VersionLatest is newer than every mapped C version, it's submitted to main.
+	VersionLatest
+	// VersionLegacy is older than the latest mapped C version, it's submitted to a release branch.
+	VersionLegacy
+	// VersionHistorical is older than a mapped patch version of the same major and minor version,
+	// it can't be submitted at all.
+	VersionHistorical
+)
+
+func (k VersionKind) String() string {
+	switch k {
+	case VersionLatest:
+		return "latest"
+	case VersionLegacy:
+		return "legacy"
+	case VersionHistorical:
+		return "historical"
 	}
-	vers := ver.CVersions(cfg.Upstream.Package.Name)
-	currentVersion := versions.ToSemVer(cfg.Upstream.Package.Version)
+	return "unknown"
+}
 
-	// skip when we're the only latest version or C version doesn't follow semver.
-	if len(vers) == 0 || !semver.IsValid(currentVersion) {
-		return
+func (k VersionKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// VersionClass is the classification of a C version by ClassifyCVersion.
+type VersionClass struct {
+	Kind VersionKind
+	// Previous is the closest mapped C version older than the classified legacy version in semver form,
+	// empty if there is none.
+	Previous string
+}
+
+// ClassifyCVersion classifies cversion of clib against the C versions mapped in ver.
+func ClassifyCVersion(ver *versions.Versions, clib, cversion string) VersionClass {
+	vers := ver.CVersions(clib)
+	currentVersion := versions.ToSemVer(cversion)
+
+	if !semver.IsValid(currentVersion) {
+		return VersionClass{Kind: VersionUnknown}
+	}
+	// we're the only latest version
+	if len(vers) == 0 {
+		return VersionClass{Kind: VersionLatest}
 	}
 
 	sort.Sort(versions.ByVersionDescending(vers))
 
-	latestVersion := vers[0]
-
-	isLatest := semver.Compare(currentVersion, latestVersion) > 0
-	// fast-path: we're the latest version
-	if isLatest {
-		// case1: we're the latest version, but mapped version is not latest, invalid.
-		// example: all version: 1.8.1 => v1.2.0 1.7.1 => v1.1.0 current: 1.9.1 => v1.0.0
-		if semver.Compare(ver.LatestGoVersion(cfg.Upstream.Package.Name), mappedVersion) > 0 {
-			panic("mapped version should not less than the legacy one.")
-		}
-		return
-	} else if !isLegacy {
-		// case2: if we're legacy version, the pr is submited to main, that's invalid.
-		// in the most common case, it should be conflict.
-		// however, consider about the extraordinary case.
-		panic("legacy version MUST not submit to main branch")
+	if semver.Compare(currentVersion, vers[0]) > 0 {
+		return VersionClass{Kind: VersionLatest}
 	}
 
 	// find the closest verion which is smaller than us.
@@ -174,38 +194,83 @@ func checkLegacyVersion(ver *versions.Versions, cfg config.LLPkgConfig, mappedVe
 
 	hasClosestSemver := i < len(vers) &&
 		semver.Compare(vers[i], currentVersion) < 0
-	// case3: we're the smallest version
+	// we're the smallest version
 	// example: latest: 1.6.1 maintain: 1.5.1, that's valid
 	if !hasClosestSemver {
-		return
+		return VersionClass{Kind: VersionLegacy}
 	}
 
-	// case4: the major and minor version of the previous version is same,
-	// which means we're not the latest patch version, invalid.
-	// example: all version: 1.6.1 1.5.3 1.5.1 current: 1.5.2, so the previous one is 1.5.3, that's invalid
+	// the major and minor version of the previous version is same,
+	// which means we're not the latest patch version.
+	// example: all version: 1.6.1 1.5.3 1.5.1 current: 1.5.2, so the previous one is 1.5.3
 	previousVersion := vers[i-1]
 
 	if semver.MajorMinor(previousVersion) == semver.MajorMinor(currentVersion) &&
 		semver.Compare(previousVersion, currentVersion) > 0 {
+		return VersionClass{Kind: VersionHistorical}
+	}
+	return VersionClass{Kind: VersionLegacy, Previous: vers[i]}
+}
+
+// checkLegacyVersion validates versioning strategy for legacy package submissions
+// Ensures semantic versioning compliance and proper branch maintenance strategy
+func checkLegacyVersion(ver *versions.Versions, cfg config.LLPkgConfig, mappedVersion string, isLegacy bool) {
+	if slices.Contains(ver.GoVersions(cfg.Upstream.Package.Name), mappedVersion) {
+		panic("repeat semver")
+	}
+	class := ClassifyCVersion(ver, cfg.Upstream.Package.Name, cfg.Upstream.Package.Version)
+
+	switch class.Kind {
+	case VersionUnknown:
+		// skip when C version doesn't follow semver.
+		return
+	case VersionLatest:
+		// case1: we're the latest version, but mapped version is not latest, invalid.
+		// example: all version: 1.8.1 => v1.2.0 1.7.1 => v1.1.0 current: 1.9.1 => v1.0.0
+		if semver.Compare(ver.LatestGoVersion(cfg.Upstream.Package.Name), mappedVersion) > 0 {
+			panic("mapped version should not less than the legacy one.")
+		}
+		return
+	}
+	if !isLegacy {
+		// case2: if we're legacy version, the pr is submited to main, that's invalid.
+		// in the most common case, it should be conflict.
+		// however, consider about the extraordinary case.
+		panic("legacy version MUST not submit to main branch")
+	}
+
+	switch {
+	case class.Kind == VersionHistorical:
+		// case4: we're not the latest patch version for current major and minor, invalid.
 		panic(`cannot submit a historical legacy version.
 	for more details: https://github.com/goplus/llpkgstore/blob/main/docs/llpkgstore.md#branch-maintenance-strategy`)
+	case class.Previous == "":
+		// case3: we're the smallest version, valid.
+		return
 	}
 
 	// case5: we're the latest patch version for current major and minor, check the mapped version
 	// our mapped version should be larger than the closest one.
 	// example: current submit: 1.5.2 => v1.1.1, closest minor: 1.4.1 => v1.1.0, valid.
-	originalVersion := ver.SearchBySemVer(cfg.Upstream.Package.Name, vers[i])
+	closest := closestMappedVersion(ver, cfg.Upstream.Package.Name, class.Previous)
+
+	if semver.Compare(closest, mappedVersion) > 0 {
+		panic("mapped version should not less than the legacy one.")
+	}
+}
+
+// closestMappedVersion returns the latest Go version mapped to previous,
+// the semver form of a mapped C version.
+func closestMappedVersion(ver *versions.Versions, clib, previous string) string {
+	originalVersion := ver.SearchBySemVer(clib, previous)
 	if originalVersion == "" {
 		panic("cannot find original C version from semver, this should not happen.")
 	}
-	closestMappedVersion := ver.LatestGoVersionForCVersion(cfg.Upstream.Package.Name, originalVersion)
-	if closestMappedVersion == "" {
+	goVersion := ver.LatestGoVersionForCVersion(clib, originalVersion)
+	if goVersion == "" {
 		panic("cannot find latest Go version from C version, this should not happen.")
 	}
-
-	if semver.Compare(closestMappedVersion, mappedVersion) > 0 {
-		panic("mapped version should not less than the legacy one.")
-	}
+	return goVersion
 }
 
 // Setenv writes environment variables to GITHUB_ENV for GitHub Actions consumption
@@ -16,6 +16,8 @@ import (
 	"github.com/goplus/llpkgstore/internal/actions/file"
 	"github.com/goplus/llpkgstore/internal/actions/pc"
 	"github.com/goplus/llpkgstore/internal/actions/versions"
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/goplus/llpkgstore/upstream/cache"
 )
 
 const (
@@ -41,8 +43,8 @@ func regex(packageName string) *regexp.Regexp {
 	return regexp.MustCompile(fmt.Sprintf(regexString, packageName))
 }
 
-func binaryZip(packageName string) string {
-	return fmt.Sprintf("%s_%s.zip", packageName, currentSuffix)
+func binaryZip(packageName string, platform upstream.Platform) string {
+	return fmt.Sprintf("%s_%s_%s.zip", packageName, platform.GOOS, platform.GOARCH)
 }
 
 // DefaultClient provides GitHub API client capabilities with authentication for Actions workflows
@@ -406,11 +408,23 @@ func (d *DefaultClient) checkVersion(ver *versions.Versions, cfg config.LLPkgCon
 	checkLegacyVersion(ver, cfg, mappedVersion, isLegacy)
 }
 
+// checkLockfile ensures the lockfile of installers supporting lockfiles exists and is up to date,
+// so that the release installs exactly what the PR has been verified with.
+func (d *DefaultClient) checkLockfile(ctx context.Context, path string, cfg config.LLPkgConfig) {
+	uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
+	must(err)
+	defer uc.Close()
+	must(uc.Prepare(ctx))
+	if err := uc.CheckLock(ctx, path); err != nil {
+		panic(fmt.Sprintf("%v, run llpkgstore lock %s to update it", err, path))
+	}
+}
+
 // CheckPR validates PR changes and returns affected packages
 // Returns:
 //
 //	[]string: List of affected package paths
-func (d *DefaultClient) CheckPR() []string {
+func (d *DefaultClient) CheckPR(ctx context.Context) []string {
 	// build a file path map
 	pathMap := map[string][]string{}
 	for _, path := range Changes() {
@@ -448,6 +462,8 @@ func (d *DefaultClient) CheckPR() []string {
 			panic("directory name is not equal to package name in llpkg.cfg")
 		}
 		d.checkVersion(ver, cfg)
+		must(checkDependencies(ver, cfg))
+		d.checkLockfile(ctx, path, cfg)
 
 		allPaths = append(allPaths, path)
 	}
@@ -502,6 +518,8 @@ func (d *DefaultClient) Postprocessing() {
 	// write it to llpkgstore.json
 	ver := versions.Read("llpkgstore.json")
 	ver.Write(clib, cfg.Upstream.Package.Version, mappedVersion)
+	// sign it so that llgo can verify it, see metadata.WithTrustedKeys
+	must(SignMetadata("llpkgstore.json"))
 
 	// we have finished tagging the commit, safe to remove the branch
 	if branchName, isLegacy := d.isLegacyVersion(); isLegacy {
@@ -510,7 +528,9 @@ func (d *DefaultClient) Postprocessing() {
 	// move to website in Github Action...
 }
 
-func (d *DefaultClient) Release() {
+// Release installs the package of the mapped version of the latest commit for platform,
+// with the overrides of llpkg.cfg for it, and uploads it to the release of the version.
+func (d *DefaultClient) Release(ctx context.Context, installCache *cache.Cache, platform upstream.Platform) {
 	version := d.mappedVersion()
 	// skip it when no mapped version is found
 	if version == "" {
@@ -522,12 +542,24 @@ func (d *DefaultClient) Release() {
 	cfg, err := config.ParseLLPkgConfig(filepath.Join(clib, "llpkg.cfg"))
 	must(err)
 
-	uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
+	uc, err := config.NewUpstreamForPlatform(cfg.Upstream, platform)
 	must(err)
+	defer uc.Close()
+	must(uc.Prepare(ctx))
+	// the lockfile is locked for the host, it doesn't pin a package overridden for platform.
+	shared, err := cfg.Upstream.SharesLockfile(platform)
+	must(err)
+	var lockfile string
+	if shared {
+		lockfile, err = uc.UseLockfileIn(clib)
+		must(err)
+	}
 
 	tempDir, _ := os.MkdirTemp("", "llpkg-tool")
-	_, err = uc.Installer.Install(uc.Pkg, tempDir)
+	result, err := installCache.Install(ctx, uc, lockfile, tempDir)
 	must(err)
+	// never release shared libraries for a static llpkg
+	must(result.CheckLinkage(uc.Pkg.Linkage))
 
 	pkgConfigDir := filepath.Join(tempDir, "lib", "pkgconfig")
 	// clear exist .pc
@@ -535,24 +567,24 @@ func (d *DefaultClient) Release() {
 
 	err = os.Mkdir(pkgConfigDir, 0777)
 	must(err)
-	pcFiles := filepath.Join(tempDir, "*.pc")
 
-	matches, _ := filepath.Glob(pcFiles)
-
-	if len(matches) == 0 {
+	if len(result.PCFiles) == 0 {
 		panic("no pc file found, this should not happen")
 	}
 	// generate pc template to lib/pkgconfig
-	for _, matchPC := range matches {
-		err := pc.GenerateTemplateFromPC(matchPC, pkgConfigDir)
+	for _, pcFile := range result.PCFiles {
+		err := pc.GenerateTemplateFromPC(pcFile, pkgConfigDir)
 		must(err)
 		// okay, safe to remove old pc
-		os.Remove(matchPC)
+		os.Remove(pcFile)
 	}
 
-	file.RemovePattern(filepath.Join(tempDir, "*.sh"))
+	for _, generatedFile := range result.GeneratedFiles {
+		os.Remove(generatedFile)
+	}
 
-	zipFilePath, _ := filepath.Abs(binaryZip(uc.Pkg.Name))
+	// the asset is named after the base package, which platform overrides may rename.
+	zipFilePath, _ := filepath.Abs(binaryZip(cfg.Upstream.Package.Name, platform))
 
 	err = file.Zip(tempDir, zipFilePath)
 	must(err)
@@ -0,0 +1,125 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/goplus/llpkgstore/config"
+	"github.com/goplus/llpkgstore/internal/actions/versions"
+	"github.com/goplus/llpkgstore/upstream/cache"
+)
+
+// checkDependencies checks that every llpkg dependency of cfg is a mapped version recorded in llpkgstore.json.
+func checkDependencies(ver *versions.Versions, cfg config.LLPkgConfig) error {
+	var errs []error
+	for _, dep := range cfg.Dependencies {
+		if ver.CVersionOf(dep.Name, dep.Version) == "" {
+			errs = append(errs, fmt.Errorf("dependency %s %s isn't a released llpkg version in llpkgstore.json", dep.Name, dep.Version))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dependencyConfig returns the llpkg.cfg of dep in repoDir, the root of the llpkg repository,
+// with the upstream package version dep is mapped from.
+// The lockfile in the directory of dep is only valid if the version is the one of its llpkg.cfg.
+func dependencyConfig(ver *versions.Versions, repoDir string, dep config.DependencyConfig) (cfg config.LLPkgConfig, useLockfile bool, err error) {
+	cversion := ver.CVersionOf(dep.Name, dep.Version)
+	if cversion == "" {
+		return cfg, false, fmt.Errorf("dependency %s %s isn't a released llpkg version in llpkgstore.json", dep.Name, dep.Version)
+	}
+	cfg, err = config.ParseLLPkgConfig(filepath.Join(repoDir, dep.Name, "llpkg.cfg"))
+	if err != nil {
+		return cfg, false, fmt.Errorf("dependency %s: %w", dep.Name, err)
+	}
+	useLockfile = cfg.Upstream.Package.Version == cversion
+	cfg.Upstream.Package.Version = cversion
+	return cfg, useLockfile, nil
+}
+
+// InstallDependencies installs the upstream packages of the llpkg dependencies of cfg, transitively,
+// each into the directory named after it in outputDir, and returns these directories,
+// which hold the .pc files of the dependencies, for PKG_CONFIG_PATH.
+//
+// repoDir is the root of the llpkg repository, holding llpkgstore.json and the directories of the dependencies.
+// The llpkg.cfg of a dependency is read from its directory, with the upstream package version
+// its mapped version is mapped from in llpkgstore.json.
+func InstallDependencies(ctx context.Context, installCache *cache.Cache, repoDir string, cfg config.LLPkgConfig, outputDir string) ([]string, error) {
+	if len(cfg.Dependencies) == 0 {
+		return nil, nil
+	}
+	metadataPath := filepath.Join(repoDir, "llpkgstore.json")
+	// versions.Read creates a missing file.
+	if _, err := os.Stat(metadataPath); err != nil {
+		return nil, fmt.Errorf("reading dependencies: %w", err)
+	}
+	ver := versions.Read(metadataPath)
+
+	var dirs []string
+	installed := map[string]string{}
+	var install func(deps []config.DependencyConfig) error
+	install = func(deps []config.DependencyConfig) error {
+		for _, dep := range deps {
+			if dep.Name == cfg.Upstream.Package.Name {
+				return fmt.Errorf("dependency cycle: %s depends on itself", dep.Name)
+			}
+			if version, ok := installed[dep.Name]; ok {
+				if version != dep.Version {
+					return fmt.Errorf("conflicting versions %s and %s of dependency %s", version, dep.Version, dep.Name)
+				}
+				continue
+			}
+			installed[dep.Name] = dep.Version
+			depCfg, useLockfile, err := dependencyConfig(ver, repoDir, dep)
+			if err != nil {
+				return err
+			}
+			dir := filepath.Join(outputDir, dep.Name)
+			var lockfileDir string
+			if useLockfile {
+				lockfileDir = filepath.Join(repoDir, dep.Name)
+			}
+			if err := installDependency(ctx, installCache, dep, depCfg, lockfileDir, dir); err != nil {
+				return fmt.Errorf("dependency %s: %w", dep.Name, err)
+			}
+			dirs = append(dirs, dir)
+			if err := install(depCfg.Dependencies); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := install(cfg.Dependencies); err != nil {
+		return nil, err
+	}
+	return dirs, nil
+}
+
+// installDependency installs the upstream of dep, configured by depCfg, into dir,
+// resolved from the lockfile in lockfileDir unless it's empty.
+func installDependency(ctx context.Context, installCache *cache.Cache, dep config.DependencyConfig, depCfg config.LLPkgConfig, lockfileDir, dir string) error {
+	uc, err := config.NewUpstreamFromConfig(depCfg.Upstream)
+	if err != nil {
+		return err
+	}
+	defer uc.Close()
+	if err := uc.Prepare(ctx); err != nil {
+		return err
+	}
+	var lockfile string
+	if lockfileDir != "" {
+		if lockfile, err = uc.UseLockfileIn(lockfileDir); err != nil {
+			return err
+		}
+	}
+	log.Printf("Installing dependency %s %s (%s %s)", dep.Name, dep.Version, uc.Pkg.Name, uc.Pkg.Version)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	_, err = installCache.Install(ctx, uc, lockfile, dir)
+	return err
+}
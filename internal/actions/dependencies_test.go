@@ -0,0 +1,75 @@
+package actions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/config"
+	"github.com/goplus/llpkgstore/internal/actions/versions"
+)
+
+// writeDependencyRepo writes an llpkg repository whose zlib llpkg is installed from a local prefix,
+// at 1.3.1 in llpkg.cfg while v1.0.0 is mapped from 1.3.
+func writeDependencyRepo(t *testing.T) string {
+	repo := t.TempDir()
+	prefix := t.TempDir()
+	os.MkdirAll(filepath.Join(prefix, "include"), 0777)
+	os.MkdirAll(filepath.Join(prefix, "lib"), 0777)
+	os.WriteFile(filepath.Join(prefix, "include", "zlib.h"), []byte("int zlibVersion(void);"), 0644)
+	os.WriteFile(filepath.Join(prefix, "lib", "libz.so"), []byte("ELF"), 0644)
+
+	os.WriteFile(filepath.Join(repo, "llpkgstore.json"), []byte(`{
+		"zlib": {"versions": {"1.3": ["v1.0.0"], "1.3.1": ["v1.1.0"]}}
+	}`), 0644)
+	os.Mkdir(filepath.Join(repo, "zlib"), 0777)
+	os.WriteFile(filepath.Join(repo, "zlib", "llpkg.cfg"), []byte(`{
+		"upstream": {
+			"installer": {"name": "local", "config": {"path": "`+filepath.ToSlash(prefix)+`"}},
+			"package": {"name": "zlib", "version": "1.3.1"}
+		}
+	}`), 0644)
+	return repo
+}
+
+func TestCheckDependencies(t *testing.T) {
+	repo := writeDependencyRepo(t)
+	ver := versions.Read(filepath.Join(repo, "llpkgstore.json"))
+	cfg := config.LLPkgConfig{Dependencies: []config.DependencyConfig{{Name: "zlib", Version: "v1.0.0"}}}
+	if err := checkDependencies(ver, cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cfg.Dependencies = append(cfg.Dependencies, config.DependencyConfig{Name: "zlib", Version: "v1.2.0"}, config.DependencyConfig{Name: "zstd", Version: "v1.0.0"})
+	err := checkDependencies(ver, cfg)
+	if err == nil || !strings.Contains(err.Error(), "zlib v1.2.0") || !strings.Contains(err.Error(), "zstd v1.0.0") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestInstallDependencies(t *testing.T) {
+	repo := writeDependencyRepo(t)
+	cfg := config.LLPkgConfig{
+		Upstream:     config.UpstreamConfig{Package: config.PackageConfig{Name: "libxml2", Version: "2.13.6"}},
+		Dependencies: []config.DependencyConfig{{Name: "zlib", Version: "v1.0.0"}},
+	}
+	outputDir := t.TempDir()
+	dirs, err := InstallDependencies(context.Background(), nil, repo, cfg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || dirs[0] != filepath.Join(outputDir, "zlib") {
+		t.Fatalf("unexpected dependency dirs: %v", dirs)
+	}
+	// the version mapped to v1.0.0 is installed, not the one of llpkg.cfg.
+	content, err := os.ReadFile(filepath.Join(dirs[0], "zlib.pc"))
+	if err != nil || !strings.Contains(string(content), "Version: 1.3\n") {
+		t.Errorf("unexpected pc file: %s %v", content, err)
+	}
+
+	cfg.Dependencies[0].Version = "v2.0.0"
+	if _, err := InstallDependencies(context.Background(), nil, repo, cfg, t.TempDir()); err == nil || !strings.Contains(err.Error(), "v2.0.0") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
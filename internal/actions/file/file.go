@@ -1,12 +1,16 @@
 package file
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // CopyFS copies the file system fsys into the directory dir,
@@ -161,3 +165,140 @@ func RemovePattern(pattern string) {
 		os.Remove(match)
 	}
 }
+
+// ExtractTarGz extracts a gzip-compressed tarball into the directory dir,
+// creating dir if necessary.
+//
+// The first strip leading path components are removed from every entry,
+// like tar's --strip-components. Entries that would be extracted outside of dir
+// are rejected, as well as symbolic links pointing outside of dir, also through other symbolic links,
+// and entries under a symbolic link extracted before.
+func ExtractTarGz(archive, dir string, strip int) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	var links []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return checkLinks(dir, links)
+		}
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(strings.Trim(filepath.ToSlash(hdr.Name), "/"), "/")
+		if len(parts) <= strip {
+			continue
+		}
+		name := filepath.FromSlash(strings.Join(parts[strip:], "/"))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+		// writing under a symbolic link could escape dir, whatever it points to.
+		parent := filepath.Dir(name)
+		if hdr.Typeflag == tar.TypeDir {
+			parent = name
+		}
+		if link := symlinkIn(dir, parent); link != "" {
+			return fmt.Errorf("invalid path in archive: %s is under the symlink %s", hdr.Name, link)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0777)
+		case tar.TypeReg:
+			// replace a symbolic link rather than write through it.
+			if info, lerr := os.Lstat(target); lerr == nil && info.Mode()&fs.ModeSymlink != 0 {
+				os.Remove(target)
+			}
+			err = extractFile(tr, target, hdr.FileInfo().Mode())
+		case tar.TypeSymlink:
+			link := filepath.Join(filepath.Dir(name), filepath.FromSlash(hdr.Linkname))
+			if filepath.IsAbs(hdr.Linkname) || !filepath.IsLocal(link) {
+				return fmt.Errorf("invalid symlink in archive: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			if err = os.MkdirAll(filepath.Dir(target), 0777); err == nil {
+				os.Remove(target)
+				err = os.Symlink(hdr.Linkname, target)
+				links = append(links, name)
+			}
+		default:
+			// skip devices, hard links and other special files
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// symlinkIn returns the first of name and its parent directories in dir which is a symbolic link,
+// or an empty string if there's none.
+func symlinkIn(dir, name string) string {
+	path := dir
+	for _, part := range strings.Split(filepath.Clean(name), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if err != nil {
+			return ""
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return path
+		}
+	}
+	return ""
+}
+
+// checkLinks checks that the symbolic links extracted into dir resolve inside of dir,
+// as a link may point outside through another link although its target is lexically inside.
+// Dangling links are left alone.
+func checkLinks(dir string, links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range links {
+		resolved, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if rel, err := filepath.Rel(root, resolved); err != nil || !filepath.IsLocal(rel) && rel != "." {
+			return fmt.Errorf("invalid symlink in archive: %s resolves to %s outside of %s", name, resolved, dir)
+		}
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, target string, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+		return err
+	}
+	w, err := os.OpenFile(target, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0666|mode&0777)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return &os.PathError{Op: "Extract", Path: target, Err: err}
+	}
+	return w.Close()
+}
@@ -1,10 +1,13 @@
 package file
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"compress/gzip"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -140,3 +143,97 @@ func TestCopySkip(t *testing.T) {
 		t.Errorf("unexpected skip file: want: 123 got: %s", string(toContent))
 	}
 }
+
+type tarEntry struct {
+	name     string
+	content  string
+	linkname string
+}
+
+func writeTarGz(t *testing.T, fileName string, entries []tarEntry) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if e.linkname != "" {
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.linkname, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.content))
+	}
+}
+
+func TestExtractTarGz(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "test.tar.gz")
+	writeTarGz(t, archive, []tarEntry{
+		{name: "cjson-1.7.18/include/cjson/cJSON.h", content: "123"},
+		{name: "cjson-1.7.18/lib/libcjson.so.1", content: "456"},
+		{name: "cjson-1.7.18/lib/libcjson.so", linkname: "libcjson.so.1"},
+	})
+
+	dir := t.TempDir()
+	if err := ExtractTarGz(archive, dir, 1); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "include", "cjson", "cJSON.h"))
+	if err != nil || string(b) != "123" {
+		t.Errorf("unexpected content: %s %v", string(b), err)
+	}
+	b, err = os.ReadFile(filepath.Join(dir, "lib", "libcjson.so"))
+	if err != nil || string(b) != "456" {
+		t.Errorf("unexpected symlink content: %s %v", string(b), err)
+	}
+
+	evil := filepath.Join(t.TempDir(), "evil.tar.gz")
+	writeTarGz(t, evil, []tarEntry{{name: "../evil", content: "1"}})
+	if err := ExtractTarGz(evil, t.TempDir(), 0); err == nil {
+		t.Error("unexpected extraction outside of dir")
+	}
+	writeTarGz(t, evil, []tarEntry{{name: "evil", linkname: "../../etc/passwd"}})
+	if err := ExtractTarGz(evil, t.TempDir(), 0); err == nil {
+		t.Error("unexpected symlink outside of dir")
+	}
+
+	// sub/up resolves to dir, so escape resolves to its parent although it's lexically sub.
+	writeTarGz(t, evil, []tarEntry{{name: "sub/up", linkname: ".."}, {name: "escape", linkname: "sub/up/.."}})
+	if err := ExtractTarGz(evil, t.TempDir(), 0); err == nil || !strings.Contains(err.Error(), "escape") {
+		t.Errorf("unexpected symlink chain outside of dir: %v", err)
+	}
+
+	// a file is never written through a symlink extracted before, whatever it points to.
+	outside := t.TempDir()
+	writeTarGz(t, evil, []tarEntry{{name: "sub/up", linkname: ".."}, {name: "sub/up/evil", content: "1"}})
+	if err := ExtractTarGz(evil, filepath.Join(outside, "dir"), 0); err == nil {
+		t.Error("unexpected extraction under a symlink")
+	}
+	writeTarGz(t, evil, []tarEntry{{name: "lib", linkname: "."}, {name: "lib/libfoo.a", content: "1"}})
+	if err := ExtractTarGz(evil, filepath.Join(outside, "dir"), 0); err == nil {
+		t.Error("unexpected extraction under a symlink")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "evil")); !os.IsNotExist(err) {
+		t.Errorf("file written outside of dir: %v", err)
+	}
+
+	// a symlink to an existing file is replaced by a later file, not written through.
+	target := filepath.Join(outside, "target")
+	os.WriteFile(target, []byte("keep"), 0644)
+	dir = t.TempDir()
+	os.Symlink(target, filepath.Join(dir, "file"))
+	writeTarGz(t, evil, []tarEntry{{name: "file", content: "1"}})
+	if err := ExtractTarGz(evil, dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "keep" {
+		t.Errorf("file written through a symlink: %s", string(b))
+	}
+}
@@ -1,6 +1,7 @@
 package llcppg
 
 import (
+	"context"
 	"encoding/hex"
 	"log"
 	"os"
@@ -103,7 +104,7 @@ func TestLlcppg(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	_, err = uc.Installer.Install(uc.Pkg, "testgenerate")
+	_, err = uc.Install(context.Background(), "testgenerate")
 	if err != nil {
 		log.Fatal(err)
 	}
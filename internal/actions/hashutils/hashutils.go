@@ -2,12 +2,17 @@ package hashutils
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+var ErrChecksumMismatch = errors.New("sha256 mismatch")
+
 // File hash a file in SHA-256
 func File(filePath string) ([]byte, error) {
 	f, err := os.Open(filePath)
@@ -48,3 +53,17 @@ func Dir(dir string, canHash func(string) bool) (fileMap map[string][]byte, err
 
 	return
 }
+
+// Verify checks the SHA-256 of a file against the hex-encoded expected sum.
+// Returns an error wrapping ErrChecksumMismatch if they don't match.
+func Verify(filePath, expected string) error {
+	sum, err := File(filePath)
+	if err != nil {
+		return err
+	}
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if actual := hex.EncodeToString(sum); actual != expected {
+		return fmt.Errorf("%w: %s: want %s, got %s", ErrChecksumMismatch, filePath, expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package pc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// File describes a .pc file synthesized by llpkgstore for packages which don't ship one.
+// The generated file uses the conventional prefix/libdir/includedir variables,
+// so the include and lib directories are expected under prefix.
+type File struct {
+	Name        string
+	Description string
+	Version     string
+	// Requires lists the pkg-config names this package depends on.
+	Requires []string
+	// Libs lists the library names to link against, without the -l prefix.
+	Libs []string
+	// LibsPrivate lists extra linker flags only needed for static linking.
+	LibsPrivate []string
+	// Cflags lists extra compiler flags, -I${includedir} is always added.
+	Cflags []string
+}
+
+// Generate renders f as a .pc file whose prefix is prefix.
+func Generate(f File, prefix string) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "prefix=%s\n", prefix)
+	buf.WriteString("libdir=${prefix}/lib\n")
+	buf.WriteString("includedir=${prefix}/include\n")
+	buf.WriteString("\n")
+	fmt.Fprintf(&buf, "Name: %s\n", f.Name)
+	fmt.Fprintf(&buf, "Description: %s\n", f.Description)
+	fmt.Fprintf(&buf, "Version: %s\n", f.Version)
+	if len(f.Requires) > 0 {
+		fmt.Fprintf(&buf, "Requires: %s\n", strings.Join(f.Requires, " "))
+	}
+
+	libs := []string{`-L"${libdir}"`}
+	for _, lib := range f.Libs {
+		libs = append(libs, "-l"+lib)
+	}
+	fmt.Fprintf(&buf, "Libs: %s\n", strings.Join(libs, " "))
+	if len(f.LibsPrivate) > 0 {
+		fmt.Fprintf(&buf, "Libs.private: %s\n", strings.Join(f.LibsPrivate, " "))
+	}
+	cflags := append([]string{`-I"${includedir}"`}, f.Cflags...)
+	fmt.Fprintf(&buf, "Cflags: %s\n", strings.Join(cflags, " "))
+
+	return buf.Bytes()
+}
@@ -0,0 +1,125 @@
+package pc
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Info is the part of a .pc file needed to find the headers of a package.
+type Info struct {
+	Name string
+	// Requires lists the pkg-config names of Requires and Requires.private, without version constraints.
+	Requires []string
+	// Cflags are the split Cflags with variables expanded and quotes removed.
+	Cflags []string
+}
+
+var varRef = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// Parse parses the content of a .pc file, expanding ${var} references.
+func Parse(content []byte) *Info {
+	info := &Info{}
+	vars := map[string]string{}
+	expand := func(s string) string {
+		return varRef.ReplaceAllStringFunc(s, func(ref string) string {
+			return vars[ref[2:len(ref)-1]]
+		})
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		// keywords end with ':', variables with '=', whichever comes first.
+		i := strings.IndexAny(line, ":=")
+		if i < 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(line[:i]), expand(strings.TrimSpace(line[i+1:]))
+		if line[i] == '=' {
+			vars[key] = value
+			continue
+		}
+		switch key {
+		case "Name":
+			info.Name = value
+		case "Requires", "Requires.private":
+			info.Requires = append(info.Requires, requiredNames(value)...)
+		case "Cflags":
+			info.Cflags = append(info.Cflags, SplitFlags(value)...)
+		}
+	}
+	return info
+}
+
+// requiredNames returns the package names of a Requires field, e.g. "zlib >= 1.2, openssl" => zlib openssl.
+func requiredNames(value string) []string {
+	var names []string
+	fields := strings.Fields(strings.ReplaceAll(value, ",", " "))
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "=", "!=", "<", "<=", ">", ">=":
+			// skip the version
+			i++
+		default:
+			names = append(names, fields[i])
+		}
+	}
+	return names
+}
+
+// SplitFlags splits compiler or linker flags like a shell, removing quotes.
+func SplitFlags(s string) []string {
+	var flags []string
+	var cur strings.Builder
+	inFlag := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote, inFlag = c, true
+		case c == '\\' && i+1 < len(s):
+			i++
+			cur.WriteByte(s[i])
+			inFlag = true
+		case c == ' ' || c == '\t':
+			if inFlag {
+				flags = append(flags, cur.String())
+				cur.Reset()
+				inFlag = false
+			}
+		default:
+			cur.WriteByte(c)
+			inFlag = true
+		}
+	}
+	if inFlag {
+		flags = append(flags, cur.String())
+	}
+	return flags
+}
+
+// IncludeDirs returns the directories of the -I flags among flags.
+func IncludeDirs(flags []string) []string {
+	var dirs []string
+	for i := 0; i < len(flags); i++ {
+		switch flag := flags[i]; {
+		case flag == "-I" && i+1 < len(flags):
+			i++
+			dirs = append(dirs, flags[i])
+		case strings.HasPrefix(flag, "-I") && flag != "-I":
+			dirs = append(dirs, flag[2:])
+		}
+	}
+	return dirs
+}
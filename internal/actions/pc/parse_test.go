@@ -0,0 +1,45 @@
+package pc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	info := Parse([]byte(`prefix=/opt/curl
+# comment
+includedir=${prefix}/include
+
+Name: libcurl
+Version: 8.6.0
+Requires: zlib >= 1.2, openssl
+Requires.private: libnghttp2
+Libs: -L${prefix}/lib -lcurl
+Cflags: -I"${includedir}" -I ${prefix}/include/curl -DCURL_STATICLIB
+`))
+	if info.Name != "libcurl" {
+		t.Errorf("unexpected name: %s", info.Name)
+	}
+	if want := []string{"zlib", "openssl", "libnghttp2"}; !reflect.DeepEqual(info.Requires, want) {
+		t.Errorf("unexpected requires: %v", info.Requires)
+	}
+	if want := []string{"-I/opt/curl/include", "-I", "/opt/curl/include/curl", "-DCURL_STATICLIB"}; !reflect.DeepEqual(info.Cflags, want) {
+		t.Errorf("unexpected cflags: %q", info.Cflags)
+	}
+	if want := []string{"/opt/curl/include", "/opt/curl/include/curl"}; !reflect.DeepEqual(IncludeDirs(info.Cflags), want) {
+		t.Errorf("unexpected include dirs: %v", IncludeDirs(info.Cflags))
+	}
+}
+
+func TestSplitFlags(t *testing.T) {
+	for s, want := range map[string][]string{
+		`-I"/a b" -lfoo`:   {"-I/a b", "-lfoo"},
+		`  -DX='1 2'  `:    {"-DX=1 2"},
+		`-I/a\ b -L/c`:     {"-I/a b", "-L/c"},
+		`-DEMPTY="" -lbar`: {"-DEMPTY=", "-lbar"},
+	} {
+		if got := SplitFlags(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("SplitFlags(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
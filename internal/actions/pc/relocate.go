@@ -0,0 +1,38 @@
+package pc
+
+import (
+	"bytes"
+	"path"
+	"path/filepath"
+	"regexp"
+)
+
+var (
+	prefixLine   = regexp.MustCompile(`(?m)^prefix=.*\n?`)
+	pcFileDirRef = regexp.MustCompile(`\$\{pcfiledir\}((?:/\.\.)*)`)
+)
+
+// Relocate rewrites a .pc file so that it can be placed at the root of prefix.
+//
+// Any existing prefix variable is dropped and an absolute prefix=prefix line is
+// written as the first line, which is the form PrefixMatch and GenerateTemplateFromPC expect.
+// References to ${pcfiledir} are resolved against pcDir, the directory (relative to prefix)
+// the .pc file was originally installed in, e.g. ${pcfiledir}/../.. in lib/pkgconfig becomes ${prefix}.
+func Relocate(content []byte, prefix, pcDir string) []byte {
+	content = prefixLine.ReplaceAll(content, nil)
+
+	rel := filepath.ToSlash(filepath.Clean(pcDir))
+	content = pcFileDirRef.ReplaceAllFunc(content, func(ref []byte) []byte {
+		parents := pcFileDirRef.FindSubmatch(ref)[1]
+		dir := path.Clean(rel + string(parents))
+		if dir == "." {
+			return []byte("${prefix}")
+		}
+		return []byte("${prefix}/" + dir)
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("prefix=" + prefix + "\n")
+	buf.Write(content)
+	return buf.Bytes()
+}
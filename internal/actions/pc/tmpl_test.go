@@ -64,3 +64,60 @@ func TestABSPathPCTemplate(t *testing.T) {
 		t.Errorf("unexpected content: got: %s", string(b))
 	}
 }
+
+func TestRelocate(t *testing.T) {
+	vcpkgPC := `prefix=${pcfiledir}/../..
+exec_prefix=${prefix}
+libdir=${exec_prefix}/lib
+includedir=${prefix}/include
+moduledir=${pcfiledir}/../cmake
+
+Name: libcjson
+Version: 1.7.18
+Libs: -L"${libdir}" -lcjson
+Cflags: -I"${includedir}"
+`
+	expected := `prefix=/opt/llpkg
+exec_prefix=${prefix}
+libdir=${exec_prefix}/lib
+includedir=${prefix}/include
+moduledir=${prefix}/lib/cmake
+
+Name: libcjson
+Version: 1.7.18
+Libs: -L"${libdir}" -lcjson
+Cflags: -I"${includedir}"
+`
+	got := Relocate([]byte(vcpkgPC), "/opt/llpkg", filepath.Join("lib", "pkgconfig"))
+	if string(got) != expected {
+		t.Errorf("unexpected content: got: %s", string(got))
+	}
+	if m := PrefixMatch.FindSubmatch(got); len(m) != 2 || string(m[1]) != "/opt/llpkg" {
+		t.Errorf("unexpected prefix match: %q", m)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	expected := `prefix=/opt/llpkg
+libdir=${prefix}/lib
+includedir=${prefix}/include
+
+Name: cjson
+Description: cjson
+Version: 1.7.18
+Libs: -L"${libdir}" -lcjson -lcjson_utils
+Libs.private: -lm
+Cflags: -I"${includedir}" -DCJSON_API_VISIBILITY
+`
+	got := Generate(File{
+		Name:        "cjson",
+		Description: "cjson",
+		Version:     "1.7.18",
+		Libs:        []string{"cjson", "cjson_utils"},
+		LibsPrivate: []string{"-lm"},
+		Cflags:      []string{"-DCJSON_API_VISIBILITY"},
+	}, "/opt/llpkg")
+	if string(got) != expected {
+		t.Errorf("unexpected content: got: %s", string(got))
+	}
+}
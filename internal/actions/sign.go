@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/goplus/llpkgstore/metadata"
+)
+
+// SigningKeysEnv is the environment variable holding the ed25519 private keys llpkgstore.json is signed with,
+// base64-encoded and separated by commas or whitespace. Several keys are set while rotating them.
+const SigningKeysEnv = "LLPKGSTORE_SIGNING_KEYS"
+
+// signingKeys returns the private keys in SigningKeysEnv.
+func signingKeys() ([]ed25519.PrivateKey, error) {
+	fields := strings.FieldsFunc(os.Getenv(SigningKeysEnv), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	var keys []ed25519.PrivateKey
+	for i, field := range fields {
+		key, err := metadata.ParsePrivateKey(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %d: %w", SigningKeysEnv, i, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SignMetadata writes the detached signature of the llpkgstore.json at path, by the keys in SigningKeysEnv
+// and timestamped now, next to it. Without keys, it removes the signature instead, which would be bad for the new content.
+func SignMetadata(path string) error {
+	keys, err := signingKeys()
+	if err != nil {
+		return err
+	}
+	signaturePath := path + metadata.SignatureSuffix
+	if len(keys) == 0 {
+		log.Printf("%s is not set, %s is published unsigned", SigningKeysEnv, path)
+		if err := os.Remove(signaturePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(signaturePath, metadata.Sign(data, time.Now(), keys...), 0644)
+}
@@ -0,0 +1,53 @@
+package actions
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goplus/llpkgstore/metadata"
+)
+
+func TestSignMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llpkgstore.json")
+	data := []byte(`{"cjson":{"versions":[{"c":"1.7.18","go":["v1.2.0"]}]}}`)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldPublic, oldPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	newPublic, newPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+	// both keys while rotating them
+	t.Setenv(SigningKeysEnv, base64.StdEncoding.EncodeToString(oldPrivate.Seed())+",\n"+
+		base64.StdEncoding.EncodeToString(newPrivate.Seed()))
+	if err := SignMetadata(path); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := os.ReadFile(path + metadata.SignatureSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []ed25519.PublicKey{oldPublic, newPublic} {
+		if _, err := metadata.Verify(data, sig, []ed25519.PublicKey{key}); err != nil {
+			t.Errorf("key %s: %v", metadata.KeyID(key), err)
+		}
+	}
+
+	// without keys, the stale signature is removed
+	t.Setenv(SigningKeysEnv, "")
+	if err := SignMetadata(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + metadata.SignatureSuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale signature kept: %v", err)
+	}
+
+	t.Setenv(SigningKeysEnv, "not a key")
+	if err := SignMetadata(path); err == nil {
+		t.Error("Expected error for an invalid key, but got nil")
+	}
+}
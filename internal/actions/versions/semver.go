@@ -1,6 +1,11 @@
 package versions
 
-import "golang.org/x/mod/semver"
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
 
 // Package versions provides utilities for working with semantic versioning.
 
@@ -47,3 +52,41 @@ func IsSemver(cversions []string) bool {
 	}
 	return true
 }
+
+// Compare compares two C versions, returning -1, 0 or +1.
+// Versions are compared as semantic versions after ToSemVer, so 1.7.9 < 1.7.18,
+// a valid semantic version is greater than an invalid one, and versions which
+// compare equal are ordered lexically.
+func Compare(a, b string) int {
+	if a == "" || b == "" {
+		return strings.Compare(a, b)
+	}
+	if cmp := semver.Compare(ToSemVer(a), ToSemVer(b)); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(a, b)
+}
+
+// Components of a semantic version, see Bump.
+const (
+	Major = iota
+	Minor
+	Patch
+)
+
+// Bump returns the canonical version following v with component incremented
+// and the lower components reset, e.g. Bump("v1.2.3", Minor) is "v1.3.0".
+// It returns "" if v isn't a valid semantic version.
+func Bump(v string, component int) string {
+	v = semver.Canonical(v)
+	if v == "" {
+		return ""
+	}
+	var parts [3]int
+	fmt.Sscanf(v, "v%d.%d.%d", &parts[0], &parts[1], &parts[2])
+	parts[component]++
+	for i := component + 1; i < len(parts); i++ {
+		parts[i] = 0
+	}
+	return fmt.Sprintf("v%d.%d.%d", parts[0], parts[1], parts[2])
+}
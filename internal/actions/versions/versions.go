@@ -2,7 +2,10 @@ package versions
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"slices"
@@ -17,19 +20,29 @@ type Versions struct {
 	metadata.MetadataMap
 
 	fileName string
+	// format is the form the file is in, kept by Write so that the clients reading it don't break,
+	// see llpkgstore metadata convert.
+	format metadata.Format
 }
 
-// appendVersion adds a new version to the slice while preventing duplicates.
-// It panics if the element already exists in the array to enforce uniqueness constraints.
-// Parameters:
-//
-//	arr: Slice of versions to modify
-//	elem: Version to append
-func appendVersion(arr []string, elem string) []string {
-	if slices.Contains(arr, elem) {
-		log.Fatalf("version %s has already existed", elem)
+// Load reads the version mapping file fileName without creating it,
+// a missing file has no mappings. Unlike Read, it returns an error for a malformed file.
+func Load(fileName string) (*Versions, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	m := metadata.MetadataMap{}
+	format := metadata.FormatArray
+	if len(b) > 0 {
+		if format, err = metadata.DetectFormat(b); err != nil {
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
 	}
-	return append(arr, elem)
+	return &Versions{MetadataMap: m, fileName: fileName, format: format}, nil
 }
 
 // Read initializes a Versions struct by reading version mappings from a file.
@@ -51,27 +64,20 @@ func Read(fileName string) *Versions {
 	}
 
 	m := metadata.MetadataMap{}
+	format := metadata.FormatArray
 
 	if len(b) > 0 {
 		json.Unmarshal(b, &m)
+		format, _ = metadata.DetectFormat(b)
 	}
 
 	return &Versions{
 		MetadataMap: m,
 		fileName:    f.Name(),
+		format:      format,
 	}
 }
 
-// cVersions retrieves the version mappings for a specific C library.
-// It returns a map where keys are C library versions and values are supported Go versions.
-func (v *Versions) cVersions(clib string) map[metadata.CVersion][]metadata.GoVersion {
-	versions := v.MetadataMap[clib]
-	if versions == nil {
-		return nil
-	}
-	return versions.Versions
-}
-
 // CVersions returns all available versions of the specified C library.
 // The versions are returned as semantic version strings.
 func (v *Versions) CVersions(clib string) (ret []string) {
@@ -79,8 +85,8 @@ func (v *Versions) CVersions(clib string) (ret []string) {
 	if versions == nil {
 		return
 	}
-	for version := range versions.Versions {
-		ret = append(ret, ToSemVer(version))
+	for _, mapping := range versions.Mappings() {
+		ret = append(ret, ToSemVer(mapping.C))
 	}
 	return
 }
@@ -91,19 +97,16 @@ func (v *Versions) GoVersions(clib string) (ret []string) {
 	if versions == nil {
 		return
 	}
-	for _, goversion := range versions.Versions {
-		ret = append(ret, goversion...)
+	for _, mapping := range versions.Mappings() {
+		ret = append(ret, mapping.Go...)
 	}
 	return
 }
 
 // LatestGoVersionForCVersion finds the latest Go version compatible with a specific C library version.
 func (v *Versions) LatestGoVersionForCVersion(clib, cver string) string {
-	version := v.MetadataMap[clib]
-	if version == nil {
-		return ""
-	}
-	goVersions := version.Versions[cver]
+	// GoVersions returns a copy, sorting it keeps the order of the mappings.
+	goVersions := v.MetadataMap[clib].GoVersions(cver)
 	if len(goVersions) == 0 {
 		return ""
 	}
@@ -111,11 +114,20 @@ func (v *Versions) LatestGoVersionForCVersion(clib, cver string) string {
 	return goVersions[len(goVersions)-1]
 }
 
+// CVersionOf returns the C library version the Go version of clib is mapped from, or "" if it isn't mapped.
+func (v *Versions) CVersionOf(clib, goVersion string) string {
+	return v.MetadataMap[clib].CVersion(goVersion)
+}
+
 // SearchBySemVer looks up a C library version by its semantic version string.
 func (v *Versions) SearchBySemVer(clib, semver string) string {
-	for version := range v.cVersions(clib) {
-		if ToSemVer(version) == semver {
-			return version
+	versions := v.MetadataMap[clib]
+	if versions == nil {
+		return ""
+	}
+	for _, mapping := range versions.Mappings() {
+		if ToSemVer(mapping.C) == semver {
+			return mapping.C
 		}
 	}
 	return ""
@@ -139,22 +151,20 @@ func (v *Versions) LatestGoVersion(clib string) string {
 //	clibVersion: The specific version of the C library.
 //	mappedVersion: The Go version to map with the C library version.
 //
-// It appends the Go version to the existing list for the C library version and saves the updated metadata.
+// It appends the Go version to the existing list for the C library version and saves the updated metadata
+// in the form the file was read in. It exits if the Go version has already been mapped.
 func (v *Versions) Write(clib, clibVersion, mappedVersion string) {
 	clibVersions := v.MetadataMap[clib]
 	if clibVersions == nil {
-		clibVersions = &metadata.Metadata{
-			Versions: map[metadata.CVersion][]metadata.GoVersion{},
-		}
+		clibVersions = &metadata.Metadata{}
 		v.MetadataMap[clib] = clibVersions
 	}
-	versions := clibVersions.Versions[clibVersion]
-
-	versions = appendVersion(versions, mappedVersion)
-
-	clibVersions.Versions[clibVersion] = versions
+	if slices.Contains(clibVersions.GoVersions(clibVersion), mappedVersion) {
+		log.Fatalf("version %s has already existed", mappedVersion)
+	}
+	clibVersions.Add(clibVersion, mappedVersion)
 	// sync to disk
-	b, _ := json.Marshal(&v.MetadataMap)
+	b, _ := v.MetadataMap.Encode(v.format)
 
 	os.WriteFile(v.fileName, []byte(b), 0644)
 }
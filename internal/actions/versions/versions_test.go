@@ -3,7 +3,9 @@ package versions
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"testing"
 
 	"golang.org/x/mod/semver"
@@ -49,6 +51,10 @@ func TestCVersions(t *testing.T) {
 		t.Errorf("unexpected search by semver result: want: %v got: %v", "1.3", v.SearchBySemVer("cgood", "v1.3.0"))
 	}
 
+	if v.CVersionOf("cgood", "v0.1.1") != "1.3" || v.CVersionOf("cgood", "v0.2.0") != "" {
+		t.Errorf("unexpected C version of v0.1.1: %q", v.CVersionOf("cgood", "v0.1.1"))
+	}
+
 	if v.SearchBySemVer("agood", "v1.3.0") != "" {
 		t.Errorf("unexpected search by semver result: want: %v got: %v", "", v.SearchBySemVer("cgood", "v1.3.0"))
 	}
@@ -96,7 +102,73 @@ func TestAppend(t *testing.T) {
 
 	b, _ := os.ReadFile("llpkgstore.json")
 
-	if !bytes.Equal(b, []byte(`{"cjson":{"versions":{"1.7.18":["v1.0.0","v1.0.1"],"1.7.19":["v1.0.2"]}},"libxml":{"versions":{"1.45.1.4":["v1.0.0"],"1.45.1.5":["v1.0.1"]}}}`)) {
-		t.Error("unexpected append result")
+	if !bytes.Equal(b, []byte(`{"cjson":{"versions":[{"c":"1.7.18","go":["v1.0.0","v1.0.1"]},{"c":"1.7.19","go":["v1.0.2"]}]},"libxml":{"versions":[{"c":"1.45.1.4","go":["v1.0.0"]},{"c":"1.45.1.5","go":["v1.0.1"]}]}}`)) {
+		t.Errorf("unexpected append result: %s", b)
+	}
+}
+
+func TestAppendLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llpkgstore.json")
+	err := os.WriteFile(path, []byte(`{"cjson":{"versions":{"1.7.18":["v1.0.0"]}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := Read(path)
+	v.Write("cjson", "1.7.19", "v1.1.0")
+	v.Write("libxml", "1.45.1.4", "v1.0.0")
+
+	b, _ := os.ReadFile(path)
+	if !bytes.Equal(b, []byte(`{"cjson":{"versions":{"1.7.18":["v1.0.0"],"1.7.19":["v1.1.0"]}},"libxml":{"versions":{"1.45.1.4":["v1.0.0"]}}}`)) {
+		t.Errorf("legacy file not kept in the legacy form: %s", b)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	vers := []string{"1.7.18", "1.10", "1.7.9", "snapshot", "v1.7.9", "1.7.10"}
+	slices.SortFunc(vers, Compare)
+	expected := []string{"snapshot", "1.7.9", "v1.7.9", "1.7.10", "1.7.18", "1.10"}
+	if !slices.Equal(vers, expected) {
+		t.Errorf("unexpected order: %v", vers)
+	}
+}
+
+func TestBump(t *testing.T) {
+	for _, tc := range []struct {
+		v         string
+		component int
+		want      string
+	}{
+		{"v1.2.3", Major, "v2.0.0"},
+		{"v1.2.3", Minor, "v1.3.0"},
+		{"v1.2.3", Patch, "v1.2.4"},
+		{"v0.1", Minor, "v0.2.0"},
+		{"1.2.3", Patch, ""},
+	} {
+		if got := Bump(tc.v, tc.component); got != tc.want {
+			t.Errorf("Bump(%s, %d) = %s, want %s", tc.v, tc.component, got, tc.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "llpkgstore.json")
+	ver, err := Load(path)
+	if err != nil || len(ver.MetadataMap) != 0 {
+		t.Fatalf("unexpected versions: %v %v", ver, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file created: %v", err)
+	}
+
+	os.WriteFile(path, []byte(`{"cjson":{"versions":{"1.7.18":["v1.0.0"]}}}`), 0644)
+	if ver, err = Load(path); err != nil || ver.LatestGoVersion("cjson") != "v1.0.0" {
+		t.Errorf("unexpected versions: %v %v", ver, err)
+	}
+
+	os.WriteFile(path, []byte(`{"cjson":`), 0644)
+	if _, err := Load(path); err == nil {
+		t.Error("unexpected success for a malformed file")
 	}
 }
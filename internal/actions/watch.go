@@ -0,0 +1,290 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/config"
+	"github.com/goplus/llpkgstore/internal/actions/versions"
+	"github.com/goplus/llpkgstore/metadata"
+	"github.com/goplus/llpkgstore/upstream"
+	"golang.org/x/mod/semver"
+)
+
+// UpdateBranchPrefix prefixes the branches created by PrepareProposal.
+const UpdateBranchPrefix = "llpkg-update/"
+
+// Proposal is a C version of an llpkg found upstream which isn't mapped yet.
+type Proposal struct {
+	Clib string `json:"clib"`
+	// Dir is the directory of the llpkg.
+	Dir      string      `json:"dir"`
+	CVersion string      `json:"cVersion"`
+	Revision string      `json:"revision,omitempty"`
+	Remote   string      `json:"remote,omitempty"`
+	Kind     VersionKind `json:"kind"`
+	// MappedVersion is the proposed Go version, empty if the C version can't be released.
+	MappedVersion string `json:"mappedVersion,omitempty"`
+	// Base is the branch to submit the version to, a release branch for legacy versions.
+	Base string `json:"base"`
+	// Note explains why a version can't be released.
+	Note string `json:"note,omitempty"`
+	// Branch is the branch created by PrepareProposal.
+	Branch string `json:"branch,omitempty"`
+}
+
+// ReleaseAs returns the Release-as trailer of the proposal.
+func (p *Proposal) ReleaseAs() string {
+	return MappedVersionPrefix + p.Clib + "/" + p.MappedVersion
+}
+
+// LLPkgDirs returns the llpkg directories, which contain an llpkg.cfg, right under root.
+func LLPkgDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "llpkg.cfg")); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// Watch searches the upstream of the llpkg in every dir for C versions which aren't mapped in ver yet,
+// and proposes a mapped version for each of them.
+// Directories which fail are skipped and their errors returned joined.
+func Watch(ctx context.Context, ver *versions.Versions, dirs []string) ([]Proposal, error) {
+	var proposals []Proposal
+	var errs []error
+	for _, dir := range dirs {
+		cfg, err := config.ParseLLPkgConfig(filepath.Join(dir, "llpkg.cfg"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dir, err))
+			continue
+		}
+		uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
+		var results []upstream.SearchResult
+		if err == nil {
+			if err = uc.Prepare(ctx); err == nil {
+				results, err = uc.Search(ctx)
+			}
+			uc.Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dir, err))
+			continue
+		}
+		for _, p := range proposeVersions(ver, uc.Pkg, results) {
+			p.Dir = dir
+			proposals = append(proposals, p)
+		}
+	}
+	return proposals, errors.Join(errs...)
+}
+
+// proposeVersions classifies the unmapped versions in results and proposes their mapped versions.
+//
+// Only stable versions newer than the first mapped C version (or pkg.Version if nothing is mapped yet)
+// are considered. They're handled from the oldest to the latest as if the previous ones had been released,
+// so that the proposed mapped versions keep the order of the C versions,
+// e.g. with 1.7.18 => v1.2.0 mapped, new 1.7.19 and 1.8.0 are proposed as v1.3.0 and v1.4.0.
+func proposeVersions(ver *versions.Versions, pkg upstream.Package, results []upstream.SearchResult) []Proposal {
+	clib := pkg.Name
+	mapped := &metadata.Metadata{}
+	if m := ver.MetadataMap[clib]; m != nil {
+		mapped = m.Clone()
+	}
+	sim := &versions.Versions{MetadataMap: metadata.MetadataMap{clib: mapped}}
+
+	known := sim.CVersions(clib)
+	known = append(known, versions.ToSemVer(pkg.Version))
+	slices.SortFunc(known, semver.Compare)
+	baseline := known[0]
+
+	var candidates []upstream.SearchResult
+	for _, r := range results {
+		v := versions.ToSemVer(r.Version)
+		switch {
+		case r.Name != clib, !semver.IsValid(v), semver.Prerelease(v) != "",
+			slices.Contains(known, v), semver.Compare(v, baseline) <= 0,
+			slices.ContainsFunc(candidates, func(c upstream.SearchResult) bool { return c.Version == r.Version }):
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+	slices.SortStableFunc(candidates, func(a, b upstream.SearchResult) int {
+		return versions.Compare(a.Version, b.Version)
+	})
+
+	var proposals []Proposal
+	for _, r := range candidates {
+		class := ClassifyCVersion(sim, clib, r.Version)
+		p := Proposal{
+			Clib:     clib,
+			CVersion: r.Version,
+			Revision: r.Revision,
+			Remote:   r.Remote,
+			Kind:     class.Kind,
+			Base:     defaultReleaseBranch,
+		}
+		switch class.Kind {
+		case VersionLatest:
+			p.MappedVersion = nextLatestVersion(sim, clib, r.Version)
+		case VersionLegacy:
+			if class.Previous == "" {
+				p.Note = "older than every mapped version"
+				break
+			}
+			closest := closestMappedVersion(sim, clib, class.Previous)
+			p.MappedVersion = versions.Bump(closest, versions.Patch)
+			for slices.Contains(sim.GoVersions(clib), p.MappedVersion) {
+				p.MappedVersion = versions.Bump(p.MappedVersion, versions.Patch)
+			}
+			p.Base = BranchPrefix + clib + "/" + closest
+		case VersionHistorical:
+			p.Note = "a newer patch version of " + semver.MajorMinor(versions.ToSemVer(r.Version)) + " is mapped"
+		}
+		if p.MappedVersion != "" {
+			mapped.Add(r.Version, p.MappedVersion)
+		}
+		proposals = append(proposals, p)
+	}
+	return proposals
+}
+
+// nextLatestVersion returns the mapped version of cversion newer than every mapped C version,
+// following the version mapping rules: the initial version is v1.0.0, or v0.1.0 for unstable C libraries,
+// a new major C version bumps the major version and other C updates bump the minor version.
+func nextLatestVersion(ver *versions.Versions, clib, cversion string) string {
+	latestGoVersion := ver.LatestGoVersion(clib)
+	current := versions.ToSemVer(cversion)
+	if latestGoVersion == "" {
+		if semver.Major(current) == "v0" {
+			return "v0.1.0"
+		}
+		return "v1.0.0"
+	}
+	vers := ver.CVersions(clib)
+	slices.SortFunc(vers, semver.Compare)
+	if semver.Major(current) != semver.Major(vers[len(vers)-1]) {
+		return versions.Bump(latestGoVersion, versions.Major)
+	}
+	return versions.Bump(latestGoVersion, versions.Minor)
+}
+
+// git runs git in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// bumpConfigVersion sets the package version in the llpkg.cfg at path to version.
+func bumpConfigVersion(path, version string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	b, err = config.SetPackageVersion(b, version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// PrepareProposal creates the branch llpkg-update/<clib>/<cversion> from the base of p,
+// bumps the version in llpkg.cfg, refreshes the lockfile if there is one, and commits it
+// with the Release-as trailer. The working tree isn't touched, the commit is made in a temporary worktree.
+func PrepareProposal(ctx context.Context, p *Proposal) error {
+	if p.MappedVersion == "" {
+		return fmt.Errorf("%s/%s: no mapped version to release", p.Clib, p.CVersion)
+	}
+	absDir, err := filepath.Abs(p.Dir)
+	if err != nil {
+		return err
+	}
+	top, err := git(ctx, absDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(top, absDir)
+	if err != nil {
+		return err
+	}
+	// CI checkouts may only have the remote branch.
+	base := ""
+	for _, ref := range []string{p.Base, "origin/" + p.Base} {
+		if _, err := git(ctx, top, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+			base = ref
+			break
+		}
+	}
+	if base == "" {
+		return fmt.Errorf("base branch %s doesn't exist, create it with the %s%s label",
+			p.Base, LabelPrefix, p.Base)
+	}
+
+	worktree, err := os.MkdirTemp("", "llpkg-watch")
+	if err != nil {
+		return err
+	}
+	os.Remove(worktree)
+	branch := UpdateBranchPrefix + p.Clib + "/" + p.CVersion
+	if _, err := git(ctx, top, "worktree", "add", "-b", branch, worktree, base); err != nil {
+		return err
+	}
+	defer git(context.WithoutCancel(ctx), top, "worktree", "remove", "--force", worktree)
+
+	dir := filepath.Join(worktree, rel)
+	if err := bumpConfigVersion(filepath.Join(dir, "llpkg.cfg"), p.CVersion); err != nil {
+		return err
+	}
+	cfg, err := config.ParseLLPkgConfig(filepath.Join(dir, "llpkg.cfg"))
+	if err != nil {
+		return err
+	}
+	uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
+	if err != nil {
+		return err
+	}
+	defer uc.Close()
+	if err := uc.Prepare(ctx); err != nil {
+		return err
+	}
+	if lockfile, err := uc.UseLockfileIn(dir); err != nil {
+		return err
+	} else if lockfile != "" {
+		if _, err := uc.Lock(ctx, dir); err != nil {
+			return err
+		}
+	}
+	if _, err := git(ctx, worktree, "add", "-A"); err != nil {
+		return err
+	}
+	title := fmt.Sprintf("%s: update to %s", p.Clib, p.CVersion)
+	if _, err := git(ctx, worktree, "commit", "-m", title, "-m", p.ReleaseAs()); err != nil {
+		return err
+	}
+	p.Branch = branch
+	return nil
+}
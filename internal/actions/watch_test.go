@@ -0,0 +1,184 @@
+package actions
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/internal/actions/versions"
+	"github.com/goplus/llpkgstore/metadata"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+func testVersions() *versions.Versions {
+	return &versions.Versions{MetadataMap: metadata.MetadataMap{
+		"cjson": {Versions: map[metadata.CVersion][]metadata.GoVersion{
+			"1.5.1":  {"v1.0.0", "v1.0.1"},
+			"1.6.2":  {"v1.1.0"},
+			"1.7.18": {"v1.2.0"},
+		}},
+	}}
+}
+
+func searchResults(vers ...string) []upstream.SearchResult {
+	var results []upstream.SearchResult
+	for _, v := range vers {
+		results = append(results, upstream.SearchResult{Name: "cjson", Version: v})
+	}
+	return results
+}
+
+func TestProposeVersions(t *testing.T) {
+	results := searchResults("1.4.0", "1.5.0", "1.5.2", "1.5.3", "1.6.1", "1.6.3", "1.7.18", "1.7.19", "1.8.0", "2.0.0-beta", "2.0.0")
+	proposals := proposeVersions(testVersions(), upstream.Package{Name: "cjson", Version: "1.7.18"}, results)
+
+	expected := []Proposal{
+		{Clib: "cjson", CVersion: "1.5.2", Kind: VersionLegacy, MappedVersion: "v1.0.2", Base: "release-branch.cjson/v1.0.1"},
+		{Clib: "cjson", CVersion: "1.5.3", Kind: VersionLegacy, MappedVersion: "v1.0.3", Base: "release-branch.cjson/v1.0.2"},
+		{Clib: "cjson", CVersion: "1.6.1", Kind: VersionHistorical, Base: "main", Note: "a newer patch version of v1.6 is mapped"},
+		{Clib: "cjson", CVersion: "1.6.3", Kind: VersionLegacy, MappedVersion: "v1.1.1", Base: "release-branch.cjson/v1.1.0"},
+		{Clib: "cjson", CVersion: "1.7.19", Kind: VersionLatest, MappedVersion: "v1.3.0", Base: "main"},
+		{Clib: "cjson", CVersion: "1.8.0", Kind: VersionLatest, MappedVersion: "v1.4.0", Base: "main"},
+		{Clib: "cjson", CVersion: "2.0.0", Kind: VersionLatest, MappedVersion: "v2.0.0", Base: "main"},
+	}
+	if !reflect.DeepEqual(proposals, expected) {
+		t.Errorf("unexpected proposals:\nwant %+v\ngot  %+v", expected, proposals)
+	}
+
+	// nothing mapped yet
+	proposals = proposeVersions(&versions.Versions{MetadataMap: metadata.MetadataMap{}},
+		upstream.Package{Name: "libass", Version: "0.17.1"}, []upstream.SearchResult{{Name: "libass", Version: "0.17.3"}})
+	if len(proposals) != 1 || proposals[0].MappedVersion != "v0.1.0" {
+		t.Errorf("unexpected proposals: %+v", proposals)
+	}
+}
+
+// watchInstaller reports the versions in its "versions" config key.
+type watchInstaller struct {
+	config map[string]string
+}
+
+func (w *watchInstaller) Name() string              { return "watch-test" }
+func (w *watchInstaller) Config() map[string]string { return w.config }
+func (w *watchInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	return nil, nil
+}
+func (w *watchInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	return searchResults(strings.Fields(w.config["versions"])...), nil
+}
+
+func TestWatch(t *testing.T) {
+	upstream.Register("watch-test", func(config map[string]string) upstream.Installer {
+		return &watchInstaller{config: config}
+	})
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, "cjson"), 0777)
+	os.WriteFile(filepath.Join(root, "cjson", "llpkg.cfg"), []byte(`{
+  "upstream": {
+    "installer": {"name": "watch-test", "config": {"versions": "1.7.18 1.7.19"}},
+    "package": {"name": "cjson", "version": "1.7.18"}
+  }
+}`), 0644)
+	os.Mkdir(filepath.Join(root, "empty"), 0777)
+
+	dirs, err := LLPkgDirs(root)
+	if err != nil || !reflect.DeepEqual(dirs, []string{filepath.Join(root, "cjson")}) {
+		t.Fatalf("unexpected dirs: %v %v", dirs, err)
+	}
+	proposals, err := Watch(context.Background(), testVersions(), dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proposals) != 1 || proposals[0].Dir != dirs[0] || proposals[0].ReleaseAs() != "Release-as: cjson/v1.3.0" {
+		t.Errorf("unexpected proposals: %+v", proposals)
+	}
+}
+
+func TestPrepareProposal(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("fake conan requires a POSIX shell")
+	}
+	// the fake conan logs its commands and writes the lockfile.
+	binDir := t.TempDir()
+	log := filepath.Join(t.TempDir(), "conan.log")
+	os.WriteFile(filepath.Join(binDir, "conan"), []byte(`#!/bin/sh
+echo "$*" >> "`+log+`"
+for arg; do
+	case "$arg" in
+	--lockfile-out=*) echo '{"version": "0.5", "requires": ["cjson/1.7.19"]}' > "${arg#*=}" ;;
+	esac
+done
+`), 0755)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("CONAN_HOME", t.TempDir())
+	repo := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := git(context.Background(), repo, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	cfg := `{
+  "upstream": {
+    "installer": {
+      "name": "conan",
+      "config": {
+        "remotes": "internal=https://conan.example.com"
+      }
+    },
+    "package": {
+      "name": "cjson",
+      "version": "1.7.18"
+    }
+  }
+}`
+	os.Mkdir(filepath.Join(repo, "cjson"), 0777)
+	os.WriteFile(filepath.Join(repo, "cjson", "llpkg.cfg"), []byte(cfg), 0644)
+	os.WriteFile(filepath.Join(repo, "cjson", "conan.lock"), []byte(`{"version": "0.5", "requires": ["cjson/1.7.18"]}`), 0644)
+	run("init", "-b", "main")
+	run("config", "user.name", "test")
+	run("config", "user.email", "test@example.com")
+	run("add", "-A")
+	run("commit", "-m", "add cjson")
+
+	p := &Proposal{Clib: "cjson", Dir: filepath.Join(repo, "cjson"), CVersion: "1.7.19", Kind: VersionLatest, MappedVersion: "v1.3.0", Base: "main"}
+	if err := PrepareProposal(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Branch != "llpkg-update/cjson/1.7.19" {
+		t.Errorf("unexpected branch: %s", p.Branch)
+	}
+	if got := run("show", p.Branch+":cjson/llpkg.cfg"); got != strings.Replace(cfg, "1.7.18", "1.7.19", 1) {
+		t.Errorf("unexpected llpkg.cfg:\n%s", got)
+	}
+	// the configured remotes are added before the lockfile is refreshed.
+	b, _ := os.ReadFile(log)
+	if commands := string(b); !strings.Contains(commands, "remote add --force internal https://conan.example.com\nlock create ") {
+		t.Errorf("unexpected conan commands:\n%s", commands)
+	}
+	if got := run("show", p.Branch+":cjson/conan.lock"); !strings.Contains(got, "cjson/1.7.19") {
+		t.Errorf("lockfile not refreshed:\n%s", got)
+	}
+	if msg := run("log", "-1", "--format=%B", p.Branch); !strings.Contains(msg, "Release-as: cjson/v1.3.0") {
+		t.Errorf("unexpected commit message: %s", msg)
+	}
+	// the working tree is left untouched
+	if b, _ := os.ReadFile(filepath.Join(repo, "cjson", "llpkg.cfg")); string(b) != cfg {
+		t.Errorf("working tree modified: %s", b)
+	}
+
+	legacy := &Proposal{Clib: "cjson", Dir: filepath.Join(repo, "cjson"), CVersion: "1.7.17", Kind: VersionLegacy, MappedVersion: "v1.0.1", Base: "release-branch.cjson/v1.0.0"}
+	if err := PrepareProposal(context.Background(), legacy); err == nil || !strings.Contains(err.Error(), "branch:release-branch.cjson/v1.0.0") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package cmdbuilder
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -77,3 +78,10 @@ func (c *CmdBuilder) Cmd() *exec.Cmd {
 	cmds = append(cmds, c.Args()...)
 	return exec.Command(c.name, cmds...)
 }
+
+// CmdContext is like Cmd but the command is bound to ctx, see CommandContext.
+func (c *CmdBuilder) CmdContext(ctx context.Context) *exec.Cmd {
+	cmds := append([]string{c.subcommand}, c.objs...)
+	cmds = append(cmds, c.Args()...)
+	return CommandContext(ctx, c.name, cmds...)
+}
@@ -0,0 +1,20 @@
+package cmdbuilder
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// WaitDelay bounds how long Wait blocks for I/O of the killed process tree after ctx is done.
+const WaitDelay = 5 * time.Second
+
+// CommandContext is like exec.CommandContext, but when ctx is done it kills the whole
+// process tree of the command instead of the direct child only, so that processes
+// spawned by tools like conan or cmake don't outlive the cancellation.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = WaitDelay
+	return cmd
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package cmdbuilder
+
+import "os/exec"
+
+// killProcessGroup is a no-op on platforms without process groups,
+// cancellation kills the direct child only.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package cmdbuilder
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommandContextKillsProcessTree(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// the grandchild holds stdout open, so Output only returns early if the whole tree is killed,
+	// otherwise it'd wait until WaitDelay closes the pipe.
+	cmd := CommandContext(ctx, "sh", "-c", "sleep 60 & wait")
+	start := time.Now()
+	if _, err := cmd.Output(); err == nil {
+		t.Error("expected an error from the cancelled command")
+	}
+	if elapsed := time.Since(start); elapsed >= WaitDelay {
+		t.Errorf("process tree wasn't killed on cancellation, took %s", elapsed)
+	}
+}
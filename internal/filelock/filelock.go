@@ -0,0 +1,40 @@
+// Package filelock provides exclusive locks on files shared between processes.
+package filelock
+
+import (
+	"context"
+	"time"
+)
+
+// pollInterval is how often a busy lock is retried.
+const pollInterval = 100 * time.Millisecond
+
+// Lock is an exclusive lock held on a file.
+type Lock struct {
+	path string
+	lock *lockFile
+}
+
+// Acquire locks the file at path, creating it if needed, and waits until the lock
+// is released by its owner or ctx is done.
+func Acquire(ctx context.Context, path string) (*Lock, error) {
+	for {
+		f, err := tryLock(path)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			return &Lock{path: path, lock: f}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	return l.lock.unlock(l.path)
+}
@@ -0,0 +1,30 @@
+//go:build !unix
+
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is the lock file created exclusively by its owner.
+// Unlike flock(2), the lock outlives a process which dies holding it,
+// the file must then be removed by hand.
+type lockFile struct{}
+
+// tryLock returns nil without an error if the file is locked by someone else.
+func tryLock(path string) (*lockFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	f.Close()
+	return &lockFile{}, nil
+}
+
+func (l *lockFile) unlock(path string) error {
+	return os.Remove(path)
+}
@@ -0,0 +1,45 @@
+package filelock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	l, err := Acquire(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// busy until released
+	ctx, cancel := context.WithTimeout(context.Background(), 3*pollInterval)
+	defer cancel()
+	if _, err := Acquire(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the lock to be busy, got %v", err)
+	}
+
+	acquired := make(chan error)
+	go func() {
+		l, err := Acquire(context.Background(), path)
+		if err == nil {
+			err = l.Release()
+		}
+		acquired <- err
+	}()
+	time.Sleep(pollInterval)
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * pollInterval):
+		t.Fatal("lock not acquired after release")
+	}
+}
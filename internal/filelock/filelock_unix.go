@@ -0,0 +1,35 @@
+//go:build unix
+
+package filelock
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile is the open file flock(2) is held on, the lock is released if the process dies.
+type lockFile struct {
+	f *os.File
+}
+
+// tryLock returns nil without an error if the file is locked by someone else.
+func tryLock(path string) (*lockFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) || errors.Is(err, syscall.EINTR) {
+			return nil, nil
+		}
+		return nil, &os.PathError{Op: "flock", Path: path, Err: err}
+	}
+	return &lockFile{f}, nil
+}
+
+func (l *lockFile) unlock(path string) error {
+	// the file is kept, removing it would race with processes which have opened it already.
+	return l.f.Close()
+}
@@ -0,0 +1,125 @@
+// Package textdiff renders line-based unified diffs of small text files, e.g. config files
+// rewritten by llpkgstore, for previews.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines around changes in a hunk.
+const context = 3
+
+// op is a line of an edit script: ' ' kept, '-' deleted or '+' inserted.
+type op struct {
+	kind byte
+	line string
+}
+
+// lines splits s into lines, without the trailing newline of the last one.
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// edits returns the edit script from a to b by the longest common subsequence of their lines.
+// It's quadratic, which is fine for files of a few hundred lines.
+func edits(a, b []string) []op {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// Unified returns the unified diff from old, named oldName, to new, named newName,
+// or an empty string if they're equal.
+func Unified(oldName, newName, old, new string) string {
+	ops := edits(lines(old), lines(new))
+	var out strings.Builder
+	// the line numbers before ops[k], in old and new.
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	oldLine[0], newLine[0] = 1, 1
+	for k, o := range ops {
+		oldLine[k+1], newLine[k+1] = oldLine[k], newLine[k]
+		if o.kind != '+' {
+			oldLine[k+1]++
+		}
+		if o.kind != '-' {
+			newLine[k+1]++
+		}
+	}
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		// a hunk spans changes separated by at most 2*context unchanged lines.
+		start := max(0, k-context)
+		end := k
+		for unchanged := 0; end < len(ops) && unchanged <= 2*context; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// trim the trailing unchanged lines to context.
+		for end > k && ops[end-1].kind == ' ' {
+			end--
+		}
+		end = min(len(ops), end+context)
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		oldCount, newCount := oldLine[end]-oldLine[start], newLine[end]-newLine[start]
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldCount), hunkRange(newLine[start], newCount))
+		for _, o := range ops[start:end] {
+			out.WriteByte(o.kind)
+			out.WriteString(o.line)
+			out.WriteByte('\n')
+		}
+		k = end
+	}
+	return out.String()
+}
+
+// hunkRange formats the range of a hunk, the line before it if it's empty.
+func hunkRange(line, count int) string {
+	if count == 0 {
+		line--
+	}
+	if count == 1 {
+		return fmt.Sprint(line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
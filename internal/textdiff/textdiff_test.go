@@ -0,0 +1,51 @@
+package textdiff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	if diff := Unified("a", "b", "x\ny\n", "x\ny\n"); diff != "" {
+		t.Errorf("unexpected diff of equal texts: %q", diff)
+	}
+
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	new := "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\nb\n13\n14\n15\n"
+	want := `--- llpkg.cfg
++++ llpkg.cfg (migrated)
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -9,7 +10,7 @@
+ 9
+ 10
+ 11
+-12
++b
+ 13
+ 14
+ 15
+`
+	if diff := Unified("llpkg.cfg", "llpkg.cfg (migrated)", old, new); diff != want {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	// changes close to each other share a hunk.
+	want = `--- a
++++ b
+@@ -1,5 +1,4 @@
+-1
+ 2
+ 3
+-4
++d
+ 5
+`
+	if diff := Unified("a", "b", "1\n2\n3\n4\n5\n", "2\n3\nd\n5\n"); diff != want {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	if diff := Unified("a", "b", "", "x\n"); diff != "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n" {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
@@ -1,6 +1,8 @@
 package metadata
 
 import (
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,12 +11,100 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/goplus/llpkgstore/internal/filelock"
 )
 
 var (
 	ErrCacheFileNotFound = errors.New("cache file not found")
+	// ErrOffline is returned by Cache.Update in offline mode, see WithOffline.
+	ErrOffline = errors.New("cache is offline")
+)
+
+// Source tells where the data of a Cache comes from.
+type Source int
+
+const (
+	// SourceDisk is data loaded from the cache file and not refreshed since.
+	SourceDisk Source = iota
+	// SourceRemote is data fetched, or revalidated, from the remote.
+	SourceRemote
+	// SourceStale is data which couldn't be refreshed, once older than the max age (see WithMaxAge)
+	// or to answer a miss of a Manager.
+	SourceStale
 )
 
+func (s Source) String() string {
+	switch s {
+	case SourceDisk:
+		return "disk"
+	case SourceRemote:
+		return "remote"
+	case SourceStale:
+		return "stale"
+	}
+	return fmt.Sprintf("Source(%d)", int(s))
+}
+
+// CacheInfo describes the data of a Cache.
+type CacheInfo struct {
+	Source Source
+	// UpdatedAt is when the data was last fetched or revalidated from the remote,
+	// or the modification time of the cache file it was loaded from.
+	UpdatedAt time.Time
+}
+
+// Age returns how long ago the data was updated.
+func (i CacheInfo) Age() time.Duration {
+	return time.Since(i.UpdatedAt)
+}
+
+// CacheOption configures a Cache, see NewCache.
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	offline       bool
+	maxAge        time.Duration
+	trustedKeys   []ed25519.PublicKey
+	allowUnsigned bool
+}
+
+// WithOffline never goes to the remote: the cache serves the cache file, which must exist, and Update returns ErrOffline.
+func WithOffline() CacheOption {
+	return func(o *cacheOptions) {
+		o.offline = true
+	}
+}
+
+// WithMaxAge refreshes the data once it's older than maxAge, at creation and by Refresh,
+// and keeps serving it as SourceStale if the refresh fails.
+// Without it, the data loaded from disk isn't refreshed at creation and Refresh always updates it.
+func WithMaxAge(maxAge time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithTrustedKeys verifies the data, fetched or loaded from disk, against its detached signature
+// published at the remote URL with SignatureSuffix, and rejects it unless it's signed by one of keys.
+// Trusting the keys before and after a rotation accepts the data signed by either, see Sign.
+// Fetched data signed before the cached data is rejected with ErrRollback, so that an old copy can't be replayed.
+// Without it, the data isn't verified.
+func WithTrustedKeys(keys ...ed25519.PublicKey) CacheOption {
+	return func(o *cacheOptions) {
+		o.trustedKeys = append(o.trustedKeys, keys...)
+	}
+}
+
+// WithUnsignedAllowed accepts data without a signature along with WithTrustedKeys,
+// e.g. while a mirror doesn't publish signatures yet. Data with a bad signature is still rejected,
+// and so is unsigned data replacing signed data.
+func WithUnsignedAllowed() CacheOption {
+	return func(o *cacheOptions) {
+		o.allowUnsigned = true
+	}
+}
+
 // Cache represents a local cache for storing and retrieving data.
 // It binds a local file path and a remote data source URL
 type Cache[T any] struct {
@@ -22,116 +112,281 @@ type Cache[T any] struct {
 
 	cacheFilePath string // local file path for cache storage
 	remoteUrl     string // URL of the remote data source
+	opts          cacheOptions
+
+	raw        []byte     // the cached data as fetched, which the signature is of
+	signature  []byte     // detached signature of raw, if any
+	signedAt   time.Time  // timestamp of signature, zero if unsigned
+	validators validators // validators of the cached data
+	loaded     bool       // whether data has been loaded from disk or remote
+	info       CacheInfo
+}
+
+const (
+	// validatorsSuffix is the suffix of the sidecar file holding the validators of the cache file.
+	validatorsSuffix = ".meta"
+	// lockSuffix is the suffix of the file locked by updates.
+	lockSuffix = ".lock"
+)
 
-	modTime time.Time // last modified time of the cached data
+// validators are the HTTP validators of the cached data returned by the remote,
+// sent back in conditional requests so that unmodified data isn't downloaded again.
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// FetchedAt is when the data was last fetched or revalidated.
+	FetchedAt time.Time `json:"fetchedAt"`
 }
 
 // NewCache initializes and loads the cache from disk or remote source
-func NewCache[T any](cacheFilePath, remoteUrl string) (*Cache[T], error) {
+func NewCache[T any](cacheFilePath, remoteUrl string, opts ...CacheOption) (*Cache[T], error) {
 	cache := &Cache[T]{
 		cacheFilePath: cacheFilePath,
 		remoteUrl:     remoteUrl,
 	}
+	for _, o := range opts {
+		o(&cache.opts)
+	}
 
 	err := cache.loadFromDisk()
-	if err != nil {
+	switch {
+	case err == nil:
+		if cache.opts.maxAge > 0 {
+			err = cache.Refresh()
+		}
+	case cache.opts.offline:
+		// nothing to serve
+	default:
 		// local cache missing or invalid, fetch from remote
 		err = cache.Update()
-		if err != nil {
-			return nil, fmt.Errorf("error building cache: %v", err)
-		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error building cache: %w", err)
 	}
 
 	return cache, nil
 }
 
 // Update refreshes the cache by fetching remote data and saving to disk
+//
+// The update holds a lock on the cache file shared with other processes. It starts from
+// the cache file, which another process may have updated meanwhile, so that a 304 revalidates it.
 func (c *Cache[T]) Update() error {
-	err := c.fetch()
+	if c.opts.offline {
+		return ErrOffline
+	}
+
+	l, err := c.lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	if err := c.loadFromDisk(); err != nil {
+		// without a valid cache file, a 304 would leave nothing to serve.
+		c.validators = validators{}
+	}
+	modified, err := c.fetch()
 	if err != nil {
 		return err
 	}
 
-	err = c.saveToDisk()
+	c.validators.FetchedAt = time.Now()
+	err = c.saveToDisk(modified)
 	if err != nil {
 		return err
 	}
 
+	c.loaded = true
+	c.info = CacheInfo{Source: SourceRemote, UpdatedAt: c.validators.FetchedAt}
 	return nil
 }
 
-// fetch retrieves the latest data from the remote source using conditional requests
-func (c *Cache[T]) fetch() error {
-	// Create HTTP request with If-Modified-Since header to reduce unnecessary downloads
+// Refresh updates the cache following its policy: never in offline mode,
+// only once the data is older than the max age if there's one, always otherwise.
+// If the update fails with a max age, the data loaded before is kept as SourceStale and nil is returned.
+func (c *Cache[T]) Refresh() error {
+	if c.opts.offline {
+		return nil
+	}
+	if c.opts.maxAge == 0 {
+		return c.Update()
+	}
+	if c.loaded && c.info.Age() <= c.opts.maxAge {
+		return nil
+	}
+	return c.updateOrStale()
+}
+
+// updateOrStale updates the cache whatever the age of the data, except in offline mode.
+// If the update fails, the data loaded before is kept as SourceStale and nil is returned.
+func (c *Cache[T]) updateOrStale() error {
+	if c.opts.offline {
+		return nil
+	}
+	err := c.Update()
+	if err != nil && c.loaded {
+		c.info.Source = SourceStale
+		return nil
+	}
+	return err
+}
+
+// Info returns the source and age of the data.
+func (c *Cache[T]) Info() CacheInfo {
+	return c.info
+}
+
+// fetch retrieves the latest data from the remote source using conditional requests.
+// It reports whether the data has been modified, i.e. not revalidated with a 304.
+func (c *Cache[T]) fetch() (bool, error) {
+	// Send the validators of the cached data to reduce unnecessary downloads
 	req, err := http.NewRequest("GET", c.remoteUrl, nil)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if c.validators.ETag != "" {
+		req.Header.Set("If-None-Match", c.validators.ETag)
 	}
-	if !c.modTime.IsZero() {
-		req.Header.Set("If-Modified-Since", c.modTime.Format(http.TimeFormat))
+	if c.validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", c.validators.LastModified)
 	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer resp.Body.Close()
 
 	switch resp.StatusCode {
 	case http.StatusNotModified:
-		return nil
+		return false, nil
 	case http.StatusOK:
 		// Read and parse the response body
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return err
+			return false, err
+		}
+
+		var signature []byte
+		var signedAt time.Time
+		if len(c.opts.trustedKeys) > 0 {
+			if signature, err = c.fetchSignature(); err != nil {
+				return false, err
+			}
+			if signedAt, err = c.verify(body, signature); err != nil {
+				return false, fmt.Errorf("%s: %w", c.remoteUrl, err)
+			}
+			if signedAt.Before(c.signedAt) {
+				return false, fmt.Errorf("%s: signed at %s, cached signed at %s: %w",
+					c.remoteUrl, signedAt.Format(time.RFC3339), c.signedAt.Format(time.RFC3339), ErrRollback)
+			}
 		}
 
 		var bodyData T
 		err = json.Unmarshal(body, &bodyData)
 		if err != nil {
-			return err
+			return false, err
 		}
-		c.data = bodyData
+		c.data, c.raw, c.signature, c.signedAt = bodyData, body, signature, signedAt
 
-		// Update last modified time from response headers
-		lastModified := resp.Header.Get("Last-Modified")
-		if lastModified != "" {
-			c.modTime, err = time.Parse(http.TimeFormat, lastModified)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
+		// Keep the validators of the server for the next request
+		c.validators.ETag = resp.Header.Get("ETag")
+		c.validators.LastModified = resp.Header.Get("Last-Modified")
+		return true, nil
 	default:
-		return fmt.Errorf("HTTP error %d: %s", resp.StatusCode, resp.Status)
+		return false, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, resp.Status)
+	}
+}
+
+// fetchSignature retrieves the detached signature of the remote data, nil if it isn't signed.
+func (c *Cache[T]) fetchSignature() ([]byte, error) {
+	resp, err := http.Get(c.remoteUrl + SignatureSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, resp.Status)
+	}
+}
+
+// verify verifies data against its signature following the options of c,
+// and returns the timestamp of the signature, zero if data isn't signed.
+func (c *Cache[T]) verify(data, signature []byte) (time.Time, error) {
+	if len(c.opts.trustedKeys) == 0 {
+		return time.Time{}, nil
+	}
+	signedAt, err := Verify(data, signature, c.opts.trustedKeys)
+	if errors.Is(err, ErrUnsigned) && c.opts.allowUnsigned {
+		return time.Time{}, nil
 	}
+	return signedAt, err
 }
 
 func (c *Cache[T]) Data() T {
 	return c.data
 }
 
-// saveToDisk persists the current cache data to the local file system
-func (c *Cache[T]) saveToDisk() error {
-	// Create directory structure if needed
+// lock locks the cache file against the updates of other processes.
+func (c *Cache[T]) lock() (*filelock.Lock, error) {
 	err := os.MkdirAll(filepath.Dir(c.cacheFilePath), 0755)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return filelock.Acquire(context.Background(), c.cacheFilePath+lockSuffix)
+}
 
-	// Serialize data to JSON
-	file, err := json.Marshal(c.data)
+// saveToDisk persists the current cache data, if modified, and its validators to the local file system.
+// Both files are replaced atomically, so readers never see a partial write.
+func (c *Cache[T]) saveToDisk(modified bool) error {
+	if modified {
+		// Keep the signature of the data, if any, next to it
+		signaturePath := c.cacheFilePath + SignatureSuffix
+		if c.signature != nil {
+			if err := writeFileAtomic(signaturePath, c.signature); err != nil {
+				return err
+			}
+		} else if err := os.Remove(signaturePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		// Write the data as fetched, which the signature is of
+		if err := writeFileAtomic(c.cacheFilePath, c.raw); err != nil {
+			return err
+		}
+	}
+
+	file, err := json.Marshal(c.validators)
 	if err != nil {
 		return err
 	}
+	return writeFileAtomic(c.cacheFilePath+validatorsSuffix, file)
+}
 
-	// Write to file with proper permissions
-	err = os.WriteFile(c.cacheFilePath, file, 0644)
+// writeFileAtomic writes data to a temporary file in the directory of path and renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return err
 	}
-
-	return nil
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+	// Write with proper permissions
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
 }
 
 func (c *Cache[T]) loadFromDisk() error {
@@ -142,20 +397,40 @@ func (c *Cache[T]) loadFromDisk() error {
 			return fmt.Errorf("error read file from cache: %v", err)
 		}
 
+		// Verify the cache file, which may have been written with other trusted keys.
+		signature, err := os.ReadFile(c.cacheFilePath + SignatureSuffix)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		signedAt, err := c.verify(file, signature)
+		if err != nil {
+			return fmt.Errorf("error verifying cache: %w", err)
+		}
+
 		// Unmarshal the cache file.
 		var fileData T
 		err = json.Unmarshal(file, &fileData)
 		if err != nil {
 			return fmt.Errorf("error json unmarshal from cache: %v", err)
 		}
-		c.data = fileData
+		c.data, c.raw, c.signature, c.signedAt = fileData, file, signature, signedAt
 
-		// Get the last modified time of the cache.
-		fileInfo, err := os.Stat(c.cacheFilePath)
-		if err != nil {
-			return err
+		// Read the validators, the data is revalidated unconditionally without them.
+		c.validators = validators{}
+		if file, err := os.ReadFile(c.cacheFilePath + validatorsSuffix); err == nil {
+			json.Unmarshal(file, &c.validators)
+		}
+		updatedAt := c.validators.FetchedAt
+		if updatedAt.IsZero() {
+			// Get the last modified time of the cache.
+			fileInfo, err := os.Stat(c.cacheFilePath)
+			if err != nil {
+				return err
+			}
+			updatedAt = fileInfo.ModTime()
 		}
-		c.modTime = fileInfo.ModTime()
+		c.loaded = true
+		c.info = CacheInfo{Source: SourceDisk, UpdatedAt: updatedAt}
 	} else {
 		return ErrCacheFileNotFound
 	}
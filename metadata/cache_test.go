@@ -1,23 +1,28 @@
 package metadata
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 // Test data
 var testCacheData = MetadataMap{
-	"example-module": &Metadata{
-		Versions: map[CVersion][]GoVersion{
-			"1.7.18": {"v1.2.0"},
-		},
-	},
+	"example-module": newTestMetadata(
+		VersionMapping{C: "1.7.18", Go: []GoVersion{"v1.2.0"}},
+	),
 }
 
 func TestCache_InitFromRemote(t *testing.T) {
@@ -67,15 +72,24 @@ func TestCache_LoadFromDisk(t *testing.T) {
 	}
 }
 
-// Test HTTP 304 Not Modified response
+// Test HTTP 304 Not Modified response to the validators returned by the server
 func TestCache_Fetch304(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Sat, 01 Jan 2022 00:00:00 GMT"
+	)
+	var requests atomic.Int64
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("If-Modified-Since") == "old-time" {
+		if requests.Add(1) > 1 {
+			if r.Header.Get("If-None-Match") != etag || r.Header.Get("If-Modified-Since") != lastModified {
+				t.Errorf("unexpected validators: %q, %q", r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since"))
+			}
 			w.WriteHeader(http.StatusNotModified)
-		} else {
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(testCacheData)
+			return
 		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		json.NewEncoder(w).Encode(testCacheData)
 	}))
 	defer server.Close()
 
@@ -83,21 +97,30 @@ func TestCache_Fetch304(t *testing.T) {
 	cachePath := filepath.Join(tmpDir, "cache.json")
 
 	// Initial load
-	cache, _ := NewCache[MetadataMap](cachePath, server.URL)
-
-	// Set modTime to an old time
-	cache.modTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := NewCache[MetadataMap](cachePath, server.URL); err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
 
-	// Second fetch should return 304
-	err := cache.fetch()
+	// The validators are persisted, and sent by another cache of the same file
+	cache, err := NewCache[MetadataMap](cachePath, server.URL)
 	if err != nil {
-		t.Fatalf("Failed to fetch cache: %v", err)
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	err = cache.Update()
+	if err != nil {
+		t.Fatalf("Failed to update cache: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("%d requests, want 2", requests.Load())
 	}
 
 	// Validate data remains unchanged
 	if !reflect.DeepEqual(cache.Data(), testCacheData) {
 		t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
 	}
+	if cache.Info().Source != SourceRemote {
+		t.Errorf("unexpected source %v", cache.Info().Source)
+	}
 }
 
 // Test invalid JSON response
@@ -114,3 +137,404 @@ func TestCache_InvalidJSON(t *testing.T) {
 		t.Errorf("Expected error for invalid JSON response, but got nil")
 	}
 }
+
+// countingServer returns a server serving testCacheData, or failing if fail is set, and counting its requests.
+func countingServer(t *testing.T, fail bool) (*httptest.Server, *atomic.Int64) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(testCacheData)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// writeCacheFile writes testCacheData to a cache file modified age ago.
+func writeCacheFile(t *testing.T, age time.Duration) string {
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	err := os.WriteFile(cachePath, []byte(`{"example-module":{"versions":[{"c":"1.7.18","go":["v1.2.0"]}]}}`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write cache file: %v", err)
+	}
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(cachePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return cachePath
+}
+
+// Test offline mode serving the cache file without any request
+func TestCache_Offline(t *testing.T) {
+	server, requests := countingServer(t, false)
+
+	cache, err := NewCache[MetadataMap](writeCacheFile(t, 48*time.Hour), server.URL, WithOffline(), WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	if !reflect.DeepEqual(cache.Data(), testCacheData) {
+		t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+	}
+	if err := cache.Refresh(); err != nil {
+		t.Errorf("Refresh failed offline: %v", err)
+	}
+	if err := cache.Update(); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected ErrOffline, got %v", err)
+	}
+	if info := cache.Info(); info.Source != SourceDisk || info.Age() < 47*time.Hour {
+		t.Errorf("unexpected cache info: %v, age %v", info.Source, info.Age())
+	}
+	if requests.Load() != 0 {
+		t.Errorf("%d requests sent offline", requests.Load())
+	}
+
+	_, err = NewCache[MetadataMap](filepath.Join(t.TempDir(), "missing.json"), server.URL, WithOffline())
+	if !errors.Is(err, ErrCacheFileNotFound) {
+		t.Errorf("Expected ErrCacheFileNotFound, got %v", err)
+	}
+}
+
+// Test the max age policy: fresh data is served from disk, old data is refreshed, or served stale on failure
+func TestCache_MaxAge(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		age      time.Duration
+		fail     bool
+		source   Source
+		requests int64
+	}{
+		{"fresh", time.Minute, false, SourceDisk, 0},
+		{"refreshed", 48 * time.Hour, false, SourceRemote, 1},
+		{"stale", 48 * time.Hour, true, SourceStale, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, requests := countingServer(t, tc.fail)
+
+			cache, err := NewCache[MetadataMap](writeCacheFile(t, tc.age), server.URL, WithMaxAge(time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+			if !reflect.DeepEqual(cache.Data(), testCacheData) {
+				t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+			}
+			if cache.Info().Source != tc.source {
+				t.Errorf("unexpected source %v, want %v", cache.Info().Source, tc.source)
+			}
+			if requests.Load() != tc.requests {
+				t.Errorf("%d requests, want %d", requests.Load(), tc.requests)
+			}
+		})
+	}
+
+	// without data to fall back to, the failure is returned.
+	server, _ := countingServer(t, true)
+	if _, err := NewCache[MetadataMap](filepath.Join(t.TempDir(), "missing.json"), server.URL, WithMaxAge(time.Hour)); err == nil {
+		t.Error("Expected error, but got nil")
+	}
+}
+
+// Test that an update revalidates the cache file written by another process
+func TestCache_UpdateSharedFile(t *testing.T) {
+	var version atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"%d"`, version.Load())
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		data := MetadataMap{"example-module": &Metadata{}}
+		for i := int64(0); i <= version.Load(); i++ {
+			data["example-module"].Add("1.7.18", fmt.Sprintf("v1.2.%d", i))
+		}
+		w.Header().Set("ETag", etag)
+		json.NewEncoder(w).Encode(data)
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	first, err := NewCache[MetadataMap](cachePath, server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	second, err := NewCache[MetadataMap](cachePath, server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	version.Store(1)
+	if err := first.Update(); err != nil {
+		t.Fatalf("Failed to update cache: %v", err)
+	}
+	// second gets a 304 for the file written by first
+	if err := second.Update(); err != nil {
+		t.Fatalf("Failed to update cache: %v", err)
+	}
+	want := []GoVersion{"v1.2.0", "v1.2.1"}
+	if got := second.Data()["example-module"].GoVersions("1.7.18"); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected data: %v, want %v", got, want)
+	}
+}
+
+// Test concurrent updates of the same cache file while it's read, run it with -race
+func TestCache_ConcurrentUpdates(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a new version on every request, large enough for torn writes to show
+		n := requests.Add(1)
+		data := MetadataMap{}
+		for i := 0; i < 200; i++ {
+			data[fmt.Sprintf("module-%d", i)] = newTestMetadata(
+				VersionMapping{C: "1.0.0", Go: []GoVersion{fmt.Sprintf("v1.0.%d", n)}},
+			)
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, n))
+		json.NewEncoder(w).Encode(data)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	cachePath := filepath.Join(tmpDir, "cache.json")
+	if _, err := NewCache[MetadataMap](cachePath, server.URL); err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			// every cache is like another process sharing the file
+			cache, err := NewCache[MetadataMap](cachePath, server.URL)
+			if err != nil {
+				errs <- err
+				return
+			}
+			for j := 0; j < 10; j++ {
+				if err := cache.Update(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				cache, err := NewCache[MetadataMap](cachePath, "https://unused.com")
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(cache.Data()) != 200 {
+					errs <- fmt.Errorf("read %d modules", len(cache.Data()))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"cache.json", "cache.json.lock", "cache.json.meta"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected files %v, want %v", names, want)
+	}
+}
+
+// signedServer returns a server serving data and its signature sig, none if sig is nil.
+func signedServer(t *testing.T, data, sig []byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/llpkgstore.json":
+			w.Write(data)
+		case r.URL.Path == "/llpkgstore.json"+SignatureSuffix && sig != nil:
+			w.Write(sig)
+		default:
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Test verification of the fetched data against the trusted keys
+func TestCache_TrustedKeys(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"example-module":{"versions":[{"c":"1.7.18","go":["v1.2.0"]}]}}`)
+
+	for _, tc := range []struct {
+		name string
+		sig  []byte
+		opts []CacheOption
+		want error
+	}{
+		{"signed", Sign(data, time.Now(), private), nil, nil},
+		{"badly signed", Sign(data, time.Now(), otherPrivate), nil, ErrBadSignature},
+		{"badly signed, unsigned allowed", Sign(data, time.Now(), otherPrivate), []CacheOption{WithUnsignedAllowed()}, ErrBadSignature},
+		{"unsigned", nil, nil, ErrUnsigned},
+		{"unsigned allowed", nil, []CacheOption{WithUnsignedAllowed()}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := signedServer(t, data, tc.sig)
+			cachePath := filepath.Join(t.TempDir(), "cache.json")
+			opts := append([]CacheOption{WithTrustedKeys(public)}, tc.opts...)
+
+			cache, err := NewCache[MetadataMap](cachePath, server.URL+"/llpkgstore.json", opts...)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+			if err != nil {
+				if _, err := os.Stat(cachePath); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("rejected data saved: %v", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(cache.Data(), testCacheData) {
+				t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+			}
+
+			// the cache file is verified when loaded, offline too
+			if _, err := NewCache[MetadataMap](cachePath, server.URL+"/llpkgstore.json", append(opts, WithOffline())...); err != nil {
+				t.Errorf("Failed to load verified cache: %v", err)
+			}
+		})
+	}
+}
+
+// Test that a tampered cache file is rejected and fetched again
+func TestCache_TamperedFile(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"example-module":{"versions":[{"c":"1.7.18","go":["v1.2.0"]}]}}`)
+	server := signedServer(t, data, Sign(data, time.Now(), private))
+	remoteUrl := server.URL + "/llpkgstore.json"
+
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	if _, err := NewCache[MetadataMap](cachePath, remoteUrl, WithTrustedKeys(public)); err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	tampered := []byte(`{"example-module":{"versions":[{"c":"1.7.18","go":["v6.6.6"]}]}}`)
+	if err := os.WriteFile(cachePath, tampered, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewCache[MetadataMap](cachePath, remoteUrl, WithTrustedKeys(public), WithOffline()); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature offline, got %v", err)
+	}
+	cache, err := NewCache[MetadataMap](cachePath, remoteUrl, WithTrustedKeys(public))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	if !reflect.DeepEqual(cache.Data(), testCacheData) {
+		t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+	}
+}
+
+// Test that the data isn't verified without trusted keys
+func TestCache_NoTrustedKeys(t *testing.T) {
+	// a signature of other data doesn't matter without trusted keys
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := signedServer(t, []byte(`{}`), Sign([]byte(`{"other": {}}`), time.Now(), private))
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	cache, err := NewCache[MetadataMap](cachePath, server.URL+"/llpkgstore.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cache.Data()) != 0 {
+		t.Errorf("unexpected data: %v", cache.Data())
+	}
+}
+
+// Test that fetched data signed before the cached data is rejected
+func TestCache_Rollback(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := []byte(`{"example-module":{"versions":[{"c":"1.7.18","go":["v1.2.0"]}]}}`)
+	current := []byte(`{"example-module":{"versions":[{"c":"1.7.18","go":["v1.2.0","v1.2.1"]}]}}`)
+	now := time.Now()
+	oldSig, currentSig := Sign(old, now.Add(-time.Hour), private), Sign(current, now, private)
+
+	var mu sync.Mutex
+	data, sig := current, currentSig
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/llpkgstore.json":
+			w.Write(data)
+		case r.URL.Path == "/llpkgstore.json"+SignatureSuffix && sig != nil:
+			w.Write(sig)
+		default:
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	serve := func(d, s []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		data, sig = d, s
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	cache, err := NewCache[MetadataMap](cachePath, server.URL+"/llpkgstore.json", WithTrustedKeys(public), WithUnsignedAllowed())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	// an old copy, validly signed, and an unsigned copy are both rejected
+	for name, replayed := range map[string][2][]byte{"old": {old, oldSig}, "unsigned": {old, nil}} {
+		serve(replayed[0], replayed[1])
+		if err := cache.Update(); !errors.Is(err, ErrRollback) {
+			t.Errorf("%s: expected ErrRollback, got %v", name, err)
+		}
+		if got := cache.Data()["example-module"].GoVersions("1.7.18"); len(got) != 2 {
+			t.Errorf("%s: cached data replaced: %v", name, got)
+		}
+		if b, _ := os.ReadFile(cachePath); !bytes.Equal(b, current) {
+			t.Errorf("%s: cache file replaced: %s", name, b)
+		}
+	}
+
+	// a new process starts from the cache file, and rejects the old copy as well
+	serve(old, oldSig)
+	second, err := NewCache[MetadataMap](cachePath, server.URL+"/llpkgstore.json", WithTrustedKeys(public))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	if err := second.Update(); !errors.Is(err, ErrRollback) {
+		t.Errorf("expected ErrRollback, got %v", err)
+	}
+
+	// the same data signed again is accepted
+	serve(current, Sign(current, now, private))
+	if err := cache.Update(); err != nil {
+		t.Errorf("Failed to update with data signed at the same time: %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// VersionMapping is an entry of Metadata.Mappings, the Go versions converted from a C version.
+type VersionMapping struct {
+	C  CVersion    `json:"c"`
+	Go []GoVersion `json:"go"`
+}
+
+// Format is the form of the versions of the llpkgs in llpkgstore.json.
+type Format int
+
+const (
+	// FormatArray is the documented form, an array of version mappings:
+	//
+	//	"versions": [{"c": "1.3", "go": ["v0.1.0", "v0.1.1"]}]
+	FormatArray Format = iota
+	// FormatLegacy is the form read by older llgo releases, an object mapping C versions to Go versions:
+	//
+	//	"versions": {"1.3": ["v0.1.0", "v0.1.1"]}
+	FormatLegacy
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatArray:
+		return "array"
+	case FormatLegacy:
+		return "legacy"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat parses the name of a Format, as returned by its String method.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range []Format{FormatArray, FormatLegacy} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown llpkgstore.json format %q, expected array or legacy", name)
+}
+
+// legacyMetadata is Metadata in FormatLegacy.
+type legacyMetadata struct {
+	Versions map[CVersion][]GoVersion `json:"versions"`
+}
+
+// isLegacyVersions reports whether data, the versions of an llpkg, is in FormatLegacy.
+func isLegacyVersions(data json.RawMessage) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{'
+}
+
+// UnmarshalJSON decodes m in either Format, filling both Versions and the ordered mappings.
+// Legacy mappings are ordered by C version, as the object they're decoded from has no order.
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Versions json.RawMessage `json:"versions"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	m.Versions, m.mappings = nil, nil
+	if !isLegacyVersions(aux.Versions) {
+		if len(aux.Versions) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(aux.Versions, &m.mappings); err != nil {
+			return err
+		}
+		m.Versions = legacyVersions(m.mappings)
+		return nil
+	}
+	if err := json.Unmarshal(aux.Versions, &m.Versions); err != nil {
+		return err
+	}
+	m.mappings = sortedMappings(m.Versions)
+	return nil
+}
+
+// MarshalJSON encodes m in FormatArray.
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Versions []VersionMapping `json:"versions"`
+	}{m.Mappings()})
+}
+
+// legacyVersions returns mappings in the form of Metadata.Versions,
+// merging the Go versions of mappings of the same C version.
+func legacyVersions(mappings []VersionMapping) map[CVersion][]GoVersion {
+	if mappings == nil {
+		return nil
+	}
+	versions := make(map[CVersion][]GoVersion, len(mappings))
+	for _, mapping := range mappings {
+		versions[mapping.C] = append(versions[mapping.C], mapping.Go...)
+	}
+	return versions
+}
+
+// sortedMappings returns the mappings of versions ordered by C version.
+func sortedMappings(versions map[CVersion][]GoVersion) []VersionMapping {
+	var mappings []VersionMapping
+	for cVersion, goVersions := range versions {
+		mappings = append(mappings, VersionMapping{C: cVersion, Go: goVersions})
+	}
+	slices.SortFunc(mappings, func(a, b VersionMapping) int {
+		return compareCVersions(a.C, b.C)
+	})
+	return mappings
+}
+
+// Mappings returns the version mappings of m in the order they've been released,
+// followed by those only set in m.Versions ordered by C version.
+// The Go versions are shared with m, they must not be modified.
+func (m *Metadata) Mappings() []VersionMapping {
+	if m == nil {
+		return nil
+	}
+	mappings := m.mappings
+	var missing map[CVersion][]GoVersion
+	for cVersion, goVersions := range m.Versions {
+		for _, goVersion := range goVersions {
+			if !slices.ContainsFunc(m.mappings, func(mapping VersionMapping) bool {
+				return mapping.C == cVersion && slices.Contains(mapping.Go, goVersion)
+			}) {
+				if missing == nil {
+					missing = map[CVersion][]GoVersion{}
+				}
+				missing[cVersion] = append(missing[cVersion], goVersion)
+			}
+		}
+	}
+	if missing != nil {
+		mappings = append(slices.Clip(mappings), sortedMappings(missing)...)
+	}
+	return mappings
+}
+
+// compareCVersions compares C versions as semantic versions, falling back to their strings.
+func compareCVersions(a, b CVersion) int {
+	toSemVer := func(v string) string {
+		if !strings.HasPrefix(v, "v") {
+			v = "v" + v
+		}
+		return semver.Canonical(v)
+	}
+	if cmp := semver.Compare(toSemVer(a), toSemVer(b)); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(a, b)
+}
+
+// GoVersions returns the Go versions mapped from cVersion, none if it isn't mapped.
+func (m *Metadata) GoVersions(cVersion CVersion) []GoVersion {
+	if m == nil {
+		return nil
+	}
+	var goVersions []GoVersion
+	for _, mapping := range m.Mappings() {
+		if mapping.C == cVersion {
+			goVersions = append(goVersions, mapping.Go...)
+		}
+	}
+	return goVersions
+}
+
+// CVersion returns the C version goVersion is mapped from, or "" if it isn't mapped.
+func (m *Metadata) CVersion(goVersion GoVersion) CVersion {
+	if m == nil {
+		return ""
+	}
+	for _, mapping := range m.Mappings() {
+		if slices.Contains(mapping.Go, goVersion) {
+			return mapping.C
+		}
+	}
+	return ""
+}
+
+// Add maps goVersion from cVersion, after the Go versions already mapped from it,
+// or in a new mapping at the end if cVersion isn't mapped yet. It updates both Versions and Mappings.
+func (m *Metadata) Add(cVersion CVersion, goVersion GoVersion) {
+	if m.Versions == nil {
+		m.Versions = map[CVersion][]GoVersion{}
+	}
+	m.Versions[cVersion] = append(m.Versions[cVersion], goVersion)
+	for i := range m.mappings {
+		if m.mappings[i].C == cVersion {
+			m.mappings[i].Go = append(m.mappings[i].Go, goVersion)
+			return
+		}
+	}
+	m.mappings = append(m.mappings, VersionMapping{C: cVersion, Go: []GoVersion{goVersion}})
+}
+
+// Clone returns a deep copy of m, nil if m is nil.
+func (m *Metadata) Clone() *Metadata {
+	if m == nil {
+		return nil
+	}
+	clone := &Metadata{}
+	if m.Versions != nil {
+		clone.Versions = make(map[CVersion][]GoVersion, len(m.Versions))
+	}
+	for cVersion, goVersions := range m.Versions {
+		clone.Versions[cVersion] = slices.Clone(goVersions)
+	}
+	if m.mappings != nil {
+		clone.mappings = make([]VersionMapping, 0, len(m.mappings))
+	}
+	for _, mapping := range m.mappings {
+		clone.mappings = append(clone.mappings, VersionMapping{C: mapping.C, Go: slices.Clone(mapping.Go)})
+	}
+	return clone
+}
+
+// DetectFormat returns the Format of data, the content of llpkgstore.json.
+// A file with an llpkg in the legacy form is FormatLegacy, even if others are in the array form.
+func DetectFormat(data []byte) (Format, error) {
+	var raw map[string]struct {
+		Versions json.RawMessage `json:"versions"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return 0, err
+	}
+	for _, m := range raw {
+		if isLegacyVersions(m.Versions) {
+			return FormatLegacy, nil
+		}
+	}
+	return FormatArray, nil
+}
+
+// Encode returns the content of llpkgstore.json for m in format.
+// In FormatLegacy, the Go versions of mappings of the same C version are merged.
+func (m MetadataMap) Encode(format Format) ([]byte, error) {
+	switch format {
+	case FormatArray:
+		return json.Marshal(m)
+	case FormatLegacy:
+		legacy := make(map[string]legacyMetadata, len(m))
+		for name, metadata := range m {
+			versions := legacyVersions(metadata.Mappings())
+			if versions == nil {
+				versions = map[CVersion][]GoVersion{}
+			}
+			legacy[name] = legacyMetadata{Versions: versions}
+		}
+		return json.Marshal(legacy)
+	}
+	return nil, fmt.Errorf("unknown llpkgstore.json format %v", format)
+}
@@ -0,0 +1,137 @@
+package metadata
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// newTestMetadata returns the metadata of mappings as decoded from llpkgstore.json.
+func newTestMetadata(mappings ...VersionMapping) *Metadata {
+	m := &Metadata{Versions: map[CVersion][]GoVersion{}, mappings: []VersionMapping{}}
+	for _, mapping := range mappings {
+		for _, goVersion := range mapping.Go {
+			m.Add(mapping.C, goVersion)
+		}
+	}
+	return m
+}
+
+// TestMetadata_UnmarshalJSON verifies that both forms of versions are decoded, legacy mappings ordered by C version.
+func TestMetadata_UnmarshalJSON(t *testing.T) {
+	expected := MetadataMap{
+		"cjson": newTestMetadata(
+			VersionMapping{C: "1.7.9", Go: []GoVersion{"v1.0.0", "v1.0.1"}},
+			VersionMapping{C: "1.7.18", Go: []GoVersion{"v1.1.0"}},
+		),
+	}
+	for name, data := range map[string]string{
+		"array":  `{"cjson":{"versions":[{"c":"1.7.9","go":["v1.0.0","v1.0.1"]},{"c":"1.7.18","go":["v1.1.0"]}]}}`,
+		"legacy": `{"cjson":{"versions":{"1.7.18":["v1.1.0"],"1.7.9":["v1.0.0","v1.0.1"]}}}`,
+	} {
+		var m MetadataMap
+		if err := json.Unmarshal([]byte(data), &m); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(m, expected) {
+			t.Errorf("%s: unexpected metadata: %v", name, m["cjson"])
+		}
+	}
+
+	var m MetadataMap
+	if err := json.Unmarshal([]byte(`{"cjson":{"versions":"1.7.18"}}`), &m); err == nil {
+		t.Error("Expected error for invalid versions, but got nil")
+	}
+}
+
+// TestMetadata_Versions verifies that metadata set through the Versions map, like before mappings were ordered,
+// is still queried and encoded, after the ordered mappings.
+func TestMetadata_Versions(t *testing.T) {
+	m := &Metadata{Versions: map[CVersion][]GoVersion{"1.7.18": {"v1.1.0"}, "1.7.9": {"v1.0.0"}}}
+	m.Add("1.8.0", "v1.2.0")
+	m.Versions["1.7.9"] = append(m.Versions["1.7.9"], "v1.0.1")
+	if got := m.GoVersions("1.7.9"); !reflect.DeepEqual(got, []GoVersion{"v1.0.0", "v1.0.1"}) {
+		t.Errorf("unexpected Go versions: %v", got)
+	}
+	if got := m.CVersion("v1.1.0"); got != "1.7.18" {
+		t.Errorf("unexpected C version: %s", got)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"versions":[{"c":"1.8.0","go":["v1.2.0"]},{"c":"1.7.9","go":["v1.0.0","v1.0.1"]},{"c":"1.7.18","go":["v1.1.0"]}]}`
+	if string(b) != want {
+		t.Errorf("unexpected encoding:\nwant %s\ngot  %s", want, string(b))
+	}
+}
+
+// TestDetectFormat verifies that a file is legacy if any llpkg is in the legacy form.
+func TestDetectFormat(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want Format
+	}{
+		{`{}`, FormatArray},
+		{`{"cjson":{"versions":[]}}`, FormatArray},
+		{`{"cjson":{"versions":{}}}`, FormatLegacy},
+		{`{"cjson":{"versions":[]},"zlib":{"versions":{"1.3":["v1.0.0"]}}}`, FormatLegacy},
+	} {
+		got, err := DetectFormat([]byte(tc.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.data, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.data, got, tc.want)
+		}
+	}
+	if _, err := DetectFormat([]byte(`{ invalid json `)); err == nil {
+		t.Error("Expected error for invalid JSON, but got nil")
+	}
+}
+
+// TestMetadataMap_Encode verifies both forms and that legacy encoding merges mappings of the same C version.
+func TestMetadataMap_Encode(t *testing.T) {
+	m := MetadataMap{"cjson": &Metadata{}}
+	m["cjson"].Add("1.7.18", "v1.0.0")
+	m["cjson"].Add("1.7.19", "v1.1.0")
+	m["cjson"].Add("1.7.18", "v1.0.1")
+	m["cjson"].mappings = append(m["cjson"].mappings, VersionMapping{C: "1.7.19", Go: []GoVersion{"v1.1.1"}})
+	m["cjson"].Versions["1.7.19"] = append(m["cjson"].Versions["1.7.19"], "v1.1.1")
+
+	for format, want := range map[Format]string{
+		FormatArray:  `{"cjson":{"versions":[{"c":"1.7.18","go":["v1.0.0","v1.0.1"]},{"c":"1.7.19","go":["v1.1.0"]},{"c":"1.7.19","go":["v1.1.1"]}]}}`,
+		FormatLegacy: `{"cjson":{"versions":{"1.7.18":["v1.0.0","v1.0.1"],"1.7.19":["v1.1.0","v1.1.1"]}}}`,
+	} {
+		b, err := m.Encode(format)
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		if string(b) != want {
+			t.Errorf("%v: got %s, want %s", format, b, want)
+		}
+	}
+
+	if got := m["cjson"].GoVersions("1.7.19"); !reflect.DeepEqual(got, []GoVersion{"v1.1.0", "v1.1.1"}) {
+		t.Errorf("unexpected Go versions of 1.7.19: %v", got)
+	}
+	if got := m["cjson"].CVersion("v1.0.1"); got != "1.7.18" {
+		t.Errorf("unexpected C version of v1.0.1: %s", got)
+	}
+	if got := m["zlib"].GoVersions("1.3"); got != nil {
+		t.Errorf("unexpected Go versions of a missing llpkg: %v", got)
+	}
+}
+
+// TestParseFormat verifies that the names of the formats round trip.
+func TestParseFormat(t *testing.T) {
+	for _, f := range []Format{FormatArray, FormatLegacy} {
+		got, err := ParseFormat(f.String())
+		if err != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %v, %v", f, got, err)
+		}
+	}
+	if _, err := ParseFormat("map"); err == nil {
+		t.Error("Expected error for unknown format, but got nil")
+	}
+}
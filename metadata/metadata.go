@@ -3,6 +3,8 @@ package metadata
 import (
 	"errors"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -17,43 +19,94 @@ type GoVersion = string
 // MetadataMap represents llpkgstore.json
 type MetadataMap map[string]*Metadata
 
+// Metadata is the entry of an llpkg in llpkgstore.json.
+// Both forms of its versions are decoded, see Format, and it's encoded in FormatArray.
 type Metadata struct {
+	// Versions maps C versions to the Go versions converted from them, without their order,
+	// which Mappings returns. It's filled by decoding and Add.
 	Versions map[CVersion][]GoVersion `json:"versions"`
+
+	// mappings lists the version mappings in the order they've been released.
+	mappings []VersionMapping
+}
+
+// Manager queries the version mappings of llpkgstore.json, cached locally and refreshed from llpkg.goplus.org
+// following the policy of the cache when a query isn't found in the cache, see CacheOption.
+//
+// A Manager is safe for concurrent use. Queries read an immutable snapshot of the metadata,
+// which an update replaces as a whole, and return copies which the caller may modify.
+type Manager interface {
+	// AllMetadata returns the metadata of every llpkg after updating the cache.
+	AllMetadata() (MetadataMap, error)
+	// MetadataByName returns the metadata of the llpkg name.
+	MetadataByName(name string) (Metadata, error)
+	// ModuleExists reports whether the llpkg name exists.
+	ModuleExists(name string) (bool, error)
+
+	// LatestCVer returns the C version the latest Go version of name is mapped from.
+	LatestCVer(name string) (string, error)
+	// LatestGoVer returns the latest Go version of name.
+	LatestGoVer(name string) (string, error)
+	// LatestGoVerFromCVer returns the latest Go version of name mapped from cVer.
+	LatestGoVerFromCVer(name, cVer string) (string, error)
+	// GoVersFromCVer returns the Go versions of name mapped from cVer.
+	GoVersFromCVer(name, cVer string) ([]string, error)
+	// CVerFromGoVer returns the C version goVer of name is mapped from.
+	CVerFromGoVer(name, goVer string) (string, error)
+	// AllGoVersFromName returns every Go version of name.
+	AllGoVersFromName(name string) ([]string, error)
+	// AllCVersFromName returns every mapped C version of name.
+	AllCVersFromName(name string) ([]string, error)
+
+	// CacheInfo returns the source and age of the metadata the queries read.
+	CacheInfo() CacheInfo
 }
 
+var _ Manager = (*metadataMgr)(nil)
+
 type metadataMgr struct {
+	// mu serializes the updates of cache and snapshot, queries don't take it.
+	mu    sync.Mutex
 	cache *Cache[MetadataMap]
 
+	snapshot atomic.Pointer[snapshot]
+}
+
+// snapshot is an immutable view of the cached metadata with the flat maps built from it.
+type snapshot struct {
+	metadata MetadataMap
+	info     CacheInfo
+
 	// Add flat hash for optimization
 	flatCToGo map[flatKey][]string // "name/cversion" -> []goversion
 	flatGoToC map[flatKey]string   // "name/goversion" -> cversion
 }
 
-// NewMetadataMgr returns a new metadata manager
-func NewMetadataMgr(cacheDir string) (*metadataMgr, error) {
+// NewManager returns a new Manager caching llpkgstore.json in cacheDir with the policy set by opts.
+// The data is verified only if opts trust keys with WithTrustedKeys.
+func NewManager(cacheDir string, opts ...CacheOption) (Manager, error) {
+	return NewMetadataMgr(cacheDir, opts...)
+}
+
+// NewMetadataMgr returns a new metadata manager, see NewManager.
+//
+// Deprecated: use NewManager.
+func NewMetadataMgr(cacheDir string, opts ...CacheOption) (*metadataMgr, error) {
 	cachePath := filepath.Join(cacheDir, cachedMetadataFileName)
-	cache, err := NewCache[MetadataMap](cachePath, remoteMetadataURL)
+	cache, err := NewCache[MetadataMap](cachePath, remoteMetadataURL, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	mgr := &metadataMgr{
-		cache:     cache,
-		flatCToGo: make(map[flatKey][]string),
-		flatGoToC: make(map[flatKey]string),
-	}
-
-	err = mgr.buildFlatVersionMaps()
-	if err != nil {
-		return nil, err
-	}
+	mgr := &metadataMgr{cache: cache}
+	mgr.snapshot.Store(newSnapshot(cache.Data(), cache.Info()))
 
 	return mgr, nil
 }
 
 // Returns all up-to-date metadata
 func (m *metadataMgr) AllMetadata() (MetadataMap, error) {
-	err := m.update()
+	err := m.refresh()
 	if err != nil {
 		return nil, err
 	}
@@ -92,58 +145,83 @@ func (m *metadataMgr) ModuleExists(name string) (bool, error) {
 	return true, nil
 }
 
-// Returns the module metadata in the cache
+// Returns the source and age of the cached metadata
+func (m *metadataMgr) CacheInfo() CacheInfo {
+	return m.snapshot.Load().info
+}
+
+// Returns a copy of the module metadata in the cache
 func (m *metadataMgr) allCachedMetadata() MetadataMap {
-	cache := m.cache.Data()
-	return cache
+	cache := m.snapshot.Load().metadata
+	clone := make(MetadataMap, len(cache))
+	for name, metadata := range cache {
+		clone[name] = metadata.Clone()
+	}
+	return clone
 }
 
-// Returns the module metadata in the cache by name
+// Returns a copy of the module metadata in the cache by name
 func (m *metadataMgr) cachedMetadataByName(name string) (Metadata, error) {
-	allMetadata := m.allCachedMetadata()
-
-	metadata, ok := allMetadata[name]
+	metadata, ok := m.snapshot.Load().metadata[name]
 	if !ok {
 		return Metadata{}, ErrMetadataNotInCache
 	}
 
-	return *metadata, nil
+	return *metadata.Clone(), nil
+}
+
+// refresh refreshes the cache following its policy, i.e. once the data is older than the max age
+// if there's one, and replaces the snapshot.
+// Concurrent queries keep reading the previous snapshot until it's replaced.
+func (m *metadataMgr) refresh() error {
+	return m.replaceSnapshot(m.cache.Refresh)
 }
 
+// update updates the cache to answer a miss, whatever the age of the data, and replaces the snapshot.
+// If the update fails, the data is kept as SourceStale, so that the miss is answered from it.
 func (m *metadataMgr) update() error {
-	err := m.cache.Update()
-	if err != nil {
-		return err
-	}
+	return m.replaceSnapshot(m.cache.updateOrStale)
+}
+
+// replaceSnapshot updates the cache with update and replaces the snapshot.
+func (m *metadataMgr) replaceSnapshot(update func() error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	err = m.buildFlatVersionMaps()
+	err := update()
 	if err != nil {
 		return err
 	}
 
+	m.snapshot.Store(newSnapshot(m.cache.Data(), m.cache.Info()))
 	return nil
 }
 
-func (m *metadataMgr) buildFlatVersionMaps() error {
-	// Reset flat hash
-	m.flatCToGo = make(map[flatKey][]string)
-	m.flatGoToC = make(map[flatKey]string)
-
-	allCachedMetadata := m.allCachedMetadata()
+// newSnapshot builds the flat maps of data.
+// data must not be modified afterwards, Cache.Update replaces it instead.
+func newSnapshot(data MetadataMap, info CacheInfo) *snapshot {
+	s := &snapshot{
+		metadata:  data,
+		info:      info,
+		flatCToGo: make(map[flatKey][]string),
+		flatGoToC: make(map[flatKey]string),
+	}
 
-	for name, metadata := range allCachedMetadata {
-		versions := metadata.Versions
-		for cVersion, goVersions := range versions {
+	for name, metadata := range data {
+		if metadata == nil {
+			continue
+		}
+		for _, mapping := range metadata.Mappings() {
 			// Build flat hash
-			cKey := flatKey{name, cVersion}
-			m.flatCToGo[cKey] = goVersions
+			cKey := flatKey{name, mapping.C}
+			s.flatCToGo[cKey] = append(s.flatCToGo[cKey], mapping.Go...)
 
-			for _, goVersion := range goVersions {
+			for _, goVersion := range mapping.Go {
 				goKey := flatKey{name, goVersion}
-				m.flatGoToC[goKey] = cVersion
+				s.flatGoToC[goKey] = mapping.C
 			}
 		}
 	}
 
-	return nil
+	return s
 }
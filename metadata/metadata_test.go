@@ -3,20 +3,22 @@ package metadata
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var testMetadata = MetadataMap{
-	"example-module": &Metadata{
-		Versions: map[CVersion][]GoVersion{
-			"1.7.18": {"v1.2.0"},
-		},
-	},
+	"example-module": newTestMetadata(
+		VersionMapping{C: "1.7.18", Go: []GoVersion{"v1.2.0"}},
+	),
 }
 
 // TestNewMetadataMgr verifies that the MetadataMgr is successfully created with valid remote data.
@@ -197,7 +199,7 @@ func TestMetadataMgr_ModuleExists(t *testing.T) {
 	}
 }
 
-// TestMetadataMgr_UpdateError verifies Update() returns error for failed remote requests
+// TestMetadataMgr_UpdateError verifies that a miss falls back to the stale data when the update fails
 func TestMetadataMgr_UpdateError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/llpkgstore.json" {
@@ -225,10 +227,68 @@ func TestMetadataMgr_UpdateError(t *testing.T) {
 		t.Fatalf("Failed to create metadata manager: %v", err)
 	}
 
-	err = mgr.update()
-	if err == nil {
+	// the age-based refresh fails without a max age
+	if err := mgr.refresh(); err == nil {
 		t.Fatal("Expected error, but got nil")
 	}
+
+	// a miss is answered from the stale data
+	if _, err := mgr.MetadataByName("nonexistent-module"); !errors.Is(err, ErrMetadataNotInCache) {
+		t.Errorf("Expected ErrMetadataNotInCache, got %v", err)
+	}
+	if mgr.CacheInfo().Source != SourceStale {
+		t.Errorf("unexpected source %v", mgr.CacheInfo().Source)
+	}
+	if _, err := mgr.MetadataByName("example-module"); err != nil {
+		t.Errorf("Failed to get stale metadata: %v", err)
+	}
+}
+
+// TestManager_MissWithinMaxAge verifies that a miss updates the cache even if the data isn't older than the max age.
+func TestManager_MissWithinMaxAge(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		data := MetadataMap{"new-module": newTestMetadata(VersionMapping{C: "1.0.0", Go: []GoVersion{"v1.0.0"}})}
+		for name, metadata := range testMetadata {
+			data[name] = metadata
+		}
+		json.NewEncoder(w).Encode(data)
+	}))
+	defer server.Close()
+
+	originalURL := remoteMetadataURL
+	defer func() { remoteMetadataURL = originalURL }()
+	remoteMetadataURL = server.URL + "/llpkgstore.json"
+
+	testMetadataJSON, err := json.Marshal(testMetadata)
+	if err != nil {
+		t.Fatalf("Failed to marshal test metadata: %v", err)
+	}
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, cachedMetadataFileName), testMetadataJSON, 0644)
+	mgr, err := NewManager(tmpDir, WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+
+	// hits within the max age are answered from the cache file
+	if _, err := mgr.MetadataByName("example-module"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.AllMetadata(); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 0 {
+		t.Fatalf("%d requests sent for fresh hits", requests.Load())
+	}
+
+	if cVer, err := mgr.CVerFromGoVer("new-module", "v1.0.0"); err != nil || cVer != "1.0.0" {
+		t.Errorf("CVerFromGoVer: %q, %v", cVer, err)
+	}
+	if requests.Load() != 1 || mgr.CacheInfo().Source != SourceRemote {
+		t.Errorf("unexpected update: %d requests, source %v", requests.Load(), mgr.CacheInfo().Source)
+	}
 }
 
 // Test invalid remote data scenario
@@ -291,3 +351,163 @@ func TestNewMetadataMgr_BadCacheFile(t *testing.T) {
 		t.Errorf("Metadata mismatch. Expected: %v, Got: %v", testMetadata, data)
 	}
 }
+
+// TestManager_ConcurrentQueries hammers the queries while the metadata is updated, run it with -race.
+// The remote alternates between two versions of the metadata, every query must see one or the other.
+func TestManager_ConcurrentQueries(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := MetadataMap{"cjson": newTestMetadata(
+			VersionMapping{C: "1.7.18", Go: []GoVersion{"v1.2.1", "v1.2.0"}},
+		)}
+		if requests.Add(1)%2 == 0 {
+			data["cjson"].Add("1.7.19", "v1.3.0")
+		}
+		json.NewEncoder(w).Encode(data)
+	}))
+	defer server.Close()
+
+	originalURL := remoteMetadataURL
+	defer func() { remoteMetadataURL = originalURL }()
+	remoteMetadataURL = server.URL + "/llpkgstore.json"
+
+	mgr, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := mgr.AllMetadata(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				latest, err := mgr.LatestGoVerFromCVer("cjson", "1.7.18")
+				if err != nil || latest != "v1.2.1" {
+					errs <- fmt.Errorf("LatestGoVerFromCVer: %q, %v", latest, err)
+					return
+				}
+				goVers, err := mgr.GoVersFromCVer("cjson", "1.7.18")
+				if err != nil || !reflect.DeepEqual(goVers, []string{"v1.2.1", "v1.2.0"}) {
+					errs <- fmt.Errorf("GoVersFromCVer: %v, %v", goVers, err)
+					return
+				}
+				if cVer, err := mgr.CVerFromGoVer("cjson", "v1.2.0"); err != nil || cVer != "1.7.18" {
+					errs <- fmt.Errorf("CVerFromGoVer: %q, %v", cVer, err)
+					return
+				}
+				if _, err := mgr.LatestGoVer("cjson"); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := mgr.AllCVersFromName("cjson"); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestManager_CopyOnWrite verifies that modifying the results of the queries doesn't modify the cached metadata.
+func TestManager_CopyOnWrite(t *testing.T) {
+	mgr, cleanup := setupTestEnv(t, MetadataMap{"cjson": newTestMetadata(
+		VersionMapping{C: "1.7.18", Go: []GoVersion{"v1.2.1", "v1.2.0"}},
+	)})
+	defer cleanup()
+
+	all, err := mgr.AllMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	all["cjson"].mappings[0].Go[0] = "v9.9.9"
+	all["cjson"].Versions["1.7.18"][0] = "v9.9.9"
+	delete(all, "cjson")
+
+	metadata, err := mgr.MetadataByName("cjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata.mappings[0].Go[1] = "v9.9.9"
+
+	goVers, err := mgr.GoVersFromCVer("cjson", "1.7.18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	goVers[0] = "v9.9.9"
+
+	if _, err := mgr.LatestGoVerFromCVer("cjson", "1.7.18"); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err = mgr.MetadataByName("cjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(metadata.mappings[0].Go, []GoVersion{"v1.2.1", "v1.2.0"}) ||
+		!reflect.DeepEqual(metadata.Versions["1.7.18"], []GoVersion{"v1.2.1", "v1.2.0"}) {
+		t.Errorf("cached metadata modified: %v %v", metadata.mappings, metadata.Versions)
+	}
+	if goVers, _ := mgr.GoVersFromCVer("cjson", "1.7.18"); !reflect.DeepEqual(goVers, []string{"v1.2.1", "v1.2.0"}) {
+		t.Errorf("cached Go versions modified: %v", goVers)
+	}
+}
+
+// TestManager_Offline verifies that an offline manager answers misses from the cache file without requests.
+func TestManager_Offline(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		json.NewEncoder(w).Encode(testMetadata)
+	}))
+	defer server.Close()
+
+	originalURL := remoteMetadataURL
+	defer func() { remoteMetadataURL = originalURL }()
+	remoteMetadataURL = server.URL + "/llpkgstore.json"
+
+	tmpDir := t.TempDir()
+	if _, err := NewManager(tmpDir, WithOffline()); !errors.Is(err, ErrCacheFileNotFound) {
+		t.Fatalf("Expected ErrCacheFileNotFound, got %v", err)
+	}
+
+	testMetadataJSON, err := json.Marshal(testMetadata)
+	if err != nil {
+		t.Fatalf("Failed to marshal test metadata: %v", err)
+	}
+	os.WriteFile(filepath.Join(tmpDir, cachedMetadataFileName), testMetadataJSON, 0644)
+	mgr, err := NewManager(tmpDir, WithOffline())
+	if err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+
+	if _, err := mgr.MetadataByName("nonexistent-module"); !errors.Is(err, ErrMetadataNotInCache) {
+		t.Errorf("Expected ErrMetadataNotInCache, got %v", err)
+	}
+	if cVer, err := mgr.CVerFromGoVer("example-module", "v1.2.0"); err != nil || cVer != "1.7.18" {
+		t.Errorf("CVerFromGoVer: %q, %v", cVer, err)
+	}
+	if _, err := mgr.AllMetadata(); err != nil {
+		t.Errorf("AllMetadata failed offline: %v", err)
+	}
+	if mgr.CacheInfo().Source != SourceDisk {
+		t.Errorf("unexpected source %v", mgr.CacheInfo().Source)
+	}
+	if requests.Load() != 0 {
+		t.Errorf("%d requests sent offline", requests.Load())
+	}
+}
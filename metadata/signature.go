@@ -0,0 +1,134 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SignatureSuffix is the suffix of the detached signature of llpkgstore.json, published next to it.
+const SignatureSuffix = ".sig"
+
+var (
+	// ErrUnsigned is returned for data without a signature, see WithUnsignedAllowed.
+	ErrUnsigned = errors.New("metadata is not signed")
+	// ErrBadSignature is returned for data without a valid signature by a trusted key.
+	ErrBadSignature = errors.New("metadata has no valid signature by a trusted key")
+	// ErrRollback is returned for data signed before the data already cached, e.g. an old copy replayed by a mirror.
+	ErrRollback = errors.New("metadata is older than the cached metadata")
+)
+
+// KeyID returns the ID of key in signatures, the hex of the first 8 bytes of its SHA-256.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParsePublicKey parses a base64-encoded ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key %q", s)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// ParsePrivateKey parses a base64-encoded ed25519 private key, either its seed or the full key.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	switch {
+	case err != nil:
+	case len(b) == ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	case len(b) == ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), nil
+	}
+	// never print the key
+	return nil, errors.New("invalid ed25519 private key")
+}
+
+// timestampField is the first field of the line of a signature holding its timestamp.
+const timestampField = "timestamp"
+
+// signedMessage returns the message signed for data at timestamp: the timestamp line followed by data,
+// so that the timestamp can't be changed without invalidating the signatures.
+func signedMessage(data []byte, timestamp string) []byte {
+	return slices.Concat([]byte(timestampField+" "+timestamp+"\n"), data)
+}
+
+// Sign returns the detached signature of data by every key at timestamp,
+// a timestamp line followed by one line per key:
+//
+//	timestamp <RFC 3339 timestamp>
+//	<key ID> <base64 signature>
+//
+// Signing with several keys allows rotating them: clients trusting any of them accept the data.
+// The signed timestamp lets clients reject data older than the data they have, see Verify.
+func Sign(data []byte, timestamp time.Time, keys ...ed25519.PrivateKey) []byte {
+	ts := timestamp.UTC().Format(time.RFC3339)
+	message := signedMessage(data, ts)
+	var sig bytes.Buffer
+	fmt.Fprintf(&sig, "%s %s\n", timestampField, ts)
+	for _, key := range keys {
+		fmt.Fprintf(&sig, "%s %s\n", KeyID(key.Public().(ed25519.PublicKey)),
+			base64.StdEncoding.EncodeToString(ed25519.Sign(key, message)))
+	}
+	return sig.Bytes()
+}
+
+// Verify verifies sig, the detached signature of data returned by Sign, against the trusted keys,
+// and returns its timestamp. It succeeds if any signature by a trusted key is valid,
+// signatures by other keys are ignored.
+// It returns ErrUnsigned if sig has no signature, and ErrBadSignature if none is valid.
+func Verify(data, sig []byte, trusted []ed25519.PublicKey) (time.Time, error) {
+	keys := make(map[string]ed25519.PublicKey, len(trusted))
+	for _, key := range trusted {
+		keys[KeyID(key)] = key
+	}
+	var timestamp string
+	var signatures [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(sig))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+		case len(fields) == 2 && fields[0] == timestampField && timestamp == "":
+			timestamp = fields[1]
+		default:
+			signatures = append(signatures, fields)
+		}
+	}
+	if len(signatures) == 0 {
+		if timestamp != "" {
+			return time.Time{}, ErrBadSignature
+		}
+		return time.Time{}, ErrUnsigned
+	}
+	signedAt, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return time.Time{}, ErrBadSignature
+	}
+	message := signedMessage(data, timestamp)
+	for _, fields := range signatures {
+		if len(fields) != 2 {
+			continue
+		}
+		key, ok := keys[fields[0]]
+		if !ok {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(fields[1])
+		if err == nil && ed25519.Verify(key, message, signature) {
+			return signedAt, nil
+		}
+	}
+	return time.Time{}, ErrBadSignature
+}
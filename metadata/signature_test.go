@@ -0,0 +1,86 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func generateKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public, private
+}
+
+// TestVerify verifies signatures by trusted keys, including while rotating them.
+func TestVerify(t *testing.T) {
+	oldPublic, oldPrivate := generateKey(t)
+	newPublic, newPrivate := generateKey(t)
+	otherPublic, otherPrivate := generateKey(t)
+	data := []byte(`{"cjson":{"versions":[{"c":"1.7.18","go":["v1.2.0"]}]}}`)
+	now := time.Now().Truncate(time.Second)
+	signed := Sign(data, now, oldPrivate)
+
+	for _, tc := range []struct {
+		name    string
+		sig     []byte
+		trusted []ed25519.PublicKey
+		want    error
+	}{
+		{"signed", signed, []ed25519.PublicKey{oldPublic}, nil},
+		{"rotating, old client", Sign(data, now, oldPrivate, newPrivate), []ed25519.PublicKey{oldPublic}, nil},
+		{"rotating, new client", Sign(data, now, oldPrivate, newPrivate), []ed25519.PublicKey{newPublic}, nil},
+		{"rotated", Sign(data, now, newPrivate), []ed25519.PublicKey{oldPublic, newPublic}, nil},
+		{"untrusted key", Sign(data, now, otherPrivate), []ed25519.PublicKey{oldPublic}, ErrBadSignature},
+		{"other data", Sign([]byte(`{}`), now, oldPrivate), []ed25519.PublicKey{oldPublic}, ErrBadSignature},
+		{"forged key ID", []byte("timestamp " + now.UTC().Format(time.RFC3339) + "\n" + KeyID(oldPublic) + " " +
+			base64.StdEncoding.EncodeToString(ed25519.Sign(otherPrivate, data))),
+			[]ed25519.PublicKey{oldPublic, otherPublic}, ErrBadSignature},
+		{"changed timestamp", bytes.Replace(signed, []byte(now.UTC().Format(time.RFC3339)),
+			[]byte(now.Add(time.Hour).UTC().Format(time.RFC3339)), 1), []ed25519.PublicKey{oldPublic}, ErrBadSignature},
+		{"no timestamp", bytes.SplitN(signed, []byte("\n"), 2)[1], []ed25519.PublicKey{oldPublic}, ErrBadSignature},
+		{"no signature", Sign(data, now), []ed25519.PublicKey{oldPublic}, ErrBadSignature},
+		{"garbage", []byte("garbage\n"), []ed25519.PublicKey{oldPublic}, ErrBadSignature},
+		{"unsigned", nil, []ed25519.PublicKey{oldPublic}, ErrUnsigned},
+		{"empty", []byte("\n"), []ed25519.PublicKey{oldPublic}, ErrUnsigned},
+	} {
+		signedAt, err := Verify(data, tc.sig, tc.trusted)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+		if err == nil && !signedAt.Equal(now) {
+			t.Errorf("%s: signed at %v, want %v", tc.name, signedAt, now)
+		}
+	}
+}
+
+// TestParseKeys verifies that keys round trip through base64, private keys as seeds or full keys.
+func TestParseKeys(t *testing.T) {
+	public, private := generateKey(t)
+
+	parsed, err := ParsePublicKey(base64.StdEncoding.EncodeToString(public))
+	if err != nil || !parsed.Equal(public) {
+		t.Errorf("ParsePublicKey: %v, %v", parsed, err)
+	}
+	for _, encoded := range []string{
+		base64.StdEncoding.EncodeToString(private.Seed()),
+		base64.StdEncoding.EncodeToString(private) + "\n",
+	} {
+		parsed, err := ParsePrivateKey(encoded)
+		if err != nil || !parsed.Equal(private) {
+			t.Errorf("ParsePrivateKey: %v", err)
+		}
+	}
+	if _, err := ParsePublicKey("c2hvcnQ="); err == nil {
+		t.Error("Expected error for a short public key, but got nil")
+	}
+	if _, err := ParsePrivateKey("not base64"); err == nil {
+		t.Error("Expected error for an invalid private key, but got nil")
+	}
+}
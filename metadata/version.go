@@ -3,6 +3,7 @@ package metadata
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"golang.org/x/mod/semver"
 )
@@ -45,7 +46,7 @@ func (m *metadataMgr) LatestGoVerFromCVer(name, cVer string) (string, error) {
 	cKey := flatKey{name, cVer}
 
 	// Search for the latest Go version
-	goVersions, ok := m.flatCToGo[cKey]
+	goVersions, ok := m.snapshot.Load().flatCToGo[cKey]
 	if !ok {
 		// Try to update if not found
 		err := m.update()
@@ -54,13 +55,15 @@ func (m *metadataMgr) LatestGoVerFromCVer(name, cVer string) (string, error) {
 		}
 
 		// Try again
-		goVersions, ok = m.flatCToGo[cKey]
+		goVersions, ok = m.snapshot.Load().flatCToGo[cKey]
 		if !ok {
 			return "", fmt.Errorf("no version mappings for %s %s", name, cVer)
 		}
 	}
 
 	if len(goVersions) > 0 {
+		// Sort a copy, the snapshot is shared by concurrent queries
+		goVersions = slices.Clone(goVersions)
 		semver.Sort(goVersions)
 		latestGoVersion := goVersions[len(goVersions)-1]
 
@@ -76,7 +79,7 @@ func (m *metadataMgr) GoVersFromCVer(name, cVer string) ([]string, error) {
 	cKey := flatKey{name, cVer}
 
 	// Search for the Go versions
-	versions, ok := m.flatCToGo[cKey]
+	versions, ok := m.snapshot.Load().flatCToGo[cKey]
 	if !ok {
 		// Try to update if not found
 		err := m.update()
@@ -85,7 +88,7 @@ func (m *metadataMgr) GoVersFromCVer(name, cVer string) ([]string, error) {
 		}
 
 		// Try again
-		versions, ok = m.flatCToGo[cKey]
+		versions, ok = m.snapshot.Load().flatCToGo[cKey]
 		if !ok {
 			return nil, fmt.Errorf("no version mappings for %s %s", name, cVer)
 		}
@@ -104,7 +107,7 @@ func (m *metadataMgr) CVerFromGoVer(name, goVer string) (string, error) {
 	goKey := flatKey{name, goVer}
 
 	// Search for the C version in the cached flat hash
-	cVersion, ok := m.flatGoToC[goKey]
+	cVersion, ok := m.snapshot.Load().flatGoToC[goKey]
 	if !ok {
 		// Update if not found
 		err := m.update()
@@ -113,7 +116,7 @@ func (m *metadataMgr) CVerFromGoVer(name, goVer string) (string, error) {
 		}
 
 		// Try again
-		cVersion, ok = m.flatGoToC[goKey]
+		cVersion, ok = m.snapshot.Load().flatGoToC[goKey]
 		if !ok {
 			return "", fmt.Errorf("no C version found for %s %s", name, goVer)
 		}
@@ -134,8 +137,9 @@ func (m *metadataMgr) AllGoVersFromName(name string) ([]string, error) {
 		}
 
 		// Extract Go versions
-		goVersions := make([]string, 0, len(m.flatGoToC))
-		for goVersionKey, _ := range m.flatGoToC {
+		flatGoToC := m.snapshot.Load().flatGoToC
+		goVersions := make([]string, 0, len(flatGoToC))
+		for goVersionKey := range flatGoToC {
 			if goVersionKey.name == name {
 				goVersions = append(goVersions, goVersionKey.version)
 			}
@@ -173,8 +177,9 @@ func (m *metadataMgr) AllCVersFromName(name string) ([]string, error) {
 		}
 
 		// Extract C versions
-		cVersions := make([]string, 0, len(m.flatCToGo))
-		for cVersionKey, _ := range m.flatCToGo {
+		flatCToGo := m.snapshot.Load().flatCToGo
+		cVersions := make([]string, 0, len(flatCToGo))
+		for cVersionKey := range flatCToGo {
 			if cVersionKey.name == name {
 				cVersions = append(cVersions, cVersionKey.version)
 			}
@@ -11,14 +11,12 @@ import (
 
 // Define more enriched test data
 var enhancedTestVersionData = MetadataMap{
-	"test-module": &Metadata{
-		Versions: map[CVersion][]GoVersion{
-			"1.7.18": {"v1.2.0", "v1.2.1"},
-			"1.7.19": {"v1.3.0"},
-			"1.8.0":  {"v1.4.0", "v1.4.1"},
-		},
-	},
-	"empty-module": &Metadata{Versions: map[CVersion][]GoVersion{}},
+	"test-module": newTestMetadata(
+		VersionMapping{C: "1.7.18", Go: []GoVersion{"v1.2.0", "v1.2.1"}},
+		VersionMapping{C: "1.7.19", Go: []GoVersion{"v1.3.0"}},
+		VersionMapping{C: "1.8.0", Go: []GoVersion{"v1.4.0", "v1.4.1"}},
+	),
+	"empty-module": newTestMetadata(),
 }
 
 // Set up test environment
@@ -0,0 +1,459 @@
+// Package cache implements a content-addressed cache of installed upstream packages,
+// so that the same package isn't reinstalled by every generate, verification and release run.
+//
+// An install is addressed by the SHA-256 of its Key. The cache root holds, for every entry:
+//
+//	{hash}/entry.json  the Entry, i.e. the key, the install result and the checksum of every file
+//	{hash}/files/      the installed files
+//	{hash}.lock        the lock held while the entry is populated, restored or removed
+//
+// Entries are populated in a {hash}.tmp-* directory which is renamed once complete,
+// so an entry is either complete or absent.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goplus/llpkgstore/internal/actions/file"
+	"github.com/goplus/llpkgstore/internal/actions/hashutils"
+	"github.com/goplus/llpkgstore/internal/filelock"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// ErrCorrupt is returned by Cache.Verify for entries whose files don't match their checksums.
+var ErrCorrupt = errors.New("corrupt cache entry")
+
+const (
+	entryFile  = "entry.json"
+	filesDir   = "files"
+	lockSuffix = ".lock"
+	tempInfix  = ".tmp-"
+)
+
+// Volatile is implemented by installers whose installs depend on more than their Key,
+// e.g. the local installer for a prefix directory, which can change at any time.
+// Installs are never cached when Volatile returns true.
+type Volatile interface {
+	Volatile() bool
+}
+
+// Fingerprinter is implemented by installers whose installs depend on files outside of their config,
+// e.g. the profiles of the conan installer. The fingerprint changes with those files and is part of the Key.
+type Fingerprinter interface {
+	Fingerprint() (string, error)
+}
+
+// Key identifies an install: the same key always installs the same files.
+type Key struct {
+	Installer string            `json:"installer"`
+	Config    map[string]string `json:"config,omitempty"`
+	Package   upstream.Package  `json:"package"`
+	// Platform is GOOS/GOARCH of the target platform.
+	Platform string `json:"platform"`
+	// Lockfile is the SHA-256 of the lockfile the package is resolved from, if any.
+	Lockfile string `json:"lockfile,omitempty"`
+	// Fingerprint is the fingerprint of the installer, if it's a Fingerprinter.
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// NewKey returns the key of installing u.Pkg for its target platform, resolved from lockfile if it isn't empty.
+func NewKey(u *upstream.Upstream, lockfile string) (Key, error) {
+	key := Key{
+		Installer: u.Installer.Name(),
+		Config:    u.Installer.Config(),
+		Package:   u.Pkg,
+		Platform:  u.TargetPlatform().String(),
+	}
+	if key.Package.Linkage == "" {
+		key.Package.Linkage = upstream.LinkageShared
+	}
+	if lockfile != "" {
+		sum, err := hashutils.File(lockfile)
+		if err != nil {
+			return Key{}, err
+		}
+		key.Lockfile = hex.EncodeToString(sum)
+	}
+	if f, ok := u.Installer.(Fingerprinter); ok {
+		fingerprint, err := f.Fingerprint()
+		if err != nil {
+			return Key{}, err
+		}
+		key.Fingerprint = fingerprint
+	}
+	return key, nil
+}
+
+// Hash returns the hex-encoded SHA-256 of the key, which addresses its entry.
+func (k Key) Hash() string {
+	// maps are marshalled with sorted keys, so the encoding is canonical.
+	b, _ := json.Marshal(k)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Entry is a cached install.
+type Entry struct {
+	Key Key `json:"key"`
+	// Prefix is the directory the package was installed in.
+	// It's replaced by the output directory in the paths of Result and in .pc files when restored.
+	Prefix string                  `json:"prefix"`
+	Result *upstream.InstallResult `json:"result"`
+	// Files maps the slash-separated path of every installed file to its hex-encoded SHA-256.
+	Files map[string]string `json:"files"`
+	// Size is the total size of the files in bytes.
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+
+	// Hash is the hash of Key, the name of the entry directory.
+	Hash string `json:"-"`
+	// Dir is the entry directory.
+	Dir string `json:"-"`
+	// LastUsed is when the entry was last populated or restored.
+	LastUsed time.Time `json:"-"`
+	// Err is set by Cache.List if entry.json can't be read, such an entry can't be used.
+	Err error `json:"-"`
+}
+
+// Cache is an install cache rooted at a directory.
+// A nil *Cache is valid and disables caching.
+type Cache struct {
+	Root string
+}
+
+// New returns the cache rooted at root.
+func New(root string) *Cache {
+	return &Cache{Root: root}
+}
+
+// DefaultRoot returns {LLGOCACHE}/llpkgstore/install, LLGOCACHE defaulting to {UserCacheDir}/llgo
+// like llgo does, see docs/llpkgstore.md.
+func DefaultRoot() (string, error) {
+	llgoCache := os.Getenv("LLGOCACHE")
+	if llgoCache == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		llgoCache = filepath.Join(userCache, "llgo")
+	}
+	return filepath.Join(llgoCache, "llpkgstore", "install"), nil
+}
+
+// lock locks the entry hash.
+func (c *Cache) lock(ctx context.Context, hash string) (*filelock.Lock, error) {
+	if err := os.MkdirAll(c.Root, 0777); err != nil {
+		return nil, err
+	}
+	return filelock.Acquire(ctx, filepath.Join(c.Root, hash+lockSuffix))
+}
+
+// Install installs u.Pkg into outputDir like u.Install, resolved from lockfile if it isn't empty,
+// restoring it from the cache if it has been installed before.
+//
+// On a miss, the package is installed into a new entry first, then restored from it.
+// Concurrent installs of the same key, even from other processes, install it once.
+// Restored files aren't verified, see Verify.
+func (c *Cache) Install(ctx context.Context, u *upstream.Upstream, lockfile, outputDir string) (*upstream.InstallResult, error) {
+	if v, ok := u.Installer.(Volatile); c == nil || ok && v.Volatile() {
+		return u.Install(ctx, outputDir)
+	}
+	key, err := NewKey(u, lockfile)
+	if err != nil {
+		return nil, err
+	}
+	hash := key.Hash()
+	l, err := c.lock(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Release()
+
+	entry, err := c.read(hash)
+	if err == nil {
+		return entry.restore(outputDir)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		// an unreadable entry is replaced.
+		if err := os.RemoveAll(filepath.Join(c.Root, hash)); err != nil {
+			return nil, err
+		}
+	}
+	return c.populate(ctx, u, key, outputDir)
+}
+
+// populate installs u.Pkg into the new entry of key and restores it into outputDir.
+// Installs by a fallback candidate are restored without being cached,
+// the key identifies the whole fallback chain and their lockfile doesn't pin them.
+func (c *Cache) populate(ctx context.Context, u *upstream.Upstream, key Key, outputDir string) (*upstream.InstallResult, error) {
+	hash := key.Hash()
+	temp, err := os.MkdirTemp(c.Root, hash+tempInfix)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(temp)
+
+	prefix := filepath.Join(temp, filesDir)
+	if err := os.Mkdir(prefix, 0777); err != nil {
+		return nil, err
+	}
+	result, err := u.Install(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{
+		Key:     key,
+		Prefix:  prefix,
+		Result:  result,
+		Created: time.Now(),
+	}
+	entry.Files, entry.Size, err = checksums(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if result.Fallback {
+		entry.Dir = temp
+		return entry.restore(outputDir)
+	}
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(temp, entryFile), b, 0644); err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(c.Root, hash)
+	if err := os.Rename(temp, dir); err != nil {
+		return nil, err
+	}
+	entry.Hash, entry.Dir, entry.LastUsed = hash, dir, entry.Created
+	return entry.restore(outputDir)
+}
+
+// checksums returns the SHA-256 of every file under dir and their total size.
+func checksums(dir string) (map[string]string, int64, error) {
+	files := map[string]string{}
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		// follow symlinks like file.CopyFS does.
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		sum, err := hashutils.File(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = hex.EncodeToString(sum)
+		size += info.Size()
+		return nil
+	})
+	return files, size, err
+}
+
+// read reads the entry hash, it returns an error wrapping fs.ErrNotExist if there's none.
+func (c *Cache) read(hash string) (*Entry, error) {
+	dir := filepath.Join(c.Root, hash)
+	path := filepath.Join(dir, entryFile)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if _, statErr := os.Stat(dir); errors.Is(err, fs.ErrNotExist) && statErr == nil {
+			// never happens for entries renamed in place, the directory was modified.
+			return nil, fmt.Errorf("%s: %w", dir, ErrCorrupt)
+		}
+		return nil, err
+	}
+	entry := &Entry{Hash: hash, Dir: dir}
+	if err := json.Unmarshal(b, entry); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if entry.Result == nil {
+		return nil, fmt.Errorf("%s: %w: missing install result", path, ErrCorrupt)
+	}
+	if info, err := os.Stat(path); err == nil {
+		entry.LastUsed = info.ModTime()
+	}
+	return entry, nil
+}
+
+// restore copies the files of e into outputDir and returns the install result relative to it.
+func (e *Entry) restore(outputDir string) (*upstream.InstallResult, error) {
+	outputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	files := filepath.Join(e.Dir, filesDir)
+	if err := file.CopyFS(outputDir, os.DirFS(files), false); err != nil {
+		return nil, err
+	}
+	// .pc files installed by llpkgstore have an absolute prefix.
+	for name := range e.Files {
+		if filepath.Ext(name) != ".pc" {
+			continue
+		}
+		path := filepath.Join(outputDir, filepath.FromSlash(name))
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, bytes.ReplaceAll(b, []byte(e.Prefix), []byte(outputDir)), 0644); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	os.Chtimes(filepath.Join(e.Dir, entryFile), now, now)
+	e.LastUsed = now
+
+	result := *e.Result
+	rebase := func(paths []string) []string {
+		if paths == nil {
+			return nil
+		}
+		ret := make([]string, len(paths))
+		for i, path := range paths {
+			ret[i] = path
+			if rel, err := filepath.Rel(e.Prefix, path); err == nil && filepath.IsLocal(rel) {
+				ret[i] = filepath.Join(outputDir, rel)
+			}
+		}
+		return ret
+	}
+	result.PCFiles = rebase(result.PCFiles)
+	result.IncludeDirs = rebase(result.IncludeDirs)
+	result.LibDirs = rebase(result.LibDirs)
+	result.BinDirs = rebase(result.BinDirs)
+	result.Licenses = rebase(result.Licenses)
+	result.GeneratedFiles = rebase(result.GeneratedFiles)
+	return &result, nil
+}
+
+// List returns the entries of the cache sorted by hash.
+// Entries whose entry.json can't be read are returned with Err set.
+func (c *Cache) List() ([]*Entry, error) {
+	dirents, err := os.ReadDir(c.Root)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []*Entry
+	for _, dirent := range dirents {
+		hash := dirent.Name()
+		if !dirent.IsDir() || strings.Contains(hash, tempInfix) {
+			continue
+		}
+		entry, err := c.read(hash)
+		if err != nil {
+			entry = &Entry{Hash: hash, Dir: filepath.Join(c.Root, hash), Err: err}
+		}
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b *Entry) int {
+		return strings.Compare(a.Hash, b.Hash)
+	})
+	return entries, nil
+}
+
+// Remove removes the entry hash, waiting for the installs using it.
+func (c *Cache) Remove(ctx context.Context, hash string) error {
+	l, err := c.lock(ctx, hash)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	return os.RemoveAll(filepath.Join(c.Root, hash))
+}
+
+// Prune removes the entries last used before t, the entries which can't be read,
+// and the leftovers of interrupted installs. It returns the removed entries.
+func (c *Cache) Prune(ctx context.Context, t time.Time) ([]*Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []*Entry
+	var errs []error
+	for _, entry := range entries {
+		if entry.Err == nil && !entry.LastUsed.Before(t) {
+			continue
+		}
+		if err := c.Remove(ctx, entry.Hash); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, entry)
+	}
+
+	temps, _ := filepath.Glob(filepath.Join(c.Root, "*"+tempInfix+"*"))
+	for _, temp := range temps {
+		hash, _, _ := strings.Cut(filepath.Base(temp), tempInfix)
+		// the temporary directory of a running install is removed once it's done.
+		l, err := c.lock(ctx, hash)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.RemoveAll(temp); err != nil {
+			errs = append(errs, err)
+		}
+		l.Release()
+	}
+	return removed, errors.Join(errs...)
+}
+
+// Verify checks the files of the entry against their checksums.
+// It returns an error wrapping ErrCorrupt listing the missing, modified and unexpected files.
+func (c *Cache) Verify(ctx context.Context, entry *Entry) error {
+	if entry.Err != nil {
+		return fmt.Errorf("%s: %w: %v", entry.Hash, ErrCorrupt, entry.Err)
+	}
+	l, err := c.lock(ctx, entry.Hash)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	files, _, err := checksums(filepath.Join(entry.Dir, filesDir))
+	if err != nil {
+		return fmt.Errorf("%s: %w: %v", entry.Hash, ErrCorrupt, err)
+	}
+	var problems []string
+	for name, sum := range entry.Files {
+		switch actual, ok := files[name]; {
+		case !ok:
+			problems = append(problems, "missing "+name)
+		case actual != sum:
+			problems = append(problems, "modified "+name)
+		}
+	}
+	for name := range files {
+		if _, ok := entry.Files[name]; !ok {
+			problems = append(problems, "unexpected "+name)
+		}
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return fmt.Errorf("%s: %w: %s", entry.Hash, ErrCorrupt, strings.Join(problems, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// countingInstaller installs a library and a .pc file with an absolute prefix, counting its installs.
+type countingInstaller struct {
+	mu       sync.Mutex
+	installs int
+	volatile bool
+}
+
+func (c *countingInstaller) Name() string              { return "counting" }
+func (c *countingInstaller) Config() map[string]string { return map[string]string{"options": "a=b"} }
+func (c *countingInstaller) Volatile() bool            { return c.volatile }
+
+func (c *countingInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	c.mu.Lock()
+	c.installs++
+	c.mu.Unlock()
+	os.MkdirAll(filepath.Join(outputDir, "lib"), 0777)
+	os.WriteFile(filepath.Join(outputDir, "lib", "libfoo.a"), []byte("archive"), 0644)
+	os.WriteFile(filepath.Join(outputDir, "foo.pc"), []byte("prefix="+outputDir+"\nLibs: -L${prefix}/lib -lfoo\n"), 0644)
+	return upstream.ScanInstallResult(pkg, "foo", outputDir)
+}
+
+func (c *countingInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	return nil, nil
+}
+
+func TestKey(t *testing.T) {
+	u := &upstream.Upstream{Installer: &countingInstaller{}, Pkg: upstream.Package{Name: "foo", Version: "1.0.0"}}
+	shared, err := NewKey(u, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Pkg.Linkage = upstream.LinkageShared
+	explicit, _ := NewKey(u, "")
+	if shared.Hash() != explicit.Hash() {
+		t.Error("the default linkage should be shared")
+	}
+	u.Pkg.Linkage = upstream.LinkageStatic
+	static, _ := NewKey(u, "")
+	if static.Hash() == shared.Hash() {
+		t.Error("the linkage should change the key")
+	}
+
+	lockfile := filepath.Join(t.TempDir(), "conan.lock")
+	os.WriteFile(lockfile, []byte("lock"), 0644)
+	locked, err := NewKey(u, lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked.Lockfile == "" || locked.Hash() == static.Hash() {
+		t.Error("the lockfile should change the key")
+	}
+
+	profile := &fingerprintInstaller{fingerprint: "a"}
+	u.Installer = profile
+	a, err := NewKey(u, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile.fingerprint = "b"
+	if b, _ := NewKey(u, ""); a.Fingerprint != "a" || b.Hash() == a.Hash() {
+		t.Error("the fingerprint should change the key")
+	}
+	profile.err = errors.New("no profile")
+	if _, err := NewKey(u, ""); !errors.Is(err, profile.err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// fingerprintInstaller is a countingInstaller depending on files outside of its config.
+type fingerprintInstaller struct {
+	countingInstaller
+	fingerprint string
+	err         error
+}
+
+func (f *fingerprintInstaller) Fingerprint() (string, error) { return f.fingerprint, f.err }
+
+func TestInstall(t *testing.T) {
+	c := New(t.TempDir())
+	installer := &countingInstaller{}
+	u := &upstream.Upstream{Installer: installer, Pkg: upstream.Package{Name: "foo", Version: "1.0.0"}}
+
+	var wg sync.WaitGroup
+	outputs := make([]string, 4)
+	errs := make([]error, len(outputs))
+	for i := range outputs {
+		outputs[i] = t.TempDir()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = c.Install(context.Background(), u, "", outputs[i])
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if installer.installs != 1 {
+		t.Errorf("expected one install, got %d", installer.installs)
+	}
+
+	output := t.TempDir()
+	result, err := c.Install(context.Background(), u, "", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if installer.installs != 1 {
+		t.Errorf("expected a cache hit, got %d installs", installer.installs)
+	}
+	if result.PCFile() != filepath.Join(output, "foo.pc") || len(result.LibDirs) != 1 || result.LibDirs[0] != filepath.Join(output, "lib") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	pc, _ := os.ReadFile(filepath.Join(output, "foo.pc"))
+	if !strings.HasPrefix(string(pc), "prefix="+output+"\n") {
+		t.Errorf("prefix not rewritten: %s", pc)
+	}
+	if _, err := os.Stat(filepath.Join(output, "lib", "libfoo.a")); err != nil {
+		t.Error(err)
+	}
+
+	// another linkage is another entry
+	u.Pkg.Linkage = upstream.LinkageStatic
+	if _, err := c.Install(context.Background(), u, "", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || installer.installs != 2 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	for _, entry := range entries {
+		if entry.Err != nil || entry.Size == 0 || entry.Files["lib/libfoo.a"] == "" {
+			t.Errorf("unexpected entry: %+v", entry)
+		}
+	}
+}
+
+func TestInstallVolatile(t *testing.T) {
+	c := New(t.TempDir())
+	installer := &countingInstaller{volatile: true}
+	u := &upstream.Upstream{Installer: installer, Pkg: upstream.Package{Name: "foo", Version: "1.0.0"}}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Install(context.Background(), u, "", t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if entries, _ := c.List(); installer.installs != 2 || len(entries) != 0 {
+		t.Errorf("volatile installs shouldn't be cached: %d installs, %d entries", installer.installs, len(entries))
+	}
+
+	// a nil cache disables caching
+	var nilCache *Cache
+	installer.volatile = false
+	if _, err := nilCache.Install(context.Background(), u, "", t.TempDir()); err != nil || installer.installs != 3 {
+		t.Errorf("unexpected nil cache install: %v", err)
+	}
+}
+
+// failingInstaller always fails.
+type failingInstaller struct{}
+
+func (failingInstaller) Name() string              { return "failing" }
+func (failingInstaller) Config() map[string]string { return nil }
+func (failingInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	return nil, errors.New("remote is down")
+}
+func (failingInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	return nil, nil
+}
+
+func TestInstallFallback(t *testing.T) {
+	c := New(t.TempDir())
+	installer := &countingInstaller{}
+	u := &upstream.Upstream{
+		Installer: upstream.NewFallback(upstream.Candidate{Installer: failingInstaller{}}, upstream.Candidate{Installer: installer}),
+		Pkg:       upstream.Package{Name: "foo", Version: "1.0.0"},
+	}
+	for i := 0; i < 2; i++ {
+		outputDir := t.TempDir()
+		result, err := c.Install(context.Background(), u, "", outputDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := os.ReadFile(filepath.Join(outputDir, "foo.pc"))
+		if !result.Fallback || !strings.HasPrefix(string(b), "prefix="+outputDir+"\n") {
+			t.Errorf("unexpected install: %+v %s", result, b)
+		}
+	}
+	// the key identifies the whole chain, not the fallback candidate which installed the package.
+	if entries, _ := c.List(); installer.installs != 2 || len(entries) != 0 {
+		t.Errorf("fallback installs shouldn't be cached: %d installs, %d entries", installer.installs, len(entries))
+	}
+}
+
+func TestVerifyAndPrune(t *testing.T) {
+	c := New(t.TempDir())
+	u := &upstream.Upstream{Installer: &countingInstaller{}, Pkg: upstream.Package{Name: "foo", Version: "1.0.0"}}
+	if _, err := c.Install(context.Background(), u, "", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := c.List()
+	if len(entries) != 1 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	entry := entries[0]
+	if err := c.Verify(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(entry.Dir, filesDir, "lib", "libfoo.a"), []byte("tampered"), 0644)
+	os.WriteFile(filepath.Join(entry.Dir, filesDir, "extra"), nil, 0644)
+	os.Remove(filepath.Join(entry.Dir, filesDir, "foo.pc"))
+	err := c.Verify(context.Background(), entry)
+	if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), "missing foo.pc, modified lib/libfoo.a, unexpected extra") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// leftovers of an interrupted install and broken entries are always pruned
+	os.Mkdir(filepath.Join(c.Root, entry.Hash+tempInfix+"123"), 0777)
+	os.Mkdir(filepath.Join(c.Root, "broken"), 0777)
+	removed, err := c.Prune(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Hash != "broken" {
+		t.Errorf("unexpected removed entries: %+v", removed)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(c.Root, "*"+tempInfix+"*")); len(matches) != 0 {
+		t.Errorf("leftovers not pruned: %v", matches)
+	}
+
+	removed, err = c.Prune(context.Background(), time.Now().Add(time.Second))
+	if err != nil || len(removed) != 1 || removed[0].Hash != entry.Hash {
+		t.Errorf("unexpected removed entries: %+v %v", removed, err)
+	}
+	if entries, _ := c.List(); len(entries) != 0 {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
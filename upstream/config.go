@@ -0,0 +1,25 @@
+package upstream
+
+// ConfigKey describes a key of the config of an installer.
+type ConfigKey struct {
+	Name        string
+	Description string
+	Required    bool
+	// Path reports whether the value is a file path,
+	// config.ParseLLPkgConfig resolves a relative one against the directory of llpkg.cfg.
+	Path bool
+}
+
+// ConfigDescriber is implemented by installers which know the keys of their config,
+// so that llpkg.cfg can be checked for unknown and missing keys before use.
+type ConfigDescriber interface {
+	// ConfigKeys returns the supported config keys.
+	ConfigKeys() []ConfigKey
+}
+
+// ConfigValidator is implemented by installers which can check the values of their config
+// without installing anything.
+type ConfigValidator interface {
+	// ValidateConfig returns an error if the config can't be used to install pkg.
+	ValidateConfig(pkg Package) error
+}
@@ -0,0 +1,57 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Dependency is a node of the dependency tree of a package.
+type Dependency struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Revision string `json:"revision,omitempty"`
+	// Options are the options the package is resolved with, e.g. conan's shared=True.
+	Options map[string]string `json:"options,omitempty"`
+	// PkgConfigName is the name of the .pc file of the package.
+	PkgConfigName string `json:"pkgConfigName,omitempty"`
+	// Dependencies are the direct dependencies of the package.
+	Dependencies []*Dependency `json:"dependencies,omitempty"`
+}
+
+// Walk calls fn for d and all of its transitive dependencies in depth-first order.
+// depth is 0 for d itself. Walk stops and returns the error if fn returns one.
+func (d *Dependency) Walk(fn func(dep *Dependency, depth int) error) error {
+	var walk func(dep *Dependency, depth int) error
+	walk = func(dep *Dependency, depth int) error {
+		if err := fn(dep, depth); err != nil {
+			return err
+		}
+		for _, child := range dep.Dependencies {
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(d, 0)
+}
+
+// DependencyResolver is implemented by installers which can resolve the dependency tree
+// of a package without installing it.
+type DependencyResolver interface {
+	// Dependencies returns the dependency tree of pkg, whose root is pkg itself.
+	Dependencies(ctx context.Context, pkg Package) (*Dependency, error)
+}
+
+// Dependencies resolves the dependency tree of u.Pkg with the search deadline applied.
+// It returns an error wrapping errors.ErrUnsupported if the installer isn't a DependencyResolver.
+func (u *Upstream) Dependencies(ctx context.Context) (*Dependency, error) {
+	resolver, ok := u.Installer.(DependencyResolver)
+	if !ok {
+		return nil, fmt.Errorf("%s: resolving dependencies: %w", u.Installer.Name(), errors.ErrUnsupported)
+	}
+	ctx, cancel := withTimeout(ctx, u.SearchTimeout, DefaultSearchTimeout)
+	defer cancel()
+	return resolver.Dependencies(ctx, u.Pkg)
+}
@@ -0,0 +1,286 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// FallbackName is the name of the installers returned by NewFallback.
+const FallbackName = "fallback"
+
+// Candidate is an installer tried by a Fallback.
+type Candidate struct {
+	Installer Installer
+	// Package is the name of the package in the upstream of Installer,
+	// empty meaning the name of the package being installed.
+	Package string
+}
+
+// pkg returns pkg as named in the upstream of c.
+func (c Candidate) pkg(pkg Package) Package {
+	if c.Package != "" {
+		pkg.Name = c.Package
+	}
+	return pkg
+}
+
+// Fallback is an Installer trying its candidates in order until one succeeds,
+// e.g. ConanCenter first and a local mirror second.
+//
+// The InstallResult records the candidate which installed the package in its Installer field,
+// and whether it's another one than the first in its Fallback field.
+// A lockfile pins the first candidate only, see Locker and DisableFallback.
+type Fallback struct {
+	candidates []Candidate
+	// lockfile is the lockfile in use, see UseLockfile.
+	lockfile string
+	// noFallback makes Install fail rather than fall back while a lockfile is in use.
+	noFallback bool
+}
+
+// NewFallback returns a Fallback trying candidates in order.
+func NewFallback(candidates ...Candidate) *Fallback {
+	return &Fallback{candidates: candidates}
+}
+
+// Candidates returns the candidates of f in order.
+func (f *Fallback) Candidates() []Candidate {
+	return slices.Clone(f.candidates)
+}
+
+func (f *Fallback) Name() string { return FallbackName }
+
+// Config returns the config of every candidate prefixed by its index,
+// e.g. 0.installer, 0.package and 0.config.options, so that it identifies the whole chain.
+func (f *Fallback) Config() map[string]string {
+	config := map[string]string{}
+	for i, c := range f.candidates {
+		prefix := strconv.Itoa(i) + "."
+		config[prefix+"installer"] = c.Installer.Name()
+		if c.Package != "" {
+			config[prefix+"package"] = c.Package
+		}
+		for key, value := range c.Installer.Config() {
+			config[prefix+"config."+key] = value
+		}
+	}
+	return config
+}
+
+// DisableFallback makes Install fail rather than fall back from the first candidate while a lockfile is in use,
+// e.g. for a PR check, which verifies what the lockfile pins.
+func (f *Fallback) DisableFallback() {
+	f.noFallback = true
+}
+
+// Install installs pkg with the first candidate which succeeds.
+// The files a failed candidate left in outputDir are removed before the next one is tried.
+// If every candidate fails, the errors of all of them are returned joined.
+func (f *Fallback) Install(ctx context.Context, pkg Package, outputDir string) (*InstallResult, error) {
+	var errs []error
+	for i, c := range f.candidates {
+		if i > 0 && f.noFallback && f.lockfile != "" {
+			return nil, fmt.Errorf("%w, not falling back as the lockfile %s pins it", errs[0], f.lockfile)
+		}
+		existing, err := dirEntries(outputDir)
+		if err != nil {
+			return nil, err
+		}
+		result, err := c.Installer.Install(ctx, c.pkg(pkg), outputDir)
+		if err == nil {
+			if result.Installer == "" {
+				result.Installer = c.Installer.Name()
+			}
+			result.Fallback = i > 0
+			return result, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.Installer.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+		if err := removeNewEntries(outputDir, existing); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("all upstreams failed: %w", errors.Join(errs...))
+}
+
+// Search returns the results of the first candidate which succeeds.
+func (f *Fallback) Search(ctx context.Context, pkg Package) ([]SearchResult, error) {
+	var errs []error
+	for _, c := range f.candidates {
+		results, err := c.Installer.Search(ctx, c.pkg(pkg))
+		if err == nil {
+			return results, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.Installer.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("all upstreams failed: %w", errors.Join(errs...))
+}
+
+// Prepare prepares every candidate which is a Preparer.
+// It only fails if no candidate could be prepared, as Install falls back from those which failed anyway.
+func (f *Fallback) Prepare(ctx context.Context) error {
+	var errs []error
+	for _, c := range f.candidates {
+		preparer, ok := c.Installer.(Preparer)
+		if !ok {
+			continue
+		}
+		if err := preparer.Prepare(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Installer.Name(), err))
+		}
+	}
+	if len(errs) < len(f.candidates) {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every candidate which is an io.Closer.
+func (f *Fallback) Close() error {
+	var errs []error
+	for _, c := range f.candidates {
+		if closer, ok := c.Installer.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", c.Installer.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dependencies resolves the dependency tree of pkg with the first candidate
+// which is a DependencyResolver and succeeds.
+func (f *Fallback) Dependencies(ctx context.Context, pkg Package) (*Dependency, error) {
+	var errs []error
+	for _, c := range f.candidates {
+		resolver, ok := c.Installer.(DependencyResolver)
+		if !ok {
+			continue
+		}
+		dep, err := resolver.Dependencies(ctx, c.pkg(pkg))
+		if err == nil {
+			return dep, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.Installer.Name(), err))
+	}
+	if errs == nil {
+		return nil, fmt.Errorf("%s: resolving dependencies: %w", f.Name(), errors.ErrUnsupported)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// locker returns the first candidate if it's a Locker.
+func (f *Fallback) locker() (Locker, Candidate, bool) {
+	if len(f.candidates) == 0 {
+		return nil, Candidate{}, false
+	}
+	locker, ok := f.candidates[0].Installer.(Locker)
+	return locker, f.candidates[0], ok
+}
+
+// LockfileName returns the lockfile name of the first candidate,
+// or an empty string if it doesn't support lockfiles.
+func (f *Fallback) LockfileName() string {
+	if locker, _, ok := f.locker(); ok {
+		return locker.LockfileName()
+	}
+	return ""
+}
+
+func (f *Fallback) Lock(ctx context.Context, pkg Package, path string) error {
+	locker, c, ok := f.locker()
+	if !ok {
+		return fmt.Errorf("%s: locking: %w", f.Name(), errors.ErrUnsupported)
+	}
+	return locker.Lock(ctx, c.pkg(pkg), path)
+}
+
+func (f *Fallback) UseLockfile(path string) {
+	if locker, _, ok := f.locker(); ok {
+		locker.UseLockfile(path)
+		f.lockfile = path
+	}
+}
+
+func (f *Fallback) CheckLock(ctx context.Context, pkg Package, path string) error {
+	locker, c, ok := f.locker()
+	if !ok {
+		return nil
+	}
+	return locker.CheckLock(ctx, c.pkg(pkg), path)
+}
+
+// Target configures every candidate for platform, see Targeter.
+// It fails if any candidate isn't a Targeter, as falling back to it would install binaries of the host.
+func (f *Fallback) Target(platform Platform) error {
+	var errs []error
+	for _, c := range f.candidates {
+		targeter, ok := c.Installer.(Targeter)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: installing for %s: %w", c.Installer.Name(), platform, errors.ErrUnsupported))
+			continue
+		}
+		if err := targeter.Target(platform); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Installer.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Volatile reports whether any candidate is volatile, see cache.Volatile.
+func (f *Fallback) Volatile() bool {
+	return slices.ContainsFunc(f.candidates, func(c Candidate) bool {
+		v, ok := c.Installer.(interface{ Volatile() bool })
+		return ok && v.Volatile()
+	})
+}
+
+// Fingerprint returns the fingerprint of the installer, the only candidate whose installs are cached,
+// see cache.Fingerprinter.
+func (f *Fallback) Fingerprint() (string, error) {
+	if fp, ok := f.candidates[0].Installer.(interface{ Fingerprint() (string, error) }); ok {
+		return fp.Fingerprint()
+	}
+	return "", nil
+}
+
+// dirEntries returns the names of the entries of dir, none if it doesn't exist.
+func dirEntries(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+// removeNewEntries removes the entries of dir which aren't among existing.
+func removeNewEntries(dir string, existing []string) error {
+	names, err := dirEntries(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if slices.Contains(existing, name) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// flakyInstaller writes a file named after itself, then fails with err if it isn't nil.
+type flakyInstaller struct {
+	name string
+	err  error
+	// installed is the package of the last Install.
+	installed Package
+}
+
+func (f *flakyInstaller) Name() string              { return f.name }
+func (f *flakyInstaller) Config() map[string]string { return map[string]string{"remote": f.name} }
+func (f *flakyInstaller) Install(ctx context.Context, pkg Package, outputDir string) (*InstallResult, error) {
+	f.installed = pkg
+	if err := os.WriteFile(filepath.Join(outputDir, f.name), nil, 0644); err != nil {
+		return nil, err
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &InstallResult{PkgConfigName: pkg.Name}, nil
+}
+func (f *flakyInstaller) Search(ctx context.Context, pkg Package) ([]SearchResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []SearchResult{{Name: pkg.Name, Version: pkg.Version}}, nil
+}
+
+func TestFallbackInstall(t *testing.T) {
+	errDown := errors.New("remote is down")
+	primary := &flakyInstaller{name: "primary", err: errDown}
+	mirror := &flakyInstaller{name: "mirror"}
+	u := &Upstream{
+		Installer: NewFallback(Candidate{Installer: primary}, Candidate{Installer: mirror, Package: "libcjson"}),
+		Pkg:       Package{Name: "cjson", Version: "1.7.18"},
+	}
+
+	outputDir := t.TempDir()
+	os.WriteFile(filepath.Join(outputDir, "existing"), nil, 0644)
+	result, err := u.Install(context.Background(), outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Installer != "mirror" || result.PkgConfigName != "libcjson" || !result.Fallback {
+		t.Errorf("unexpected install result: %+v", result)
+	}
+	if primary.installed.Name != "cjson" || mirror.installed != (Package{Name: "libcjson", Version: "1.7.18"}) {
+		t.Errorf("unexpected installed packages: %v %v", primary.installed, mirror.installed)
+	}
+	entries, _ := dirEntries(outputDir)
+	if strings.Join(entries, " ") != "existing mirror" {
+		t.Errorf("leftovers of the failed installer: %v", entries)
+	}
+
+	results, err := u.Search(context.Background())
+	if err != nil || len(results) != 1 || results[0].Name != "libcjson" {
+		t.Errorf("unexpected search results: %v %v", results, err)
+	}
+
+	mirror.err = errors.New("no such package")
+	_, err = u.Install(context.Background(), t.TempDir())
+	if !errors.Is(err, errDown) || !errors.Is(err, mirror.err) || !strings.Contains(err.Error(), "mirror: no such package") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFallbackConfig(t *testing.T) {
+	f := NewFallback(Candidate{Installer: &flakyInstaller{name: "a"}}, Candidate{Installer: &flakyInstaller{name: "b"}, Package: "c"})
+	config := f.Config()
+	want := map[string]string{"0.installer": "a", "0.config.remote": "a", "1.installer": "b", "1.package": "c", "1.config.remote": "b"}
+	if len(config) != len(want) {
+		t.Errorf("unexpected config: %v", config)
+	}
+	for key, value := range want {
+		if config[key] != value {
+			t.Errorf("unexpected config: %v", config)
+		}
+	}
+}
+
+func TestFallbackLock(t *testing.T) {
+	dir := t.TempDir()
+	locker := &lockingInstaller{}
+	u := &Upstream{
+		Installer: NewFallback(Candidate{Installer: locker}, Candidate{Installer: &flakyInstaller{name: "mirror"}}),
+		Pkg:       Package{Name: "cjson", Version: "1.7.18"},
+	}
+	path, err := u.Lock(context.Background(), dir)
+	if err != nil || path != filepath.Join(dir, "fake.lock") {
+		t.Fatalf("unexpected lockfile: %s %v", path, err)
+	}
+	if used, _ := u.UseLockfileIn(dir); used != path || locker.lockfile != path {
+		t.Errorf("lockfile not used: %s", used)
+	}
+
+	// the lockfile pins the first candidate only.
+	u.Installer = NewFallback(Candidate{Installer: &flakyInstaller{name: "mirror"}}, Candidate{Installer: locker})
+	if _, err := u.Lock(context.Background(), dir); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := u.CheckLock(context.Background(), dir); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// lockingFlakyInstaller is a flakyInstaller supporting lockfiles.
+type lockingFlakyInstaller struct {
+	flakyInstaller
+	lockfile string
+}
+
+func (l *lockingFlakyInstaller) LockfileName() string    { return "fake.lock" }
+func (l *lockingFlakyInstaller) UseLockfile(path string) { l.lockfile = path }
+func (l *lockingFlakyInstaller) Lock(ctx context.Context, pkg Package, path string) error {
+	return os.WriteFile(path, nil, 0644)
+}
+func (l *lockingFlakyInstaller) CheckLock(ctx context.Context, pkg Package, path string) error {
+	return nil
+}
+
+func TestFallbackDisableFallback(t *testing.T) {
+	errDown := errors.New("remote is down")
+	primary := &lockingFlakyInstaller{flakyInstaller: flakyInstaller{name: "primary", err: errDown}}
+	fallback := NewFallback(Candidate{Installer: primary}, Candidate{Installer: &flakyInstaller{name: "mirror"}})
+	fallback.DisableFallback()
+	u := &Upstream{Installer: fallback, Pkg: Package{Name: "cjson", Version: "1.7.18"}}
+
+	// without a lockfile, nothing pins the first candidate.
+	if result, err := u.Install(context.Background(), t.TempDir()); err != nil || result.Installer != "mirror" {
+		t.Errorf("unexpected install: %v %v", result, err)
+	}
+
+	dir := t.TempDir()
+	if _, err := u.Lock(context.Background(), dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.UseLockfileIn(dir); err != nil {
+		t.Fatal(err)
+	}
+	_, err := u.Install(context.Background(), t.TempDir())
+	if !errors.Is(err, errDown) || !strings.Contains(err.Error(), "not falling back") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	primary.err = nil
+	if result, err := u.Install(context.Background(), t.TempDir()); err != nil || result.Installer != "primary" || result.Fallback {
+		t.Errorf("unexpected install: %v %v", result, err)
+	}
+}
+
+// closingInstaller is a flakyInstaller holding resources until it's closed.
+type closingInstaller struct {
+	flakyInstaller
+	closed   bool
+	closeErr error
+}
+
+func (c *closingInstaller) Close() error {
+	c.closed = true
+	return c.closeErr
+}
+
+func TestFallbackClose(t *testing.T) {
+	errBusy := errors.New("home busy")
+	primary := &closingInstaller{flakyInstaller: flakyInstaller{name: "primary"}, closeErr: errBusy}
+	mirror := &closingInstaller{flakyInstaller: flakyInstaller{name: "mirror"}}
+	u := &Upstream{
+		Installer: NewFallback(Candidate{Installer: primary}, Candidate{Installer: &flakyInstaller{name: "plain"}}, Candidate{Installer: mirror}),
+		Pkg:       Package{Name: "cjson", Version: "1.7.18"},
+	}
+	// every candidate is closed, even after one failed
+	if err := u.Close(); !errors.Is(err, errBusy) || !strings.Contains(err.Error(), "primary") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !primary.closed || !mirror.closed {
+		t.Errorf("candidates not closed: %v %v", primary.closed, mirror.closed)
+	}
+
+	// installers which aren't closers have nothing to close
+	u.Installer = &flakyInstaller{name: "plain"}
+	if err := u.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
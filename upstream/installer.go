@@ -1,15 +1,106 @@
 package upstream
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrPackageNotFound is wrapped by the errors of every installer, plugins included,
+// when the package or its version doesn't exist in the upstream,
+// so that callers can tell it from other failures whatever the installer.
+var ErrPackageNotFound = errors.New("package not found")
+
+// ErrPCFileNotFound is returned by installers, plugins included, when the installed package has no .pc file.
+var ErrPCFileNotFound = errors.New("pc file not found")
+
 // Installer represents a package installer that can download, install, and locate binaries from a remote repository.
 // It provides methods to install packages to specific directories and search for installed package information.
+//
+// Implementations must honor ctx: when it's cancelled or its deadline expires, any child process
+// started for the operation should be killed and the operation should return ctx.Err().
 type Installer interface {
 	Name() string
 	Config() map[string]string
 	// Install downloads and installs the specified package.
 	// The outputDir is where build artifacts (e.g., .pc files, headers) are stored.
-	// Returns an error if installation fails, pkgConfigName if success.
-	Install(pkg Package, outputDir string) (pkgConfigName string, err error)
+	// Returns an error if installation fails, wrapping ErrPackageNotFound if the package doesn't exist,
+	// the description of the installed files if success.
+	Install(ctx context.Context, pkg Package, outputDir string) (*InstallResult, error)
 	// Search checks remote repository for the specified package availability.
-	// Returns the search results text and any encountered errors.
+	// Returns the available versions of the package, sorted with SortSearchResults,
+	// or an error wrapping ErrPackageNotFound if there's none.
+	Search(ctx context.Context, pkg Package) ([]SearchResult, error)
+}
+
+// LegacyInstaller is the Installer interface before context support was added.
+// Use FromLegacy to register an implementation of it.
+type LegacyInstaller interface {
+	Name() string
+	Config() map[string]string
+	Install(pkg Package, outputDir string) (pkgConfigName string, err error)
 	Search(pkg Package) ([]string, error)
 }
+
+// legacyInstaller adapts a LegacyInstaller to the Installer interface.
+type legacyInstaller struct {
+	LegacyInstaller
+}
+
+// FromLegacy adapts a LegacyInstaller to the Installer interface.
+//
+// As a LegacyInstaller can't be interrupted, the returned Installer stops waiting for it
+// and returns ctx.Err() once ctx is done, leaving the legacy operation running in the background.
+// The InstallResult is built by ScanInstallResult from the returned pkgConfigName,
+// and search results are parsed from the returned references by ParseSearchResult.
+func FromLegacy(l LegacyInstaller) Installer {
+	return &legacyInstaller{l}
+}
+
+// await runs fn in a goroutine and waits for it or ctx, whichever finishes first.
+func await[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type result struct {
+		ret T
+		err error
+	}
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	ch := make(chan result, 1)
+	go func() {
+		ret, err := fn()
+		ch <- result{ret, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.ret, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (l *legacyInstaller) Install(ctx context.Context, pkg Package, outputDir string) (*InstallResult, error) {
+	pkgConfigName, err := await(ctx, func() (string, error) {
+		return l.LegacyInstaller.Install(pkg, outputDir)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ScanInstallResult(pkg, pkgConfigName, outputDir)
+}
+
+func (l *legacyInstaller) Search(ctx context.Context, pkg Package) ([]SearchResult, error) {
+	refs, err := await(ctx, func() ([]string, error) {
+		return l.LegacyInstaller.Search(pkg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(refs))
+	for _, ref := range refs {
+		results = append(results, ParseSearchResult(ref))
+	}
+	SortSearchResults(results)
+	return results, nil
+}
@@ -1,12 +1,14 @@
 package conan
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/goplus/llpkgstore/internal/actions/file"
 	"github.com/goplus/llpkgstore/internal/actions/pc"
@@ -15,73 +17,21 @@ import (
 )
 
 var (
-	ErrPackageNotFound = errors.New("package not found")
-	ErrPCFileNotFound  = errors.New("pc file not found")
-)
-
-const (
-	ConanfileTemplate = `[requires]
-	%s/%s
-
-	[generators]
-	PkgConfigDeps
-
-	[options]
-	*:shared=True
-	%s`
+	// ErrPackageNotFound is upstream.ErrPackageNotFound.
+	//
+	// Deprecated: use upstream.ErrPackageNotFound.
+	ErrPackageNotFound = upstream.ErrPackageNotFound
+	// ErrPCFileNotFound is upstream.ErrPCFileNotFound.
+	//
+	// Deprecated: use upstream.ErrPCFileNotFound.
+	ErrPCFileNotFound = upstream.ErrPCFileNotFound
 )
 
 // in Conan, actual binary path is in the prefix field of *.pc file
-func (c *conanInstaller) findBinaryPathFromPC(pkg upstream.Package, dir string, installOutput []byte) (string, string, error) {
-	var m map[string]any
-	json.Unmarshal(installOutput, &m)
-
-	if len(m) == 0 {
-		return "", "", ErrPackageNotFound
-	}
-
-	graphMap, ok := m["graph"].(map[string]any)
-	if !ok {
-		return "", "", ErrPackageNotFound
-	}
-
-	nodeMap, ok := graphMap["nodes"].(map[string]any)
-	if !ok {
-		return "", "", ErrPackageNotFound
-	}
-
-	var pkgConfigName string
-
-	for _, packageInfo := range nodeMap {
-		packageInfoMap := packageInfo.(map[string]any)
-
-		packageName, ok := packageInfoMap["name"].(string)
-
-		if ok && packageName == pkg.Name {
-			// ok this is the result we want
-			cppInfo, ok := packageInfoMap["cpp_info"].(map[string]any)
-			if !ok {
-				continue
-			}
-			root, ok := cppInfo["root"].(map[string]any)
-			if !ok {
-				continue
-			}
-			properties, ok := root["properties"].(map[string]any)
-			if !ok {
-				continue
-			}
-			name, ok := properties["pkg_config_name"].(string)
-			if !ok {
-				continue
-			}
-			pkgConfigName = name
-			break
-		}
-	}
-	if pkgConfigName == "" {
-		// if pkg-config name is not specified, default to package name.
-		pkgConfigName = pkg.Name
+func (c *conanInstaller) findBinaryPathFromPC(pkg upstream.Package, dir string, node *Node) (string, string, error) {
+	pkgConfigName := pkg.Name
+	if node != nil {
+		pkgConfigName = node.PkgConfigName()
 	}
 	pcFile, err := os.ReadFile(filepath.Join(dir, pkgConfigName+".pc"))
 	if err != nil {
@@ -89,26 +39,89 @@ func (c *conanInstaller) findBinaryPathFromPC(pkg upstream.Package, dir string,
 	}
 	matches := pc.PrefixMatch.FindSubmatch(pcFile)
 	if len(matches) != 2 {
-		return "", "", ErrPCFileNotFound
+		return "", "", upstream.ErrPCFileNotFound
 	}
 	binaryDir := string(matches[1])
 	// check dir
 	fs, err := os.Stat(binaryDir)
 	if err != nil || !fs.IsDir() {
-		return "", "", ErrPCFileNotFound
+		return "", "", upstream.ErrPCFileNotFound
 	}
 	return binaryDir, pkgConfigName, nil
 }
 
+// dirSnapshot maps the files of a directory to their modification times, see snapshotDir.
+type dirSnapshot map[string]time.Time
+
+// snapshotDir records the files of dir, none if it doesn't exist.
+func snapshotDir(dir string) (dirSnapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	snapshot := dirSnapshot{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		snapshot[entry.Name()] = info.ModTime()
+	}
+	return snapshot, nil
+}
+
+// generatedFiles lists the files conan has generated in dir, i.e. .pc files and environment scripts:
+// those which are new or have been rewritten since before was taken.
+// The files which were there already, e.g. llpkg.cfg when installing into the llpkg directory, are left out.
+func generatedFiles(dir string, before dirSnapshot) (pcFiles, others []string, err error) {
+	after, err := snapshotDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, modTime := range after {
+		if prev, ok := before[name]; ok && prev.Equal(modTime) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if filepath.Ext(name) == ".pc" {
+			pcFiles = append(pcFiles, path)
+		} else {
+			others = append(others, path)
+		}
+	}
+	slices.Sort(pcFiles)
+	slices.Sort(others)
+	return
+}
+
+func init() {
+	upstream.Register("conan", NewConanInstaller)
+}
+
 // conanInstaller implements the upstream.Installer interface using the Conan package manager.
 // It handles installation of C/C++ libraries by executing installation commands,
 // and managing dependencies through Conan's remote repositories.
 type conanInstaller struct {
 	config map[string]string
+	// settings are the host settings of the target platform, none for the host, see Target.
+	settings []string
+	// lockfile is the lockfile to resolve from, see UseLockfile.
+	lockfile string
+	// home is the private conan home the configured remotes are added to, see Prepare.
+	home string
 }
 
 // NewConanInstaller creates a new Conan-based installer instance with provided configuration options.
-// The config map supports custom Conan options (e.g., "options": "cjson:utils=True").
+// Supported config keys, lists are space-separated:
+//   - "options": custom Conan options (e.g., "options": "cjson:utils=True").
+//   - "remotes": remotes to resolve packages from, as name=url or the name of an existing remote.
+//     Remotes with a url are added by Prepare, to a private conan home. Defaults to all remotes, and conancenter for Search.
+//   - "profile_host", "profile_build": host and build profiles, default to the detected default profile.
+//   - "settings": host settings (e.g., "build_type=Release compiler.cppstd=17").
+//   - "conf": conf entries (e.g., "tools.build:jobs=4").
 func NewConanInstaller(config map[string]string) upstream.Installer {
 	return &conanInstaller{
 		config: config,
@@ -123,84 +136,202 @@ func (c *conanInstaller) Config() map[string]string {
 	return c.config
 }
 
-// options combines Conan default options with user-specified options from configuration
-func (c *conanInstaller) options() []string {
-	arr := strings.Join([]string{`*:shared=True`, c.config["options"]}, " ")
+// ConfigKeys implements upstream.ConfigDescriber.
+func (c *conanInstaller) ConfigKeys() []upstream.ConfigKey {
+	return []upstream.ConfigKey{
+		{Name: "options", Description: "space-separated conan options, e.g. cjson/*:utils=True"},
+		{Name: "remotes", Description: "space-separated remotes to resolve packages from, as name=url or the name of an existing remote"},
+		{Name: "profile_host", Description: "host profile, defaults to the detected default profile"},
+		{Name: "profile_build", Description: "build profile, defaults to the detected default profile"},
+		{Name: "settings", Description: "space-separated host settings, e.g. build_type=Release"},
+		{Name: "conf", Description: "space-separated conf entries, e.g. tools.build:jobs=4"},
+	}
+}
+
+// conanOS and conanArch map GOOS/GOARCH to the os and arch settings of conan.
+var (
+	conanOS = map[string]string{
+		"linux":   "Linux",
+		"darwin":  "Macos",
+		"windows": "Windows",
+		"freebsd": "FreeBSD",
+	}
+	conanArch = map[string]string{
+		"amd64":   "x86_64",
+		"386":     "x86",
+		"arm64":   "armv8",
+		"arm":     "armv7",
+		"ppc64le": "ppc64le",
+		"riscv64": "riscv64",
+		"s390x":   "s390x",
+	}
+)
+
+// Target implements upstream.Targeter: the os and arch host settings select the binaries of platform,
+// the configured settings take precedence.
+func (c *conanInstaller) Target(platform upstream.Platform) error {
+	goos, arch := conanOS[platform.GOOS], conanArch[platform.GOARCH]
+	if goos == "" || arch == "" {
+		return fmt.Errorf("conan: unsupported platform %s", platform)
+	}
+	c.settings = []string{"os=" + goos, "arch=" + arch}
+	return nil
+}
+
+// options combines Conan default options with user-specified options from configuration.
+// Every package of the graph is built as shared libraries unless pkg is static.
+func (c *conanInstaller) options(pkg upstream.Package) []string {
+	shared := `*:shared=True`
+	if pkg.Static() {
+		shared = `*:shared=False`
+	}
+	arr := strings.Join([]string{shared, c.config["options"]}, " ")
 	return strings.Fields(arr)
 }
 
+// setRequires sets the requirement of pkg on builder along with everything which affects
+// its resolution: options, profiles, settings, conf, remotes and the lockfile.
+func (c *conanInstaller) setRequires(builder *cmdbuilder.CmdBuilder, pkg upstream.Package) {
+	builder.SetArg("requires", pkg.Name+"/"+pkg.Version)
+
+	for _, opt := range c.options(pkg) {
+		builder.SetArg("options", opt)
+	}
+	if profile := c.config["profile_host"]; profile != "" {
+		builder.SetArg("profile:host", profile)
+	}
+	if profile := c.config["profile_build"]; profile != "" {
+		builder.SetArg("profile:build", profile)
+	}
+	for _, setting := range slices.Concat(c.settings, strings.Fields(c.config["settings"])) {
+		builder.SetArg("settings", setting)
+	}
+	for _, conf := range strings.Fields(c.config["conf"]) {
+		builder.SetArg("conf", conf)
+	}
+	for _, remote := range c.remotes() {
+		builder.SetArg("remote", remote.Name)
+	}
+	if c.lockfile != "" {
+		builder.SetArg("lockfile", c.lockfile)
+	}
+}
+
 // Install executes Conan installation for the specified package into the output directory.
 // It generates a conan install command with required options,
 // and handles installation artifacts generation (e.g., .pc files).
-func (c *conanInstaller) Install(pkg upstream.Package, outputDir string) (string, error) {
+// The .pc files of the package, its components and its dependencies are generated by PkgConfigDeps,
+// the remaining files conan generates (environment scripts) are reported in GeneratedFiles.
+func (c *conanInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
 	// Build the following command
 	// conan install --requires %s -g PkgConfigDeps --options \\*:shared=True --build=missing --output-folder=%s\
 	builder := cmdbuilder.NewCmdBuilder(cmdbuilder.WithConanSerializer())
 
 	builder.SetName("conan")
 	builder.SetSubcommand("install")
-	builder.SetArg("requires", pkg.Name+"/"+pkg.Version)
+	c.setRequires(builder, pkg)
 	builder.SetArg("generator", "PkgConfigDeps")
 	builder.SetArg("build", "missing")
 	builder.SetArg("output-folder", outputDir)
 	builder.SetArg("format", "json")
 
-	for _, opt := range c.options() {
-		builder.SetArg("options", opt)
+	// only the files conan writes are generated, outputDir may hold others.
+	before, err := snapshotDir(outputDir)
+	if err != nil {
+		return nil, err
 	}
 
-	buildCmd := builder.Cmd()
+	buildCmd := c.command(builder.CmdContext(ctx))
 
 	// conan will output install result to Stdout, output progress to Stderr
 	buildCmd.Stderr = os.Stderr
 	ret, err := buildCmd.Output()
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		// fmt.Println(string(out))
-		return "", err
+		return nil, err
+	}
+	graph, err := ParseGraph(ret)
+	if err != nil {
+		return nil, err
+	}
+	node := graph.Find(pkg.Name)
+	binaryDir, pkgConfigName, err := c.findBinaryPathFromPC(pkg, outputDir, node)
+	if err != nil {
+		return nil, err
 	}
-	binaryDir, pkgConfigName, err := c.findBinaryPathFromPC(pkg, outputDir, ret)
+	// list the generated files before the package is copied into outputDir.
+	pcFiles, others, err := generatedFiles(outputDir, before)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	err = file.CopyFS(outputDir, os.DirFS(binaryDir), false)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	// static archives don't record their dependencies, release them along with the package.
+	if pkg.Static() && node != nil {
+		err = bundleStaticDependencies(graph, node, outputDir, filepath.Join(outputDir, pkgConfigName+".pc"))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return pkgConfigName, nil
+	result, err := upstream.ScanInstallResult(pkg, pkgConfigName, outputDir)
+	if err != nil {
+		return nil, err
+	}
+	result.PCFiles = pcFiles
+	result.GeneratedFiles = others
+	// the licenses are those of the package folder, not those which were in outputDir before.
+	result.Licenses = slices.DeleteFunc(result.Licenses, func(license string) bool {
+		rel, err := filepath.Rel(outputDir, license)
+		if err != nil {
+			return true
+		}
+		_, err = os.Stat(filepath.Join(binaryDir, rel))
+		return err != nil
+	})
+	if node != nil {
+		resolved := node.Package()
+		result.Reference = resolved.Name + "/" + resolved.Version
+		result.Revision = node.Revision()
+		result.Dependencies = graph.Dependencies(node)
+	}
+	return result, nil
 }
 
-// Search checks Conan remote repository for the specified package availability.
-// Returns the search results text and any encountered errors.
-func (c *conanInstaller) Search(pkg upstream.Package) ([]string, error) {
+// Dependencies resolves the dependency graph of pkg with the same options as Install, without installing it.
+func (c *conanInstaller) Dependencies(ctx context.Context, pkg upstream.Package) (*upstream.Dependency, error) {
 	// Build the following command
-	// conan search %s -r conancenter
+	// conan graph info --requires=%s --options=\*:shared=True --format=json
 	builder := cmdbuilder.NewCmdBuilder(cmdbuilder.WithConanSerializer())
 
 	builder.SetName("conan")
-	builder.SetSubcommand("search")
-	builder.SetObj(pkg.Name)
-	builder.SetArg("remote", "conancenter")
+	builder.SetSubcommand("graph")
+	builder.SetObj("info")
+	c.setRequires(builder, pkg)
+	builder.SetArg("format", "json")
 
-	cmd := builder.Cmd()
-	out, err := cmd.CombinedOutput()
+	cmd := c.command(builder.CmdContext(ctx))
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
 	if err != nil {
-		fmt.Println(string(out))
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
-	if strings.Contains(string(out), "not found") {
-		return nil, ErrPackageNotFound
+	graph, err := ParseGraph(out)
+	if err != nil {
+		return nil, err
 	}
-
-	var ret []string
-
-	for _, field := range strings.Fields(string(out)) {
-		prefix, _, found := strings.Cut(field, "/")
-		if found && prefix == pkg.Name {
-			ret = append(ret, field)
-		}
+	node := graph.Find(pkg.Name)
+	if node == nil {
+		return nil, upstream.ErrPackageNotFound
 	}
-
-	return ret, nil
+	return graph.Tree(node), nil
 }
@@ -1,6 +1,7 @@
 package conan
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
@@ -35,14 +36,18 @@ func TestConanInstaller(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	bp, err := c.Install(pkg, tempDir)
+	result, err := c.Install(context.Background(), pkg, tempDir)
 	if err != nil {
-		t.Errorf("Install failed: %s", err)
+		t.Fatalf("Install failed: %s", err)
 	}
 
-	t.Log(bp)
+	t.Log(result)
 
-	if err := verify(tempDir, bp); err != nil {
+	if result.Reference != pkg.Name+"/"+pkg.Version || result.PCFile() == "" {
+		t.Errorf("Unexpected install result: %+v", result)
+	}
+
+	if err := verify(tempDir, result.PkgConfigName); err != nil {
 		t.Errorf("Verify failed: %s", err)
 	}
 }
@@ -68,14 +73,18 @@ func TestConanIssue19(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	bp, err := c.Install(pkg, tempDir)
+	result, err := c.Install(context.Background(), pkg, tempDir)
 	if err != nil {
-		t.Errorf("Install failed: %s", err)
+		t.Fatalf("Install failed: %s", err)
 	}
 
-	t.Log(bp)
+	t.Log(result)
 
-	if err := verify(tempDir, bp); err != nil {
+	if result.Reference != pkg.Name+"/"+pkg.Version || result.PCFile() == "" {
+		t.Errorf("Unexpected install result: %+v", result)
+	}
+
+	if err := verify(tempDir, result.PkgConfigName); err != nil {
 		t.Errorf("Verify failed: %s", err)
 	}
 }
@@ -91,9 +100,9 @@ func TestConanSearch(t *testing.T) {
 		Name:    "cjson",
 		Version: "1.7.18",
 	}
-	ver, _ := c.Search(pkg)
-	if !slices.Contains(ver, "cjson/1.7.18") {
-		t.Errorf("unexpected search result: %s", ver)
+	ver, _ := c.Search(context.Background(), pkg)
+	if !slices.ContainsFunc(ver, func(r upstream.SearchResult) bool { return r.Reference() == "cjson/1.7.18" }) {
+		t.Errorf("unexpected search result: %v", ver)
 	}
 
 	t.Log(ver)
@@ -103,7 +112,7 @@ func TestConanSearch(t *testing.T) {
 		Version: "1.7.18",
 	}
 
-	_, err := c.Search(pkg)
+	_, err := c.Search(context.Background(), pkg)
 	if err == nil {
 		t.Errorf("unexpected behavior: %s", err)
 	}
@@ -144,3 +153,39 @@ func verify(installDir, pkgConfigName string) error {
 
 	return nil
 }
+
+// setupFakeConan puts a fake conan executable running script in front of PATH.
+func setupFakeConan(t *testing.T, script string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake conan requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "conan"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestConanDependencies(t *testing.T) {
+	graph, err := filepath.Abs("testdata/graph.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setupFakeConan(t, `[ "$1 $2 $3" = "graph info --requires=libcurl/8.6.0" ] || { echo "unexpected command: $*" >&2; exit 1; }
+cat "`+graph+`"
+`)
+
+	c := &conanInstaller{config: map[string]string{}}
+	tree, err := c.Dependencies(context.Background(), upstream.Package{Name: "libcurl", Version: "8.6.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Name != "libcurl" || len(tree.Dependencies) != 2 || tree.Dependencies[0].Name != "openssl" {
+		t.Errorf("unexpected tree: %+v", tree)
+	}
+
+	_, err = c.Dependencies(context.Background(), upstream.Package{Name: "libcurl2", Version: "8.6.0"})
+	if err == nil {
+		t.Error("unexpected success")
+	}
+}
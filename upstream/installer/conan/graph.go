@@ -0,0 +1,206 @@
+package conan
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// Options are the options of a node. Conan serializes option values as strings,
+// other scalars are accepted and formatted as well.
+type Options map[string]string
+
+func (o *Options) UnmarshalJSON(b []byte) error {
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	*o = make(Options, len(m))
+	for k, v := range m {
+		if v == nil {
+			continue
+		}
+		(*o)[k] = fmt.Sprint(v)
+	}
+	return nil
+}
+
+// CppInfo is the cpp_info of a node or of one of its components.
+type CppInfo struct {
+	IncludeDirs []string       `json:"includedirs"`
+	LibDirs     []string       `json:"libdirs"`
+	BinDirs     []string       `json:"bindirs"`
+	Libs        []string       `json:"libs"`
+	SystemLibs  []string       `json:"system_libs"`
+	Properties  map[string]any `json:"properties"`
+}
+
+// PkgConfigName returns the pkg_config_name property, if any.
+func (c *CppInfo) PkgConfigName() string {
+	name, _ := c.Properties["pkg_config_name"].(string)
+	return name
+}
+
+// Edge is a dependency of a node. Conan lists the transitive dependencies of a node,
+// Direct tells the direct ones apart.
+type Edge struct {
+	Ref    string `json:"ref"`
+	Direct bool   `json:"direct"`
+	// Build is set for build requirements, i.e. tools only used to build the node.
+	Build bool `json:"build"`
+}
+
+// Node is a package in the dependency graph.
+type Node struct {
+	// Ref is the reference of the node, name/version#revision. It's "conanfile" for the consumer.
+	Ref           string  `json:"ref"`
+	Name          string  `json:"name"`
+	Version       string  `json:"version"`
+	RecipeRev     string  `json:"rrev"`
+	Context       string  `json:"context"`
+	PackageFolder string  `json:"package_folder"`
+	Options       Options `json:"options"`
+	// CppInfo maps "root" and the component names to their cpp_info.
+	CppInfo      map[string]*CppInfo `json:"cpp_info"`
+	Dependencies map[string]Edge     `json:"dependencies"`
+}
+
+// Package returns the name and version of the node.
+func (n *Node) Package() upstream.Package {
+	pkg, _ := splitReference(n.Ref)
+	if n.Name != "" {
+		pkg.Name = n.Name
+	}
+	if n.Version != "" {
+		pkg.Version = n.Version
+	}
+	return pkg
+}
+
+// Revision returns the recipe revision of the node.
+func (n *Node) Revision() string {
+	if n.RecipeRev != "" {
+		return n.RecipeRev
+	}
+	_, rev := splitReference(n.Ref)
+	return rev
+}
+
+// PkgConfigName returns the name of the .pc file PkgConfigDeps generates for the node.
+// If pkg-config name is not specified, default to package name.
+func (n *Node) PkgConfigName() string {
+	if root := n.CppInfo["root"]; root != nil {
+		if name := root.PkgConfigName(); name != "" {
+			return name
+		}
+	}
+	return n.Package().Name
+}
+
+// Graph is the dependency graph in the JSON output of conan install and conan graph info.
+type Graph struct {
+	// Nodes maps node ids to the nodes, "0" is the consumer.
+	Nodes map[string]*Node `json:"nodes"`
+}
+
+// ParseGraph parses the --format=json output of conan install or conan graph info.
+func ParseGraph(output []byte) (*Graph, error) {
+	var m struct {
+		Graph *Graph `json:"graph"`
+	}
+	if err := json.Unmarshal(output, &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", upstream.ErrPackageNotFound, err)
+	}
+	if m.Graph == nil || len(m.Graph.Nodes) == 0 {
+		return nil, upstream.ErrPackageNotFound
+	}
+	return m.Graph, nil
+}
+
+// Find returns the host node of the package named name.
+func (g *Graph) Find(name string) *Node {
+	for _, id := range g.sortedIDs() {
+		node := g.Nodes[id]
+		if node.Context != "build" && node.Package().Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// sortedIDs returns the node ids in order, so that lookups are deterministic.
+func (g *Graph) sortedIDs() []string {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	})
+	return ids
+}
+
+// sortedEdges returns the ids of the host dependencies of node sorted by package name.
+func (g *Graph) sortedEdges(node *Node, directOnly bool) []string {
+	var ids []string
+	for id, edge := range node.Dependencies {
+		if edge.Build || (directOnly && !edge.Direct) || g.Nodes[id] == nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		return strings.Compare(g.Nodes[a].Package().Name, g.Nodes[b].Package().Name)
+	})
+	return ids
+}
+
+// Dependencies returns the transitive host dependencies of node, sorted by name.
+func (g *Graph) Dependencies(node *Node) []upstream.Package {
+	var ret []upstream.Package
+	for _, id := range g.sortedEdges(node, false) {
+		ret = append(ret, g.Nodes[id].Package())
+	}
+	return ret
+}
+
+// Tree returns the dependency tree rooted at node, following direct host dependencies.
+func (g *Graph) Tree(node *Node) *upstream.Dependency {
+	return g.tree(node, map[*Node]bool{})
+}
+
+func (g *Graph) tree(node *Node, visiting map[*Node]bool) *upstream.Dependency {
+	pkg := node.Package()
+	dep := &upstream.Dependency{
+		Name:          pkg.Name,
+		Version:       pkg.Version,
+		Revision:      node.Revision(),
+		Options:       node.Options,
+		PkgConfigName: node.PkgConfigName(),
+	}
+	// conan graphs are acyclic, but don't trust the input.
+	if visiting[node] {
+		return dep
+	}
+	visiting[node] = true
+	defer delete(visiting, node)
+
+	for _, id := range g.sortedEdges(node, true) {
+		dep.Dependencies = append(dep.Dependencies, g.tree(g.Nodes[id], visiting))
+	}
+	return dep
+}
+
+// splitReference splits a conan reference name/version[@user/channel][#revision].
+func splitReference(ref string) (pkg upstream.Package, revision string) {
+	ref, revision, _ = strings.Cut(ref, "#")
+	ref, _, _ = strings.Cut(ref, "@")
+	pkg.Name, pkg.Version, _ = strings.Cut(ref, "/")
+	return
+}
@@ -0,0 +1,99 @@
+package conan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+func readGraph(t *testing.T) *Graph {
+	b, err := os.ReadFile("testdata/graph.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := ParseGraph(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+func TestParseGraph(t *testing.T) {
+	graph := readGraph(t)
+
+	node := graph.Find("libcurl")
+	if node == nil {
+		t.Fatal("libcurl not found")
+	}
+	if pkg := node.Package(); pkg.Name != "libcurl" || pkg.Version != "8.6.0" {
+		t.Errorf("unexpected package: %v", pkg)
+	}
+	if node.Revision() != "0a5fe3d4e5a1c8b2b6d3e4f5a6b7c8d9" {
+		t.Errorf("unexpected revision: %s", node.Revision())
+	}
+	// the pkg-config name is set on the component only, fall back to the package name.
+	if node.PkgConfigName() != "libcurl" {
+		t.Errorf("unexpected pkg-config name: %s", node.PkgConfigName())
+	}
+	if node.Options["with_zlib"] != "true" || node.Options["shared"] != "True" {
+		t.Errorf("unexpected options: %v", node.Options)
+	}
+	if _, ok := node.Options["fPIC"]; ok {
+		t.Errorf("unexpected null option: %v", node.Options)
+	}
+	if graph.Find("libtool") != nil {
+		t.Error("build requirements must not be found")
+	}
+
+	// zlib has no rrev field, the revision comes from the reference.
+	if rev := graph.Find("zlib").Revision(); rev != "f52e03ae3d251dec704634230cd806a2" {
+		t.Errorf("unexpected revision: %s", rev)
+	}
+
+	deps := graph.Dependencies(node)
+	expected := []upstream.Package{{Name: "openssl", Version: "3.2.1"}, {Name: "zlib", Version: "1.3.1"}}
+	if !slices.Equal(deps, expected) {
+		t.Errorf("unexpected dependencies: %v", deps)
+	}
+
+	for _, output := range []string{``, `{}`, `{"graph": {"nodes": {}}}`} {
+		if _, err := ParseGraph([]byte(output)); !errors.Is(err, upstream.ErrPackageNotFound) {
+			t.Errorf("unexpected error for %q: %v", output, err)
+		}
+	}
+}
+
+func TestGraphTree(t *testing.T) {
+	graph := readGraph(t)
+
+	tree := graph.Tree(graph.Find("libcurl"))
+
+	var got []string
+	tree.Walk(func(dep *upstream.Dependency, depth int) error {
+		got = append(got, fmt.Sprintf("%d %s/%s %s", depth, dep.Name, dep.Version, dep.PkgConfigName))
+		return nil
+	})
+	expected := []string{
+		"0 libcurl/8.6.0 libcurl",
+		"1 openssl/3.2.1 openssl",
+		"2 zlib/1.3.1 zlib",
+		"1 zlib/1.3.1 zlib",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("unexpected tree:\nwant %v\ngot  %v", expected, got)
+	}
+
+	b, err := json.Marshal(tree.Dependencies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expectedJSON = `{"name":"openssl","version":"3.2.1","revision":"3f5ae9d7b2c1e0f4a8b6c5d4e3f2a1b0","options":{"shared":"True"},"pkgConfigName":"openssl","dependencies":[{"name":"zlib","version":"1.3.1","revision":"f52e03ae3d251dec704634230cd806a2","options":{"shared":"True"},"pkgConfigName":"zlib"}]}`
+	if string(b) != expectedJSON {
+		t.Errorf("unexpected json: %s", string(b))
+	}
+}
@@ -0,0 +1,145 @@
+package conan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/cmdbuilder"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// LockfileName is the name of the conan lockfile next to llpkg.cfg.
+const LockfileName = "conan.lock"
+
+// Lockfile is a conan lockfile. References are name/version#revision%timestamp.
+type Lockfile struct {
+	Version        string   `json:"version"`
+	Requires       []string `json:"requires"`
+	BuildRequires  []string `json:"build_requires"`
+	PythonRequires []string `json:"python_requires"`
+	ConfigRequires []string `json:"config_requires,omitempty"`
+}
+
+// ReadLockfile reads the conan lockfile at path.
+func ReadLockfile(path string) (*Lockfile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var l Lockfile
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &l, nil
+}
+
+// Locks reports whether the lockfile pins pkg.
+func (l *Lockfile) Locks(pkg upstream.Package) bool {
+	for _, ref := range l.Requires {
+		locked, _ := splitReference(ref)
+		if locked.Name == pkg.Name && locked.Version == pkg.Version {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal reports whether l and other lock the same references, regardless of their order.
+func (l *Lockfile) Equal(other *Lockfile) bool {
+	equal := func(a, b []string) bool {
+		a, b = slices.Clone(a), slices.Clone(b)
+		slices.Sort(a)
+		slices.Sort(b)
+		return slices.Equal(a, b)
+	}
+	return equal(l.Requires, other.Requires) &&
+		equal(l.BuildRequires, other.BuildRequires) &&
+		equal(l.PythonRequires, other.PythonRequires) &&
+		equal(l.ConfigRequires, other.ConfigRequires)
+}
+
+func (c *conanInstaller) LockfileName() string {
+	return LockfileName
+}
+
+// UseLockfile makes Install and Dependencies resolve strictly from the lockfile at path,
+// conan fails if a requirement isn't locked.
+func (c *conanInstaller) UseLockfile(path string) {
+	c.lockfile = path
+}
+
+// lockCreate runs conan lock create for pkg and writes the lockfile to out.
+func (c *conanInstaller) lockCreate(ctx context.Context, pkg upstream.Package, out string, args ...string) error {
+	// Build the following command
+	// conan lock create --requires=%s --options=\*:shared=True --lockfile-out=%s
+	builder := cmdbuilder.NewCmdBuilder(cmdbuilder.WithConanSerializer())
+
+	builder.SetName("conan")
+	builder.SetSubcommand("lock")
+	builder.SetObj("create")
+	c.setRequires(builder, pkg)
+	builder.SetArg("lockfile-out", out)
+
+	cmd := c.command(builder.CmdContext(ctx))
+	cmd.Args = append(cmd.Args, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// Lock resolves pkg with the latest recipe revisions and writes the lockfile to path.
+// The lockfile in use, if any, is ignored, so Lock refreshes it.
+func (c *conanInstaller) Lock(ctx context.Context, pkg upstream.Package, path string) error {
+	fresh := *c
+	fresh.lockfile = ""
+	return fresh.lockCreate(ctx, pkg, path)
+}
+
+// CheckLock checks that the lockfile at path pins pkg and resolving pkg with the current options
+// from it neither needs unlocked references nor leaves locked references unused.
+func (c *conanInstaller) CheckLock(ctx context.Context, pkg upstream.Package, path string) error {
+	locked, err := ReadLockfile(path)
+	if err != nil {
+		return err
+	}
+	if !locked.Locks(pkg) {
+		return fmt.Errorf("%w: %s doesn't lock %s/%s", upstream.ErrLockfileOutdated, path, pkg.Name, pkg.Version)
+	}
+
+	tempDir, err := os.MkdirTemp("", "llpkg-conan-lock")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	out := filepath.Join(tempDir, LockfileName)
+
+	strict := *c
+	strict.lockfile = path
+	// conan fails if a requirement isn't locked, --lockfile-clean drops the unused ones.
+	if err := strict.lockCreate(ctx, pkg, out, "--lockfile-clean"); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s: %v", upstream.ErrLockfileOutdated, path, err)
+	}
+	resolved, err := ReadLockfile(out)
+	if err != nil {
+		return err
+	}
+	if !resolved.Equal(locked) {
+		return fmt.Errorf("%w: %s: locked %s, resolved %s", upstream.ErrLockfileOutdated, path,
+			strings.Join(locked.Requires, " "), strings.Join(resolved.Requires, " "))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package conan
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+const testLockfile = `{
+    "version": "0.5",
+    "requires": [
+        "zlib/1.3.1#f52e03ae3d251dec704634230cd806a2%1708593606.497",
+        "cjson/1.7.18#39de7c1fa8d3d1b2f4b3e3e4a5b6c7d8%1713177155.123"
+    ],
+    "build_requires": [],
+    "python_requires": [],
+    "config_requires": []
+}`
+
+// fakeLockConan records its arguments to $FAKE_CONAN_LOG and writes $FAKE_CONAN_LOCK
+// to --lockfile-out, it fails if $FAKE_CONAN_FAIL is set.
+const fakeLockConan = `echo "$*" >> "$FAKE_CONAN_LOG"
+[ -z "$FAKE_CONAN_FAIL" ] || { echo "ERROR: Requirement 'cjson/1.7.19' not in lockfile" >&2; exit 1; }
+for arg; do
+	case "$arg" in
+	--lockfile-out=*) printf '%s' "$FAKE_CONAN_LOCK" > "${arg#*=}" ;;
+	esac
+done
+`
+
+func setupFakeLockConan(t *testing.T) (log string) {
+	setupFakeConan(t, fakeLockConan)
+	log = filepath.Join(t.TempDir(), "conan.log")
+	t.Setenv("FAKE_CONAN_LOG", log)
+	t.Setenv("FAKE_CONAN_LOCK", testLockfile)
+	return
+}
+
+func TestConanLock(t *testing.T) {
+	log := setupFakeLockConan(t)
+	c := &conanInstaller{config: map[string]string{"options": "cjson/*:utils=True"}}
+	pkg := upstream.Package{Name: "cjson", Version: "1.7.18"}
+	path := filepath.Join(t.TempDir(), c.LockfileName())
+
+	c.UseLockfile("/stale/conan.lock")
+	if err := c.Lock(context.Background(), pkg, path); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(log)
+	expected := "lock create --requires=cjson/1.7.18 --options=*:shared=True --options=cjson/*:utils=True --lockfile-out=" + path + "\n"
+	if string(b) != expected {
+		t.Errorf("unexpected command:\nwant %s\ngot  %s", expected, string(b))
+	}
+	lock, err := ReadLockfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lock.Locks(pkg) || lock.Locks(upstream.Package{Name: "cjson", Version: "1.7.19"}) {
+		t.Errorf("unexpected lockfile: %+v", lock)
+	}
+	static := pkg
+	static.Linkage = upstream.LinkageStatic
+	if !lock.Locks(static) {
+		t.Errorf("linkage must not affect the locked reference: %+v", lock)
+	}
+}
+
+func TestConanCheckLock(t *testing.T) {
+	log := setupFakeLockConan(t)
+	c := &conanInstaller{config: map[string]string{}}
+	pkg := upstream.Package{Name: "cjson", Version: "1.7.18"}
+	path := filepath.Join(t.TempDir(), LockfileName)
+	os.WriteFile(path, []byte(testLockfile), 0644)
+
+	if err := c.CheckLock(context.Background(), pkg, path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := os.ReadFile(log)
+	if !strings.Contains(string(b), "--lockfile="+path) || !strings.Contains(string(b), "--lockfile-clean") {
+		t.Errorf("lockfile not checked strictly: %s", string(b))
+	}
+
+	// the version in llpkg.cfg has been bumped.
+	err := c.CheckLock(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.19"}, path)
+	if !errors.Is(err, upstream.ErrLockfileOutdated) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// a new requirement isn't locked.
+	t.Setenv("FAKE_CONAN_FAIL", "1")
+	if err := c.CheckLock(context.Background(), pkg, path); !errors.Is(err, upstream.ErrLockfileOutdated) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	t.Setenv("FAKE_CONAN_FAIL", "")
+
+	// a locked requirement isn't used any more.
+	t.Setenv("FAKE_CONAN_LOCK", strings.Replace(testLockfile, `"zlib/1.3.1#f52e03ae3d251dec704634230cd806a2%1708593606.497",`, "", 1))
+	if err := c.CheckLock(context.Background(), pkg, path); !errors.Is(err, upstream.ErrLockfileOutdated) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package conan
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/actions/file"
+	"github.com/goplus/llpkgstore/internal/cmdbuilder"
+)
+
+// DefaultRemote is the remote Search uses if no remotes are configured.
+const DefaultRemote = "conancenter"
+
+// Remote is a conan remote in the "remotes" config, URL is empty for an existing remote.
+type Remote struct {
+	Name string
+	URL  string
+}
+
+// remotes parses the "remotes" config, entries are name=url or name.
+func (c *conanInstaller) remotes() []Remote {
+	var ret []Remote
+	for _, entry := range strings.Fields(c.config["remotes"]) {
+		name, url, _ := strings.Cut(entry, "=")
+		ret = append(ret, Remote{Name: name, URL: url})
+	}
+	return ret
+}
+
+// homeFiles are the files and dirs of the conan home a private home starts from.
+var homeFiles = []string{"global.conf", "remotes.json", "settings.yml", "settings_user.yml", "profiles", "extensions"}
+
+// userHome returns the conan home of the user, $CONAN_HOME or ~/.conan2.
+func userHome() (string, error) {
+	if home := os.Getenv("CONAN_HOME"); home != "" {
+		return home, nil
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ".conan2"), nil
+}
+
+// newHome creates a private conan home in the temp dir, which starts from the configuration,
+// the profiles and the remotes of the user's home and shares its package storage,
+// so that remotes can be added for a run without changing the remotes of the user.
+// The caller removes it once done, see conanInstaller.Close.
+func newHome() (string, error) {
+	from, err := userHome()
+	if err != nil {
+		return "", err
+	}
+	home, err := os.MkdirTemp("", "llpkg-conan-home")
+	if err != nil {
+		return "", err
+	}
+	if err := initHome(home, from); err != nil {
+		os.RemoveAll(home)
+		return "", err
+	}
+	return home, nil
+}
+
+// initHome copies the homeFiles of the conan home from to home and makes it share the package storage of from.
+func initHome(home, from string) error {
+	for _, name := range homeFiles {
+		info, err := os.Stat(filepath.Join(from, name))
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return err
+		case info.IsDir():
+			err = file.CopyFS(filepath.Join(home, name), os.DirFS(filepath.Join(from, name)), false)
+		default:
+			err = file.CopyFile(filepath.Join(from, name), filepath.Join(home, name))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	conf, err := os.ReadFile(filepath.Join(home, "global.conf"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !bytes.Contains(conf, []byte("core.cache:storage_path")) {
+		if len(conf) > 0 && conf[len(conf)-1] != '\n' {
+			conf = append(conf, '\n')
+		}
+		conf = append(conf, "core.cache:storage_path="+filepath.Join(from, "p")+"\n"...)
+		if err := os.WriteFile(filepath.Join(home, "global.conf"), conf, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// command makes cmd run conan in the conan home of c, if it has a private one.
+func (c *conanInstaller) command(cmd *exec.Cmd) *exec.Cmd {
+	if c.home != "" {
+		cmd.Env = append(os.Environ(), "CONAN_HOME="+c.home)
+	}
+	return cmd
+}
+
+// run runs a conan command which prints nothing of interest, its output goes to Stderr.
+func (c *conanInstaller) run(ctx context.Context, args ...string) error {
+	cmd := c.command(cmdbuilder.CommandContext(ctx, "conan", args...))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("conan %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// Prepare detects the default profile unless both profiles are configured,
+// and adds or updates the configured remotes with a url.
+// The remotes are added to a private conan home, created from the user's one by the first Prepare,
+// which all conan commands of c run in, so the remotes of the user are left untouched. Close removes it.
+// Credentials of remotes are taken from CONAN_LOGIN_USERNAME_{REMOTE} and CONAN_PASSWORD_{REMOTE} by conan.
+func (c *conanInstaller) Prepare(ctx context.Context) error {
+	if c.home == "" && slices.ContainsFunc(c.remotes(), func(remote Remote) bool { return remote.URL != "" }) {
+		home, err := newHome()
+		if err != nil {
+			return fmt.Errorf("creating conan home: %w", err)
+		}
+		c.home = home
+	}
+	if c.config["profile_host"] == "" || c.config["profile_build"] == "" {
+		if err := c.run(ctx, "profile", "detect", "--exist-ok"); err != nil {
+			return err
+		}
+	}
+	for _, remote := range c.remotes() {
+		if remote.URL == "" {
+			continue
+		}
+		if err := c.run(ctx, "remote", "add", "--force", remote.Name, remote.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// profilePath returns the path of a profile given to conan: a path, absolute or relative to the working directory,
+// or the name of a profile in the profiles of home.
+func profilePath(home, profile string) string {
+	if filepath.IsAbs(profile) {
+		return profile
+	}
+	if _, err := os.Stat(profile); err == nil {
+		return profile
+	}
+	return filepath.Join(home, "profiles", profile)
+}
+
+// Fingerprint returns the SHA-256 of the host and build profiles, the detected default profile unless configured,
+// which the installed binaries depend on but the config only names. It implements cache.Fingerprinter.
+func (c *conanInstaller) Fingerprint() (string, error) {
+	home := c.home
+	if home == "" {
+		var err error
+		if home, err = userHome(); err != nil {
+			return "", err
+		}
+	}
+	h := sha256.New()
+	for _, key := range []string{"profile_host", "profile_build"} {
+		profile := c.config[key]
+		if profile == "" {
+			profile = "default"
+		}
+		b, err := os.ReadFile(profilePath(home, profile))
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", key, err)
+		}
+		fmt.Fprintf(h, "%s %d\n", key, len(b))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Close removes the private conan home created by Prepare, if any.
+// It implements io.Closer, see upstream.Upstream.Close.
+func (c *conanInstaller) Close() error {
+	if c.home == "" {
+		return nil
+	}
+	err := os.RemoveAll(c.home)
+	c.home = ""
+	return err
+}
+
+// Volatile reports whether the installed files can change without the config changing,
+// which is the case without a lockfile, as the latest recipe revisions are resolved from the remotes.
+// It implements cache.Volatile.
+func (c *conanInstaller) Volatile() bool {
+	return c.lockfile == ""
+}
@@ -0,0 +1,208 @@
+package conan
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+func TestConanPrepare(t *testing.T) {
+	setupFakeConan(t, `echo "$CONAN_HOME $*" >> "$FAKE_CONAN_LOG"`)
+	log := filepath.Join(t.TempDir(), "conan.log")
+	t.Setenv("FAKE_CONAN_LOG", log)
+	userHome := t.TempDir()
+	t.Setenv("CONAN_HOME", userHome)
+	os.MkdirAll(filepath.Join(userHome, "profiles"), 0755)
+	os.WriteFile(filepath.Join(userHome, "profiles", "linux-gcc13"), []byte("[settings]\n"), 0644)
+	os.WriteFile(filepath.Join(userHome, "remotes.json"), []byte(`{"remotes": []}`), 0644)
+	os.WriteFile(filepath.Join(userHome, "global.conf"), []byte("core:non_interactive=True"), 0644)
+
+	c := &conanInstaller{config: map[string]string{
+		"remotes":      "internal=https://artifactory.example.com/artifactory/api/conan/conan conancenter",
+		"profile_host": "linux-gcc13",
+	}}
+	if err := c.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the remote is added to a private home, starting from the user's one and sharing its package storage
+	if c.home == "" || c.home == userHome {
+		t.Fatalf("unexpected conan home: %q", c.home)
+	}
+	defer c.Close()
+	b, _ := os.ReadFile(log)
+	expected := c.home + ` profile detect --exist-ok
+` + c.home + ` remote add --force internal https://artifactory.example.com/artifactory/api/conan/conan
+`
+	if string(b) != expected {
+		t.Errorf("unexpected commands:\nwant %s\ngot  %s", expected, string(b))
+	}
+	for _, name := range []string{"remotes.json", filepath.Join("profiles", "linux-gcc13")} {
+		if _, err := os.Stat(filepath.Join(c.home, name)); err != nil {
+			t.Errorf("not copied from the user's home: %v", err)
+		}
+	}
+	conf, _ := os.ReadFile(filepath.Join(c.home, "global.conf"))
+	if want := "core:non_interactive=True\ncore.cache:storage_path=" + filepath.Join(userHome, "p") + "\n"; string(conf) != want {
+		t.Errorf("unexpected global.conf:\nwant %s\ngot  %s", want, string(conf))
+	}
+
+	// later commands run in the private home as well
+	os.Remove(log)
+	// the fake conan writes no lockfile, only the command matters
+	c.Lock(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"}, filepath.Join(t.TempDir(), LockfileName))
+	if b, _ = os.ReadFile(log); !strings.HasPrefix(string(b), c.home+" lock create ") {
+		t.Errorf("unexpected command: %s", string(b))
+	}
+
+	// no need to detect the default profile if both profiles are given, nor for a private home without remotes to add.
+	os.Remove(log)
+	c = &conanInstaller{config: map[string]string{"profile_host": "linux-gcc13", "profile_build": "default", "remotes": "conancenter"}}
+	if err := c.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(log); !os.IsNotExist(err) {
+		t.Error("unexpected conan command")
+	}
+	if c.home != "" {
+		t.Errorf("unexpected conan home: %q", c.home)
+	}
+}
+
+func TestConanConfigArgs(t *testing.T) {
+	log := setupFakeLockConan(t)
+	c := &conanInstaller{config: map[string]string{
+		"remotes":       "internal=https://artifactory.example.com conancenter",
+		"profile_host":  "linux-gcc13",
+		"profile_build": "default",
+		"settings":      "build_type=Release compiler.cppstd=17",
+		"conf":          "tools.build:jobs=4",
+	}}
+	path := filepath.Join(t.TempDir(), LockfileName)
+	if err := c.Lock(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"}, path); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(log)
+	expected := "lock create --requires=cjson/1.7.18 --options=*:shared=True --profile:host=linux-gcc13 --profile:build=default " +
+		"--settings=build_type=Release --settings=compiler.cppstd=17 --conf=tools.build:jobs=4 " +
+		"--remote=internal --remote=conancenter --lockfile-out=" + path + "\n"
+	if string(b) != expected {
+		t.Errorf("unexpected command:\nwant %s\ngot  %s", expected, string(b))
+	}
+}
+
+func TestConanTarget(t *testing.T) {
+	log := setupFakeLockConan(t)
+	c := &conanInstaller{config: map[string]string{"settings": "build_type=Release"}}
+	if err := c.Target(upstream.Platform{GOOS: "linux", GOARCH: "arm64"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), LockfileName)
+	if err := c.Lock(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"}, path); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(log)
+	expected := "lock create --requires=cjson/1.7.18 --options=*:shared=True " +
+		"--settings=os=Linux --settings=arch=armv8 --settings=build_type=Release --lockfile-out=" + path + "\n"
+	if string(b) != expected {
+		t.Errorf("unexpected command:\nwant %s\ngot  %s", expected, string(b))
+	}
+
+	if err := c.Target(upstream.Platform{GOOS: "plan9", GOARCH: "amd64"}); err == nil {
+		t.Error("unexpected success for an unsupported platform")
+	}
+}
+
+func TestConanFingerprint(t *testing.T) {
+	userHome := t.TempDir()
+	t.Setenv("CONAN_HOME", userHome)
+	os.MkdirAll(filepath.Join(userHome, "profiles"), 0755)
+	writeProfile := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeProfile(filepath.Join(userHome, "profiles", "default"), "[settings]\nos=Linux\n")
+
+	c := &conanInstaller{config: map[string]string{}}
+	detected, err := c.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the detected default profile changes with the machine
+	writeProfile(filepath.Join(userHome, "profiles", "default"), "[settings]\nos=Linux\ncompiler.version=14\n")
+	if fingerprint, _ := c.Fingerprint(); fingerprint == detected {
+		t.Error("the default profile should change the fingerprint")
+	}
+
+	// so does a configured profile file, for the same config
+	host := filepath.Join(t.TempDir(), "linux-gcc13")
+	writeProfile(host, "[settings]\ncompiler.version=13\n")
+	c.config["profile_host"] = host
+	before, err := c.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeProfile(host, "[settings]\ncompiler.version=13\nbuild_type=Debug\n")
+	if after, _ := c.Fingerprint(); after == before {
+		t.Error("the host profile should change the fingerprint")
+	}
+
+	c.config["profile_build"] = "missing"
+	if _, err := c.Fingerprint(); !os.IsNotExist(errors.Unwrap(err)) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConanVolatile(t *testing.T) {
+	c := &conanInstaller{config: map[string]string{}}
+	// the latest recipe revisions are installed without a lockfile
+	if !c.Volatile() {
+		t.Error("unexpected cached install without a lockfile")
+	}
+	c.UseLockfile(filepath.Join(t.TempDir(), LockfileName))
+	if c.Volatile() {
+		t.Error("unexpected volatile install with a lockfile")
+	}
+}
+
+func TestConanClose(t *testing.T) {
+	setupFakeConan(t, "")
+	t.Setenv("CONAN_HOME", t.TempDir())
+	c := &conanInstaller{config: map[string]string{"remotes": "internal=https://artifactory.example.com"}}
+	if err := c.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	home := c.home
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(home); !os.IsNotExist(err) {
+		t.Errorf("private conan home not removed: %v", err)
+	}
+	// closing twice, or without a private home, is a no-op
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConanNewHomeFailure(t *testing.T) {
+	// the private home is created in os.TempDir
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+	t.Setenv("TMP", tmpDir)
+	userHome := t.TempDir()
+	t.Setenv("CONAN_HOME", userHome)
+	// a global.conf which can't be read
+	os.MkdirAll(filepath.Join(userHome, "global.conf"), 0755)
+	if _, err := newHome(); err == nil {
+		t.Fatal("unexpected success")
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 0 {
+		t.Errorf("private conan home left behind: %v", entries)
+	}
+}
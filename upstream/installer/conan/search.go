@@ -0,0 +1,107 @@
+package conan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/goplus/llpkgstore/internal/cmdbuilder"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// listRecipe is a recipe reference in the output of `conan list --format=json`.
+type listRecipe struct {
+	Revisions map[string]json.RawMessage `json:"revisions"`
+}
+
+// parseList parses the output of `conan list <pattern>#latest --format=json`, which maps
+// every remote to either its matching recipe references or an error:
+//
+//	{"conancenter": {"cjson/1.7.18": {"revisions": {"<rrev>": {"timestamp": ...}}}}}
+//	{"internal": {"error": "..."}}
+//
+// Remotes are visited in the order of remotes, results of a remote without matching
+// references are empty and errors of remotes are returned joined.
+func parseList(output []byte, name string, remotes []string) ([]upstream.SearchResult, error) {
+	var list map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("conan list: %w", err)
+	}
+	var results []upstream.SearchResult
+	var errs []error
+	for _, remote := range remotes {
+		refs := list[remote]
+		if msg, ok := refs["error"]; ok {
+			var s string
+			json.Unmarshal(msg, &s)
+			errs = append(errs, fmt.Errorf("conan list: remote %s: %s", remote, s))
+			continue
+		}
+		for ref, raw := range refs {
+			result := upstream.ParseSearchResult(ref)
+			if result.Name != name {
+				continue
+			}
+			var recipe listRecipe
+			if err := json.Unmarshal(raw, &recipe); err != nil {
+				return nil, fmt.Errorf("conan list: %s: %w", ref, err)
+			}
+			// #latest leaves a single revision
+			for rrev := range recipe.Revisions {
+				result.Revision = rrev
+			}
+			result.Remote = remote
+			results = append(results, result)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// Search lists the versions of pkg with their latest recipe revision in the configured remotes,
+// or conancenter if no remote is configured.
+// Versions are sorted from the oldest to the latest, a version found in several remotes is reported once per remote.
+// An error wrapping upstream.ErrPackageNotFound is returned if no remote has the package.
+func (c *conanInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	// Build the following command
+	// conan list %s/*#latest --format=json --remote=conancenter
+	builder := cmdbuilder.NewCmdBuilder(cmdbuilder.WithConanSerializer())
+
+	builder.SetName("conan")
+	builder.SetSubcommand("list")
+	builder.SetObj(pkg.Name + "/*#latest")
+	builder.SetArg("format", "json")
+
+	remotes := c.remotes()
+	if len(remotes) == 0 {
+		remotes = []Remote{{Name: DefaultRemote}}
+	}
+	var names []string
+	for _, remote := range remotes {
+		builder.SetArg("remote", remote.Name)
+		names = append(names, remote.Name)
+	}
+
+	cmd := c.command(builder.CmdContext(ctx))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("conan list: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	results, err := parseList(out, pkg.Name, names)
+	// results of the remaining remotes are still usable if some remotes fail.
+	if len(results) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", upstream.ErrPackageNotFound, pkg.Name)
+	}
+	upstream.SortSearchResults(results)
+	return results, nil
+}
@@ -0,0 +1,52 @@
+package conan
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+func TestConanSearchList(t *testing.T) {
+	list, err := filepath.Abs("testdata/list.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setupFakeConan(t, `[ "$*" = "list cjson/*#latest --format=json --remote=conancenter --remote=internal --remote=offline" ] || { echo "unexpected command: $*" >&2; exit 1; }
+cat "`+list+`"
+`)
+
+	c := &conanInstaller{config: map[string]string{"remotes": "conancenter internal offline"}}
+	results, err := c.Search(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []upstream.SearchResult{
+		{Name: "cjson", Version: "1.7.9", Revision: "1c7d2b0e8a6b4e2f9d3c5a7b9e1f3d5c", Remote: "conancenter"},
+		{Name: "cjson", Version: "1.7.16", Revision: "e2b4bd6d17e4ab3e8c0a1b5f4d3c9e2a", Remote: "conancenter"},
+		{Name: "cjson", Version: "1.7.18", Revision: "6bd3d0a4b1b9ab2a9d6c8c5e0b5e6b9a", Remote: "conancenter"},
+		{Name: "cjson", Version: "1.7.18", Revision: "0a1b2c3d4e5f60718293a4b5c6d7e8f9", Remote: "internal"},
+	}
+	if !slices.Equal(results, expected) {
+		t.Errorf("unexpected results:\nwant %v\ngot  %v", expected, results)
+	}
+}
+
+func TestConanSearchNotFound(t *testing.T) {
+	setupFakeConan(t, `echo '{"conancenter": {}}'`)
+	c := &conanInstaller{config: map[string]string{}}
+	_, err := c.Search(context.Background(), upstream.Package{Name: "cjson2", Version: "1.7.18"})
+	if !errors.Is(err, upstream.ErrPackageNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// remote errors aren't reported as a missing package
+	setupFakeConan(t, `echo '{"conancenter": {"error": "connection refused"}}'`)
+	_, err = c.Search(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"})
+	if err == nil || errors.Is(err, upstream.ErrPackageNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
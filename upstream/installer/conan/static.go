@@ -0,0 +1,168 @@
+package conan
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/actions/file"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// linkOrder returns the transitive host dependencies of node in link order,
+// i.e. every package before the packages it depends on.
+func (g *Graph) linkOrder(node *Node) []*Node {
+	var order []*Node
+	visited := map[*Node]bool{node: true}
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		for _, id := range g.sortedEdges(n, true) {
+			dep := g.Nodes[id]
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			visit(dep)
+			order = append(order, dep)
+		}
+	}
+	visit(node)
+	slices.Reverse(order)
+	// conan lists the transitive dependencies of node as well, keep those no direct edge leads to.
+	for _, id := range g.sortedEdges(node, false) {
+		if dep := g.Nodes[id]; !visited[dep] {
+			visited[dep] = true
+			order = append(order, dep)
+		}
+	}
+	return order
+}
+
+// cppInfos returns the root cpp_info of n followed by those of its components sorted by name.
+func (n *Node) cppInfos() []*CppInfo {
+	var names []string
+	for name := range n.CppInfo {
+		if name != "root" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	infos := []*CppInfo{n.CppInfo["root"]}
+	for _, name := range names {
+		infos = append(infos, n.CppInfo[name])
+	}
+	return slices.DeleteFunc(infos, func(info *CppInfo) bool { return info == nil })
+}
+
+// libDirs returns the absolute library directories of n, lib in its package folder by default.
+func (n *Node) libDirs() []string {
+	var dirs []string
+	for _, info := range n.cppInfos() {
+		for _, dir := range info.LibDirs {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(n.PackageFolder, dir)
+			}
+			if !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		dirs = []string{filepath.Join(n.PackageFolder, "lib")}
+	}
+	return dirs
+}
+
+// bundleStaticDependencies makes the static package installed into outputDir self-contained:
+// the archives of the host dependencies of node are copied into the lib directory of outputDir,
+// and their libraries and system libraries are appended to Libs.private of pcFile in link order,
+// so that pkg-config --static --libs links them.
+// A dependency shipping a shared library fails with upstream.ErrLinkageMismatch,
+// the release would silently depend on it otherwise.
+func bundleStaticDependencies(graph *Graph, node *Node, outputDir, pcFile string) error {
+	libDir := filepath.Join(outputDir, "lib")
+	var libs, systemLibs []string
+	for _, dep := range graph.linkOrder(node) {
+		if dep.PackageFolder == "" {
+			continue
+		}
+		for _, dir := range dep.libDirs() {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			for _, entry := range entries {
+				switch name := entry.Name(); {
+				case entry.IsDir():
+				case upstream.IsSharedLibrary(name):
+					return fmt.Errorf("%w %s: dependency %s ships the shared library %s",
+						upstream.ErrLinkageMismatch, upstream.LinkageStatic, dep.Package().Name, name)
+				case upstream.IsArchive(name):
+					if err := os.MkdirAll(libDir, 0755); err != nil {
+						return err
+					}
+					if err := file.CopyFile(filepath.Join(dir, name), filepath.Join(libDir, name)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		for _, info := range dep.cppInfos() {
+			libs = append(libs, info.Libs...)
+			systemLibs = append(systemLibs, info.SystemLibs...)
+		}
+	}
+	var flags []string
+	for _, lib := range slices.Concat(libs, systemLibs) {
+		if flag := "-l" + lib; !slices.Contains(flags, flag) {
+			flags = append(flags, flag)
+		}
+	}
+	if len(flags) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(pcFile)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pcFile, appendLibsPrivate(content, flags), 0644)
+}
+
+var (
+	libsPrivateLine = regexp.MustCompile(`(?m)^Libs\.private:(.*)$`)
+	libsLine        = regexp.MustCompile(`(?m)^Libs:.*$`)
+)
+
+// appendLibsPrivate appends the flags Libs.private of the .pc file content doesn't have yet to it,
+// adding the field after Libs if there's none.
+func appendLibsPrivate(content []byte, flags []string) []byte {
+	if m := libsPrivateLine.FindSubmatchIndex(content); m != nil {
+		existing := strings.Fields(string(content[m[2]:m[3]]))
+		var added []string
+		for _, flag := range flags {
+			if !slices.Contains(existing, flag) {
+				added = append(added, flag)
+			}
+		}
+		if len(added) == 0 {
+			return content
+		}
+		field := bytes.TrimRight(content[m[0]:m[1]], " \t\r")
+		return slices.Concat(content[:m[0]], field, []byte(" "+strings.Join(added, " ")), content[m[1]:])
+	}
+	field := []byte("Libs.private: " + strings.Join(flags, " "))
+	if m := libsLine.FindIndex(content); m != nil {
+		return slices.Concat(content[:m[1]], []byte("\n"), field, content[m[1]:])
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+	return slices.Concat(content, field, []byte("\n"))
+}
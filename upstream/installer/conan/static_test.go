@@ -0,0 +1,201 @@
+package conan
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/internal/actions/file"
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// fakeStaticConan emulates `conan install` of cjson, depending on zlib, as static libraries:
+// the package folders ($FAKE_CONAN_PACKAGE and $FAKE_CONAN_PACKAGE-zlib) hold an archive,
+// the generated .pc files point to them and cjson.pc carries the system libraries in Libs.private.
+// The arguments are logged to $FAKE_CONAN_LOG.
+const fakeStaticConan = `[ "$1" = "install" ] || { echo "unexpected command: $*" >&2; exit 1; }
+echo "$*" > "$FAKE_CONAN_LOG"
+for arg; do
+	case "$arg" in
+	--output-folder=*) out="${arg#*=}" ;;
+	esac
+done
+pkg="$FAKE_CONAN_PACKAGE"
+shared=True
+case "$*" in
+*"--options=*:shared=False"*) shared=False ;;
+esac
+zpkg="$pkg-zlib"
+mkdir -p "$out" "$pkg/include/cjson" "$pkg/lib" "$pkg/licenses" "$zpkg/lib"
+echo "MIT" > "$pkg/licenses/LICENSE"
+echo "int cJSON_Version(void);" > "$pkg/include/cjson/cJSON.h"
+if [ "$shared" = True ]; then
+	echo "ELF" > "$pkg/lib/libcjson.so"
+	echo "ELF" > "$zpkg/lib/libz.so"
+else
+	echo "!<arch>" > "$pkg/lib/libcjson.a"
+	echo "!<arch>" > "$zpkg/lib/libz.a"
+fi
+printf 'prefix=%s\nlibdir=${prefix}/lib\nincludedir=${prefix}/include\n\nName: cjson\nDescription: Conan package cjson\nVersion: 1.7.18\nRequires: zlib\nLibs: -L"${libdir}" -lcjson\nLibs.private: -lm\nCflags: -I"${includedir}"\n' "$pkg" > "$out/cjson.pc"
+printf 'prefix=%s\nlibdir=${prefix}/lib\n\nName: zlib\nDescription: Conan package zlib\nVersion: 1.3.1\nLibs: -L"${libdir}" -lz\n' "$zpkg" > "$out/zlib.pc"
+echo "#!/bin/sh" > "$out/conanrun.sh"
+cat <<JSON
+{"graph": {"nodes": {
+	"0": {"ref": "conanfile", "context": "host", "dependencies": {
+		"1": {"ref": "cjson/1.7.18", "direct": true, "build": false},
+		"2": {"ref": "zlib/1.3.1", "direct": false, "build": false}}},
+	"1": {"ref": "cjson/1.7.18#6bd3d0a4b1b9ab2a9d6c8c5e0b5e6b9a", "name": "cjson", "version": "1.7.18",
+		"rrev": "6bd3d0a4b1b9ab2a9d6c8c5e0b5e6b9a", "context": "host", "package_folder": "$pkg",
+		"options": {"shared": "$shared"}, "cpp_info": {"root": {"libs": ["cjson"], "system_libs": ["m"]}},
+		"dependencies": {"2": {"ref": "zlib/1.3.1", "direct": true, "build": false}}},
+	"2": {"ref": "zlib/1.3.1#b3b71bfe8dd07abc7b82ff2bd0eac021", "name": "zlib", "version": "1.3.1",
+		"context": "host", "package_folder": "$zpkg",
+		"options": {"shared": "$shared"}, "cpp_info": {"root": {"libs": ["z"]}}}
+}}}
+JSON
+`
+
+func TestConanInstallStatic(t *testing.T) {
+	setupFakeConan(t, fakeStaticConan)
+	log := filepath.Join(t.TempDir(), "conan.log")
+	t.Setenv("FAKE_CONAN_LOG", log)
+	t.Setenv("FAKE_CONAN_PACKAGE", filepath.Join(t.TempDir(), "p"))
+
+	c := &conanInstaller{config: map[string]string{}}
+	pkg := upstream.Package{Name: "cjson", Version: "1.7.18", Linkage: upstream.LinkageStatic}
+	outputDir := t.TempDir()
+	result, err := c.Install(context.Background(), pkg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := os.ReadFile(log)
+	if !strings.Contains(string(b), "--options=*:shared=False") || strings.Contains(string(b), "shared=True") {
+		t.Errorf("unexpected command: %s", string(b))
+	}
+	if err := result.CheckLinkage(pkg.Linkage); err != nil {
+		t.Error(err)
+	}
+	// the archives of the dependencies are bundled, and linked statically
+	for _, archive := range []string{"libcjson.a", "libz.a"} {
+		if _, err := os.Stat(filepath.Join(outputDir, "lib", archive)); err != nil {
+			t.Errorf("archive not installed: %v", err)
+		}
+	}
+
+	// the template released with the llpkg keeps the static linker flags
+	templateDir := t.TempDir()
+	if err := pc.GenerateTemplateFromPC(result.PCFile(), templateDir); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _ := os.ReadFile(filepath.Join(templateDir, "cjson.pc"+pc.PCTemplateSuffix))
+	if !strings.HasPrefix(string(tmpl), "prefix={{.Prefix}}\n") ||
+		!strings.Contains(string(tmpl), "Libs: -L\"${libdir}\" -lcjson\n") ||
+		!strings.Contains(string(tmpl), "Libs.private: -lm -lz\n") {
+		t.Errorf("unexpected template: %s", string(tmpl))
+	}
+
+	// and released along with the package
+	zipPath := filepath.Join(t.TempDir(), "cjson.zip")
+	if err := file.Zip(outputDir, zipPath); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, filepath.ToSlash(f.Name))
+	}
+	for _, archive := range []string{"lib/libcjson.a", "lib/libz.a"} {
+		if !slices.Contains(names, archive) {
+			t.Errorf("%s not released: %v", archive, names)
+		}
+	}
+}
+
+func TestConanInstallStaticSharedDependency(t *testing.T) {
+	setupFakeConan(t, fakeStaticConan)
+	t.Setenv("FAKE_CONAN_LOG", filepath.Join(t.TempDir(), "conan.log"))
+	pkgDir := filepath.Join(t.TempDir(), "p")
+	t.Setenv("FAKE_CONAN_PACKAGE", pkgDir)
+	// a dependency which ignores *:shared=False
+	os.MkdirAll(pkgDir+"-zlib/lib", 0755)
+	os.WriteFile(pkgDir+"-zlib/lib/libz.so", []byte("ELF"), 0644)
+
+	c := &conanInstaller{config: map[string]string{}}
+	pkg := upstream.Package{Name: "cjson", Version: "1.7.18", Linkage: upstream.LinkageStatic}
+	if _, err := c.Install(context.Background(), pkg, t.TempDir()); !errors.Is(err, upstream.ErrLinkageMismatch) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAppendLibsPrivate(t *testing.T) {
+	for _, tc := range []struct {
+		name, content, want string
+	}{
+		{"existing", "Libs: -lcjson\nLibs.private: -lm \nCflags: -I.\n", "Libs: -lcjson\nLibs.private: -lm -lz\nCflags: -I.\n"},
+		{"up to date", "Libs: -lcjson\nLibs.private: -lz -lm\n", "Libs: -lcjson\nLibs.private: -lz -lm\n"},
+		{"missing", "Libs: -lcjson\nCflags: -I.\n", "Libs: -lcjson\nLibs.private: -lz -lm\nCflags: -I.\n"},
+		{"no libs", "Name: cjson", "Name: cjson\nLibs.private: -lz -lm\n"},
+	} {
+		if got := string(appendLibsPrivate([]byte(tc.content), []string{"-lz", "-lm"})); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestConanInstallShared(t *testing.T) {
+	setupFakeConan(t, fakeStaticConan)
+	t.Setenv("FAKE_CONAN_LOG", filepath.Join(t.TempDir(), "conan.log"))
+	t.Setenv("FAKE_CONAN_PACKAGE", filepath.Join(t.TempDir(), "p"))
+
+	// shared libraries pass the shared linkage check only
+	c := &conanInstaller{config: map[string]string{}}
+	result, err := c.Install(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := result.CheckLinkage(upstream.LinkageShared); err != nil {
+		t.Error(err)
+	}
+	if err := result.CheckLinkage(upstream.LinkageStatic); err == nil {
+		t.Error("unexpected success for shared libraries")
+	}
+}
+
+func TestConanInstallIntoLLPkgDir(t *testing.T) {
+	setupFakeConan(t, fakeStaticConan)
+	t.Setenv("FAKE_CONAN_LOG", filepath.Join(t.TempDir(), "conan.log"))
+	t.Setenv("FAKE_CONAN_PACKAGE", filepath.Join(t.TempDir(), "p"))
+
+	// the files of the llpkg are neither generated by conan nor licenses of the package
+	outputDir := t.TempDir()
+	for _, name := range []string{"llpkg.cfg", "go.mod", "cjson.go", "LICENSE"} {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &conanInstaller{config: map[string]string{}}
+	result, err := c.Install(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"}, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(outputDir, "conanrun.sh")}; !slices.Equal(result.GeneratedFiles, want) {
+		t.Errorf("unexpected generated files: %v, want %v", result.GeneratedFiles, want)
+	}
+	if want := []string{filepath.Join(outputDir, "cjson.pc"), filepath.Join(outputDir, "zlib.pc")}; !slices.Equal(result.PCFiles, want) {
+		t.Errorf("unexpected .pc files: %v, want %v", result.PCFiles, want)
+	}
+	if want := []string{filepath.Join(outputDir, "licenses", "LICENSE")}; !slices.Equal(result.Licenses, want) {
+		t.Errorf("unexpected licenses: %v, want %v", result.Licenses, want)
+	}
+}
@@ -0,0 +1,133 @@
+// Package layout normalizes an installed prefix into the output layout shared by all installers.
+//
+// The conan installer leaves every .pc file in the root of the output directory,
+// next to the copied include/lib/bin directories. The llcppg generator, `llpkgstore generate`
+// and pc.GenerateTemplateFromPC all rely on that layout, so other installers use this
+// package to produce the same one.
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/actions/file"
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// pcDirs lists the directories (relative to a prefix) pkg-config files are usually installed in.
+var pcDirs = []string{
+	filepath.Join("lib", "pkgconfig"),
+	filepath.Join("lib64", "pkgconfig"),
+	filepath.Join("share", "pkgconfig"),
+}
+
+// Install copies prefixDir into outputDir (unless they are the same directory)
+// and exports every .pc file found in the usual pkg-config directories to the
+// root of outputDir, with its prefix rewritten to outputDir.
+//
+// It returns the names (without .pc suffix) of the exported files.
+func Install(prefixDir, outputDir string) ([]string, error) {
+	outputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	prefixDir, err = filepath.Abs(prefixDir)
+	if err != nil {
+		return nil, err
+	}
+	if prefixDir != outputDir {
+		if err := file.CopyFS(outputDir, os.DirFS(prefixDir), false); err != nil {
+			return nil, err
+		}
+	}
+	var names []string
+	for _, dir := range pcDirs {
+		matches, _ := filepath.Glob(filepath.Join(outputDir, dir, "*.pc"))
+		for _, match := range matches {
+			content, err := os.ReadFile(match)
+			if err != nil {
+				return nil, err
+			}
+			name := filepath.Base(match)
+			err = os.WriteFile(filepath.Join(outputDir, name), pc.Relocate(content, outputDir, dir), 0644)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name[:len(name)-len(".pc")])
+		}
+	}
+	return names, nil
+}
+
+// PkgConfigName returns the first candidate which has a .pc file in the root of outputDir.
+func PkgConfigName(outputDir string, candidates ...string) (string, bool) {
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, name+".pc")); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// libExts lists the file extensions of libraries we can link against.
+var libExts = []string{".so", ".dylib", ".a", ".lib"}
+
+// Libraries returns the sorted, unique library names (without lib prefix and extension)
+// found in the lib directory of outputDir, e.g. lib/libcjson.so.1 => cjson.
+// If static is set, only archives are considered.
+func Libraries(outputDir string, static bool) []string {
+	entries, _ := os.ReadDir(filepath.Join(outputDir, "lib"))
+
+	var libs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if static && !upstream.IsArchive(name) {
+			continue
+		}
+		for _, ext := range libExts {
+			// versioned shared libraries: libcjson.so.1.7.18
+			base, _, found := strings.Cut(name, ext)
+			if !found {
+				continue
+			}
+			rest := name[len(base)+len(ext):]
+			if rest != "" && rest[0] != '.' {
+				continue
+			}
+			// versioned dylibs: libcjson.1.dylib
+			base, _, _ = strings.Cut(strings.TrimPrefix(base, "lib"), ".")
+			if base != "" && !slices.Contains(libs, base) {
+				libs = append(libs, base)
+			}
+			break
+		}
+	}
+	slices.Sort(libs)
+	return libs
+}
+
+// Synthesize writes <f.Name>.pc to the root of outputDir for prefixes which don't ship one.
+// When f.Libs is empty, all libraries found in the lib directory are linked,
+// only archives if static is set.
+func Synthesize(outputDir string, f pc.File, static bool) error {
+	outputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return err
+	}
+	if len(f.Libs) == 0 {
+		f.Libs = Libraries(outputDir, static)
+	}
+	if f.Description == "" {
+		f.Description = f.Name
+	}
+	return os.WriteFile(filepath.Join(outputDir, f.Name+".pc"), pc.Generate(f, outputDir), 0644)
+}
@@ -0,0 +1,187 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/actions/file"
+	"github.com/goplus/llpkgstore/internal/actions/hashutils"
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/goplus/llpkgstore/upstream/installer/internal/layout"
+)
+
+var (
+	// ErrPackageNotFound is upstream.ErrPackageNotFound.
+	//
+	// Deprecated: use upstream.ErrPackageNotFound.
+	ErrPackageNotFound  = upstream.ErrPackageNotFound
+	ErrMissingPath      = errors.New("local: config.path must be specified")
+	ErrMissingChecksum  = errors.New("local: config.sha256 must be specified for archives")
+	ErrChecksumMismatch = hashutils.ErrChecksumMismatch
+)
+
+func init() {
+	upstream.Register("local", NewLocalInstaller)
+}
+
+// isArchive reports whether path is a gzip-compressed tarball.
+func isArchive(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// localInstaller implements the upstream.Installer interface for prebuilt libraries,
+// which are provided as an installed prefix directory or as a tarball of it.
+type localInstaller struct {
+	config map[string]string
+}
+
+// NewLocalInstaller creates a new local installer instance with provided configuration options.
+// Supported config keys:
+//   - "path": prefix directory or .tar.gz archive of it (required),
+//     a relative path is resolved against the directory of llpkg.cfg by config.ParseLLPkgConfig.
+//   - "sha256": hex-encoded sha256 of the archive, required when path is an archive.
+//   - "strip_components": number of leading path components to strip from archive entries.
+//   - "pkg_config_name": name of the .pc file, defaults to the package name.
+//   - "libs": space-separated libraries to link in a synthesized .pc file, defaults to all found in lib.
+//   - "libs_private": space-separated extra linker flags of a synthesized .pc file
+//     only needed for static linking, e.g. "-lm -lpthread".
+//   - "cflags": extra compiler flags of a synthesized .pc file.
+func NewLocalInstaller(config map[string]string) upstream.Installer {
+	return &localInstaller{
+		config: config,
+	}
+}
+
+func (l *localInstaller) Name() string {
+	return "local"
+}
+
+func (l *localInstaller) Config() map[string]string {
+	return l.config
+}
+
+// ConfigKeys implements upstream.ConfigDescriber.
+func (l *localInstaller) ConfigKeys() []upstream.ConfigKey {
+	return []upstream.ConfigKey{
+		{Name: "path", Description: "prefix directory or .tar.gz archive of it, relative to llpkg.cfg", Required: true, Path: true},
+		{Name: "sha256", Description: "hex-encoded sha256 of the archive, required for archives"},
+		{Name: "strip_components", Description: "number of leading path components to strip from archive entries"},
+		{Name: "pkg_config_name", Description: "name of the .pc file, defaults to the package name"},
+		{Name: "libs", Description: "space-separated libraries to link in a synthesized .pc file"},
+		{Name: "libs_private", Description: "space-separated extra linker flags of a synthesized .pc file only needed for static linking"},
+		{Name: "cflags", Description: "extra compiler flags of a synthesized .pc file"},
+	}
+}
+
+// ValidateConfig implements upstream.ConfigValidator, the required keys are checked by ConfigKeys.
+func (l *localInstaller) ValidateConfig(pkg upstream.Package) error {
+	if isArchive(l.config["path"]) && l.config["sha256"] == "" {
+		return ErrMissingChecksum
+	}
+	if s := l.config["strip_components"]; s != "" {
+		if n, err := strconv.Atoi(s); err != nil || n < 0 {
+			return fmt.Errorf("local: invalid strip_components: %s", s)
+		}
+	}
+	return nil
+}
+
+// Volatile reports whether the installed files can change without the config changing,
+// which is the case for prefix directories but not for archives pinned by their sha256.
+// It implements cache.Volatile.
+func (l *localInstaller) Volatile() bool {
+	return !isArchive(l.config["path"])
+}
+
+// verify checks the archive against the configured sha256.
+func (l *localInstaller) verify(archive string) error {
+	expected := l.config["sha256"]
+	if expected == "" {
+		return ErrMissingChecksum
+	}
+	return hashutils.Verify(archive, expected)
+}
+
+// extract verifies and extracts archive into outputDir.
+func (l *localInstaller) extract(archive, outputDir string) error {
+	if err := l.verify(archive); err != nil {
+		return err
+	}
+	strip := 0
+	if s := l.config["strip_components"]; s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return fmt.Errorf("local: invalid strip_components: %s", s)
+		}
+		strip = n
+	}
+	return file.ExtractTarGz(archive, outputDir, strip)
+}
+
+// Install copies or extracts the configured prefix into outputDir.
+// .pc files shipped in the prefix are exported to the root of outputDir like the conan installer does,
+// if there isn't one for the package, it's synthesized from the include and lib directories.
+func (l *localInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path := l.config["path"]
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+	fs, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", upstream.ErrPackageNotFound, err)
+	}
+
+	prefix := path
+	if !fs.IsDir() {
+		if !isArchive(path) {
+			return nil, fmt.Errorf("local: unsupported file type: %s", path)
+		}
+		if err := l.extract(path, outputDir); err != nil {
+			return nil, err
+		}
+		prefix = outputDir
+	}
+	if _, err := layout.Install(prefix, outputDir); err != nil {
+		return nil, err
+	}
+
+	pkgConfigName := l.config["pkg_config_name"]
+	if pkgConfigName == "" {
+		pkgConfigName = pkg.Name
+	}
+	if _, ok := layout.PkgConfigName(outputDir, pkgConfigName); ok {
+		return upstream.ScanInstallResult(pkg, pkgConfigName, outputDir)
+	}
+	err = layout.Synthesize(outputDir, pc.File{
+		Name:        pkgConfigName,
+		Version:     pkg.Version,
+		Libs:        strings.Fields(l.config["libs"]),
+		LibsPrivate: strings.Fields(l.config["libs_private"]),
+		Cflags:      strings.Fields(l.config["cflags"]),
+	}, pkg.Static())
+	if err != nil {
+		return nil, err
+	}
+	return upstream.ScanInstallResult(pkg, pkgConfigName, outputDir)
+}
+
+// Search reports the configured package as the only available version if its path exists.
+func (l *localInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	path := l.config["path"]
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+	if _, err := os.Stat(filepath.Clean(path)); err != nil {
+		return nil, upstream.ErrPackageNotFound
+	}
+	return []upstream.SearchResult{{Name: pkg.Name, Version: pkg.Version}}, nil
+}
@@ -0,0 +1,211 @@
+package local
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+var testPkg = upstream.Package{
+	Name:    "cjson",
+	Version: "1.7.18",
+}
+
+// writeTarGz writes files into a gzip-compressed tarball and returns its sha256.
+func writeTarGz(t *testing.T, fileName string, files map[string]string) string {
+	f, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.New()
+	gw := gzip.NewWriter(io.MultiWriter(f, h))
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gw.Close()
+	f.Close()
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func checkPC(t *testing.T, outputDir, pkgConfigName string) string {
+	b, err := os.ReadFile(filepath.Join(outputDir, pkgConfigName+".pc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := pc.PrefixMatch.FindSubmatch(b); len(m) != 2 || string(m[1]) != outputDir {
+		t.Errorf("unexpected prefix: %s", string(b))
+	}
+	if _, err := exec.LookPath("pkg-config"); err == nil {
+		cmd := exec.Command("pkg-config", "--libs", "--cflags", pkgConfigName)
+		pc.SetPath(cmd, outputDir)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("pkg-config failed: %s %s", err, string(out))
+		}
+	}
+	return string(b)
+}
+
+func TestLocalInstallDir(t *testing.T) {
+	prefix := t.TempDir()
+	os.MkdirAll(filepath.Join(prefix, "include", "cjson"), 0777)
+	os.MkdirAll(filepath.Join(prefix, "lib", "pkgconfig"), 0777)
+	os.WriteFile(filepath.Join(prefix, "include", "cjson", "cJSON.h"), []byte("int cJSON_Version(void);"), 0644)
+	os.WriteFile(filepath.Join(prefix, "lib", "libcjson.so"), []byte("ELF"), 0644)
+	os.WriteFile(filepath.Join(prefix, "lib", "pkgconfig", "cjson.pc"), []byte(`prefix=/usr/local
+libdir=${prefix}/lib
+includedir=${prefix}/include
+
+Name: cjson
+Description: shipped
+Version: 1.7.18
+Libs: -L${libdir} -lcjson
+Cflags: -I${includedir}
+`), 0644)
+
+	l := NewLocalInstaller(map[string]string{"path": prefix})
+	if l.Name() != "local" {
+		t.Errorf("unexpected name: %s", l.Name())
+	}
+	outputDir := t.TempDir()
+	result, err := l.Install(context.Background(), testPkg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := result.PkgConfigName
+	if name != "cjson" {
+		t.Errorf("unexpected pkg-config name: %s", name)
+	}
+	if content := checkPC(t, outputDir, name); !strings.Contains(content, "Description: shipped") {
+		t.Errorf("shipped pc file not used: %s", content)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "include", "cjson", "cJSON.h")); err != nil {
+		t.Error(err)
+	}
+	if result.PCFile() != filepath.Join(outputDir, "cjson.pc") || !slices.Equal(result.LibDirs, []string{filepath.Join(outputDir, "lib")}) {
+		t.Errorf("unexpected install result: %+v", result)
+	}
+}
+
+func TestLocalInstallArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "cjson-1.7.18.tar.gz")
+	sum := writeTarGz(t, archive, map[string]string{
+		"cjson-1.7.18/include/cjson/cJSON.h": "int cJSON_Version(void);",
+		"cjson-1.7.18/lib/libcjson.so.1":     "ELF",
+		"cjson-1.7.18/lib/libcjson_utils.a":  "!<arch>",
+	})
+
+	l := NewLocalInstaller(map[string]string{
+		"path":             archive,
+		"sha256":           sum,
+		"strip_components": "1",
+	})
+	outputDir := t.TempDir()
+	result, err := l.Install(context.Background(), testPkg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := result.PkgConfigName
+	// no pc file ships with the archive, it must be synthesized
+	content := checkPC(t, outputDir, name)
+	if !strings.Contains(content, `Libs: -L"${libdir}" -lcjson -lcjson_utils`) ||
+		!strings.Contains(content, "Version: 1.7.18") {
+		t.Errorf("unexpected synthesized pc file: %s", content)
+	}
+
+	// generated template must keep the prefix placeholder at the first line
+	templateDir := t.TempDir()
+	if err := pc.GenerateTemplateFromPC(filepath.Join(outputDir, name+".pc"), templateDir); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _ := os.ReadFile(filepath.Join(templateDir, name+".pc"+pc.PCTemplateSuffix))
+	if !strings.HasPrefix(string(tmpl), "prefix={{.Prefix}}\n") {
+		t.Errorf("unexpected template: %s", string(tmpl))
+	}
+}
+
+func TestLocalInstallStatic(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "cjson-1.7.18.tar.gz")
+	sum := writeTarGz(t, archive, map[string]string{
+		"include/cjson/cJSON.h": "int cJSON_Version(void);",
+		"lib/libcjson.so.1":     "ELF",
+		"lib/libcjson_utils.a":  "!<arch>",
+	})
+
+	l := NewLocalInstaller(map[string]string{
+		"path":         archive,
+		"sha256":       sum,
+		"libs_private": "-lm",
+	})
+	pkg := testPkg
+	pkg.Linkage = upstream.LinkageStatic
+	outputDir := t.TempDir()
+	result, err := l.Install(context.Background(), pkg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only archives are linked statically
+	content := checkPC(t, outputDir, result.PkgConfigName)
+	if !strings.Contains(content, "Libs: -L\"${libdir}\" -lcjson_utils\n") ||
+		!strings.Contains(content, "Libs.private: -lm\n") {
+		t.Errorf("unexpected synthesized pc file: %s", content)
+	}
+	// the shared library shipped in the archive must be caught before release
+	if err := result.CheckLinkage(pkg.Linkage); !errors.Is(err, upstream.ErrLinkageMismatch) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	templateDir := t.TempDir()
+	if err := pc.GenerateTemplateFromPC(result.PCFile(), templateDir); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _ := os.ReadFile(filepath.Join(templateDir, result.PkgConfigName+".pc"+pc.PCTemplateSuffix))
+	if !strings.Contains(string(tmpl), "Libs.private: -lm\n") {
+		t.Errorf("unexpected template: %s", string(tmpl))
+	}
+}
+
+func TestLocalChecksum(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "cjson.tar.gz")
+	writeTarGz(t, archive, map[string]string{"include/cJSON.h": ""})
+
+	_, err := NewLocalInstaller(map[string]string{"path": archive}).Install(context.Background(), testPkg, t.TempDir())
+	if !errors.Is(err, ErrMissingChecksum) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = NewLocalInstaller(map[string]string{
+		"path":   archive,
+		"sha256": strings.Repeat("0", 64),
+	}).Install(context.Background(), testPkg, t.TempDir())
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLocalSearch(t *testing.T) {
+	l := NewLocalInstaller(map[string]string{"path": t.TempDir()})
+	ret, err := l.Search(context.Background(), testPkg)
+	if err != nil || !slices.Equal(ret, []upstream.SearchResult{{Name: "cjson", Version: "1.7.18"}}) {
+		t.Errorf("unexpected search result: %v %v", ret, err)
+	}
+	l = NewLocalInstaller(map[string]string{"path": filepath.Join(t.TempDir(), "not-exist")})
+	if _, err := l.Search(context.Background(), testPkg); !errors.Is(err, upstream.ErrPackageNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Command llpkgstore-installer-example is the reference installer plugin.
+//
+// It serves prebuilt packages from a directory tree laid out as <root>/<name>/<version>,
+// where every version directory is an installed prefix (include, lib, lib/pkgconfig...).
+// The root is taken from the "root" config key of llpkg.cfg.
+//
+// Install it on PATH and use it with:
+//
+//	"installer": {"name": "example", "config": {"root": "/path/to/prefixes"}}
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/goplus/llpkgstore/upstream/installer/internal/layout"
+	"github.com/goplus/llpkgstore/upstream/installer/plugin"
+)
+
+type exampleInstaller struct {
+	config map[string]string
+}
+
+func (e *exampleInstaller) Name() string {
+	return "example"
+}
+
+func (e *exampleInstaller) Config() map[string]string {
+	return e.config
+}
+
+func (e *exampleInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	prefix := filepath.Join(e.config["root"], pkg.Name, pkg.Version)
+	if fs, err := os.Stat(prefix); err != nil || !fs.IsDir() {
+		return nil, fmt.Errorf("%w: %s/%s", upstream.ErrPackageNotFound, pkg.Name, pkg.Version)
+	}
+	if _, err := layout.Install(prefix, outputDir); err != nil {
+		return nil, err
+	}
+	name, ok := layout.PkgConfigName(outputDir, pkg.Name, "lib"+pkg.Name)
+	if !ok {
+		return nil, upstream.ErrPCFileNotFound
+	}
+	return upstream.ScanInstallResult(pkg, name, outputDir)
+}
+
+func (e *exampleInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	entries, err := os.ReadDir(filepath.Join(e.config["root"], pkg.Name))
+	if err != nil {
+		return nil, upstream.ErrPackageNotFound
+	}
+	var ret []upstream.SearchResult
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ret = append(ret, upstream.SearchResult{Name: pkg.Name, Version: entry.Name()})
+		}
+	}
+	upstream.SortSearchResults(ret)
+	return ret, nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := plugin.Serve(ctx, os.Stdin, os.Stdout, func(config map[string]string) upstream.Installer {
+		return &exampleInstaller{config: config}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,211 @@
+// Package plugin implements upstream.Installer on top of external executables.
+//
+// An installer named <name> which isn't linked into llpkgstore is looked up as an executable
+// named llpkgstore-installer-<name> on PATH. For every operation, the plugin is started once,
+// it reads a Request from stdin and writes a Response to stdout; stderr is passed through
+// for progress output. Plugins written in Go can use Serve to implement the protocol.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/cmdbuilder"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// ExecutablePrefix is the file name prefix of installer plugins.
+const ExecutablePrefix = "llpkgstore-installer-"
+
+// pluginInstaller implements the upstream.Installer interface by delegating to an external executable.
+type pluginInstaller struct {
+	name   string
+	path   string
+	config map[string]string
+}
+
+// Lookup searches PATH for the plugin of the installer named name,
+// and returns a factory creating installers backed by it.
+func Lookup(name string) (upstream.Factory, bool) {
+	if name == "" {
+		return nil, false
+	}
+	path, err := exec.LookPath(ExecutablePrefix + name)
+	if err != nil {
+		return nil, false
+	}
+	return func(config map[string]string) upstream.Installer {
+		return NewPluginInstaller(name, path, config)
+	}, true
+}
+
+// List returns the sorted names of all installer plugins found on PATH.
+func List() []string {
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, ExecutablePrefix+"*"))
+		for _, match := range matches {
+			name := strings.TrimPrefix(filepath.Base(match), ExecutablePrefix)
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			if _, ok := Lookup(name); ok && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewPluginInstaller creates an installer named name backed by the plugin executable at path.
+func NewPluginInstaller(name, path string, config map[string]string) upstream.Installer {
+	return &pluginInstaller{
+		name:   name,
+		path:   path,
+		config: config,
+	}
+}
+
+func (p *pluginInstaller) Name() string {
+	return p.name
+}
+
+func (p *pluginInstaller) Config() map[string]string {
+	return p.config
+}
+
+// call runs the plugin with req and decodes its response.
+func (p *pluginInstaller) call(ctx context.Context, req Request) (*Response, error) {
+	req.ProtocolVersion = ProtocolVersion
+	req.Config = p.config
+
+	in, err := json.Marshal(&req)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+
+	cmd := cmdbuilder.CommandContext(ctx, p.path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var resp Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		// the plugin crashed before it could reply.
+		if runErr != nil {
+			return nil, fmt.Errorf("plugin %s: %w", p.name, runErr)
+		}
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidResponse, p.name, err)
+	}
+	if resp.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("%w: %s: want %d, got %d", ErrProtocolVersion, p.name, ProtocolVersion, resp.ProtocolVersion)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.name, resp.Error)
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.name, runErr)
+	}
+	return &resp, nil
+}
+
+// Install asks the plugin to install pkg into outputDir.
+// The plugin is expected to produce the same layout as the conan installer,
+// i.e. .pc files in the root of outputDir.
+func (p *pluginInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	outputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.call(ctx, Request{
+		Operation: OperationInstall,
+		Package:   fromUpstreamPackage(pkg),
+		OutputDir: outputDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.PkgConfigName == "" {
+		return nil, fmt.Errorf("%w: %s: empty pkgConfigName", ErrInvalidResponse, p.name)
+	}
+	if resp.Result != nil {
+		resp.Result.PkgConfigName = resp.PkgConfigName
+		return resp.Result, nil
+	}
+	return upstream.ScanInstallResult(pkg, resp.PkgConfigName, outputDir)
+}
+
+// Search asks the plugin for the available versions of pkg.
+func (p *pluginInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	resp, err := p.call(ctx, Request{
+		Operation: OperationSearch,
+		Package:   fromUpstreamPackage(pkg),
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := resp.Packages
+	if results == nil {
+		for _, ref := range resp.Results {
+			results = append(results, upstream.ParseSearchResult(ref))
+		}
+	}
+	upstream.SortSearchResults(results)
+	return results, nil
+}
+
+// Serve implements the plugin side of the protocol: it reads a request from r,
+// runs it with an installer created by factory and writes the response to w.
+//
+// Errors of the operation are reported in the response and also returned,
+// so that the plugin can exit with a non-zero status.
+// The operation runs with ctx, plugins should cancel it on SIGINT and SIGTERM.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, factory upstream.Factory) error {
+	var req Request
+	var opErr error
+	resp := Response{ProtocolVersion: ProtocolVersion}
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		opErr = fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	} else if req.ProtocolVersion != ProtocolVersion {
+		opErr = fmt.Errorf("%w: want %d, got %d", ErrProtocolVersion, ProtocolVersion, req.ProtocolVersion)
+	} else {
+		installer := factory(req.Config)
+		pkg := toUpstreamPackage(req.Package)
+
+		switch req.Operation {
+		case OperationInstall:
+			resp.Result, opErr = installer.Install(ctx, pkg, req.OutputDir)
+			if resp.Result != nil {
+				resp.PkgConfigName = resp.Result.PkgConfigName
+			}
+		case OperationSearch:
+			resp.Packages, opErr = installer.Search(ctx, pkg)
+			for _, result := range resp.Packages {
+				resp.Results = append(resp.Results, result.Reference())
+			}
+		default:
+			opErr = fmt.Errorf("%w: %s", ErrUnsupportedOperation, req.Operation)
+		}
+	}
+	if opErr != nil {
+		resp.Error = toError(opErr)
+	}
+	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+		return errors.Join(opErr, err)
+	}
+	return opErr
+}
@@ -0,0 +1,209 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+const testPCFile = `prefix=${pcfiledir}/../..
+libdir=${prefix}/lib
+includedir=${prefix}/include
+
+Name: cjson
+Version: 1.7.18
+Libs: -L"${libdir}" -lcjson
+Cflags: -I"${includedir}"
+`
+
+// pluginDir contains the reference plugin built by TestMain.
+var pluginDir string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "llpkg-plugin")
+	if err != nil {
+		panic(err)
+	}
+	exe := filepath.Join(dir, ExecutablePrefix+"example")
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	out, err := exec.Command("go", "build", "-o", exe, "./example").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		panic("cannot build reference plugin: " + string(out))
+	}
+	pluginDir = dir
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// setupPrefixes creates a <root>/cjson/1.7.18 prefix for the reference plugin.
+func setupPrefixes(t *testing.T) string {
+	root := t.TempDir()
+	prefix := filepath.Join(root, "cjson", "1.7.18")
+	os.MkdirAll(filepath.Join(prefix, "include", "cjson"), 0777)
+	os.MkdirAll(filepath.Join(prefix, "lib", "pkgconfig"), 0777)
+	os.WriteFile(filepath.Join(prefix, "include", "cjson", "cJSON.h"), []byte("int cJSON_Version(void);"), 0644)
+	os.WriteFile(filepath.Join(prefix, "lib", "pkgconfig", "cjson.pc"), []byte(testPCFile), 0644)
+	t.Setenv("PATH", pluginDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return root
+}
+
+func TestLookup(t *testing.T) {
+	setupPrefixes(t)
+
+	if _, ok := Lookup("example"); !ok {
+		t.Fatal("reference plugin not found")
+	}
+	if _, ok := Lookup("not-exist"); ok {
+		t.Error("unexpected plugin")
+	}
+	if names := List(); !slices.Contains(names, "example") {
+		t.Errorf("unexpected plugins: %v", names)
+	}
+}
+
+func TestPluginInstall(t *testing.T) {
+	root := setupPrefixes(t)
+
+	factory, ok := Lookup("example")
+	if !ok {
+		t.Fatal("reference plugin not found")
+	}
+	installer := factory(map[string]string{"root": root})
+	if installer.Name() != "example" {
+		t.Errorf("unexpected name: %s", installer.Name())
+	}
+
+	outputDir := t.TempDir()
+	result, err := installer.Install(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"}, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PkgConfigName != "cjson" || result.Reference != "cjson/1.7.18" {
+		t.Errorf("unexpected install result: %+v", result)
+	}
+	if !slices.Equal(result.PCFiles, []string{filepath.Join(outputDir, "cjson.pc")}) {
+		t.Errorf("unexpected pc files: %v", result.PCFiles)
+	}
+	b, err := os.ReadFile(filepath.Join(outputDir, "cjson.pc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := pc.PrefixMatch.FindSubmatch(b); len(m) != 2 || string(m[1]) != outputDir {
+		t.Errorf("unexpected pc file: %s", string(b))
+	}
+
+	_, err = installer.Install(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.19"}, t.TempDir())
+	if !errors.Is(err, upstream.ErrPackageNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// a prefix without a .pc file
+	os.MkdirAll(filepath.Join(root, "cjson", "1.7.9", "include"), 0777)
+	_, err = installer.Install(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.9"}, t.TempDir())
+	if !errors.Is(err, upstream.ErrPCFileNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPluginSearch(t *testing.T) {
+	root := setupPrefixes(t)
+	os.MkdirAll(filepath.Join(root, "cjson", "1.7.9"), 0777)
+
+	factory, _ := Lookup("example")
+	installer := factory(map[string]string{"root": root})
+
+	ret, err := installer.Search(context.Background(), upstream.Package{Name: "cjson"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ret, []upstream.SearchResult{{Name: "cjson", Version: "1.7.9"}, {Name: "cjson", Version: "1.7.18"}}) {
+		t.Errorf("unexpected search result: %v", ret)
+	}
+	_, err = installer.Search(context.Background(), upstream.Package{Name: "cjson2"})
+	if !errors.Is(err, upstream.ErrPackageNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPluginProtocolVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake plugin requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := `#!/bin/sh
+cat > /dev/null
+echo '{"protocolVersion": 2, "pkgConfigName": "cjson"}'
+`
+	os.WriteFile(filepath.Join(dir, ExecutablePrefix+"future"), []byte(script), 0755)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	factory, ok := Lookup("future")
+	if !ok {
+		t.Fatal("plugin not found")
+	}
+	_, err := factory(nil).Install(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"}, t.TempDir())
+	if !errors.Is(err, ErrProtocolVersion) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPluginSearchResults(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake plugin requires a POSIX shell")
+	}
+	// plugins which only report references
+	dir := t.TempDir()
+	script := `#!/bin/sh
+cat > /dev/null
+echo '{"protocolVersion": 1, "results": ["cjson/1.7.18#6bd3d0a4", "cjson/1.7.9"]}'
+`
+	os.WriteFile(filepath.Join(dir, ExecutablePrefix+"refs"), []byte(script), 0755)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	factory, ok := Lookup("refs")
+	if !ok {
+		t.Fatal("plugin not found")
+	}
+	ret, err := factory(nil).Search(context.Background(), upstream.Package{Name: "cjson"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []upstream.SearchResult{{Name: "cjson", Version: "1.7.9"}, {Name: "cjson", Version: "1.7.18", Revision: "6bd3d0a4"}}
+	if !slices.Equal(ret, expected) {
+		t.Errorf("unexpected search result: %v", ret)
+	}
+}
+
+func TestServe(t *testing.T) {
+	factory := func(config map[string]string) upstream.Installer {
+		return NewPluginInstaller("unused", "unused", config)
+	}
+	var out bytes.Buffer
+	err := Serve(context.Background(), strings.NewReader(`{"protocolVersion": 1, "operation": "remove"}`), &out, factory)
+	if !errors.Is(err, ErrUnsupportedOperation) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var resp Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ProtocolVersion != ProtocolVersion || resp.Error == nil || resp.Error.Code != CodeUnsupportedOperation {
+		t.Errorf("unexpected response: %s", out.String())
+	}
+}
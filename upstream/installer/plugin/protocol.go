@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"errors"
+
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// ProtocolVersion is the version of the JSON protocol spoken between llpkgstore and installer plugins.
+// A plugin MUST reply with the same version it was asked with.
+const ProtocolVersion = 1
+
+// Operations supported by the protocol.
+const (
+	OperationInstall = "install"
+	OperationSearch  = "search"
+)
+
+// Error codes which can be returned by a plugin.
+// They are mapped to the errors of the builtin installers, so callers can handle
+// plugin failures in the same way, e.g. errors.Is(err, upstream.ErrPackageNotFound).
+const (
+	CodePackageNotFound      = "package_not_found"
+	CodePCFileNotFound       = "pc_file_not_found"
+	CodeUnsupportedOperation = "unsupported_operation"
+	CodeInternal             = "internal"
+)
+
+var (
+	ErrUnsupportedOperation = errors.New("plugin: unsupported operation")
+	ErrProtocolVersion      = errors.New("plugin: protocol version mismatch")
+	ErrInvalidRequest       = errors.New("plugin: invalid request")
+	ErrInvalidResponse      = errors.New("plugin: invalid response")
+)
+
+// codeErrors maps error codes to the errors returned by the builtin installers.
+var codeErrors = map[string]error{
+	CodePackageNotFound:      upstream.ErrPackageNotFound,
+	CodePCFileNotFound:       upstream.ErrPCFileNotFound,
+	CodeUnsupportedOperation: ErrUnsupportedOperation,
+}
+
+// Package is the package to operate on.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Linkage is "static" if static libraries are requested, empty otherwise.
+	Linkage string `json:"linkage,omitempty"`
+}
+
+// Request is written to the plugin's stdin as a single JSON document.
+type Request struct {
+	ProtocolVersion int               `json:"protocolVersion"`
+	Operation       string            `json:"operation"`
+	Config          map[string]string `json:"config,omitempty"`
+	Package         Package           `json:"package"`
+	// OutputDir is only set for install requests, it's always an absolute path.
+	OutputDir string `json:"outputDir,omitempty"`
+}
+
+// Error describes a failed operation.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Response is read from the plugin's stdout as a single JSON document.
+type Response struct {
+	ProtocolVersion int `json:"protocolVersion"`
+	// PkgConfigName is the result of an install request.
+	PkgConfigName string `json:"pkgConfigName,omitempty"`
+	// Result optionally describes the installed files of an install request in detail,
+	// it's scanned from outputDir if omitted.
+	Result *upstream.InstallResult `json:"result,omitempty"`
+	// Results is the result of a search request, in name/version format.
+	Results []string `json:"results,omitempty"`
+	// Packages optionally describes the results of a search request in detail,
+	// they're parsed from Results if omitted.
+	Packages []upstream.SearchResult `json:"packages,omitempty"`
+	// Error is set when the operation fails.
+	Error *Error `json:"error,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Unwrap maps the error code to a known error.
+func (e *Error) Unwrap() error {
+	return codeErrors[e.Code]
+}
+
+// toError converts an error returned by an installer into a protocol error.
+func toError(err error) *Error {
+	for code, target := range codeErrors {
+		if errors.Is(err, target) {
+			return &Error{Code: code, Message: err.Error()}
+		}
+	}
+	return &Error{Code: CodeInternal, Message: err.Error()}
+}
+
+func toUpstreamPackage(pkg Package) upstream.Package {
+	return upstream.Package{Name: pkg.Name, Version: pkg.Version, Linkage: pkg.Linkage}
+}
+
+func fromUpstreamPackage(pkg upstream.Package) Package {
+	return Package{Name: pkg.Name, Version: pkg.Version, Linkage: pkg.Linkage}
+}
@@ -0,0 +1,273 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/actions/file"
+	"github.com/goplus/llpkgstore/internal/actions/hashutils"
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/internal/cmdbuilder"
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/goplus/llpkgstore/upstream/installer/internal/layout"
+)
+
+// Supported build systems.
+const (
+	BuildCMake     = "cmake"
+	BuildAutotools = "autotools"
+)
+
+var (
+	// ErrPackageNotFound is upstream.ErrPackageNotFound.
+	//
+	// Deprecated: use upstream.ErrPackageNotFound.
+	ErrPackageNotFound    = upstream.ErrPackageNotFound
+	ErrMissingArchive     = errors.New("source: config.archive must be specified")
+	ErrMissingChecksum    = errors.New("source: config.sha256 must be specified")
+	ErrUnknownBuildSystem = errors.New("source: unknown build system")
+	ErrChecksumMismatch   = hashutils.ErrChecksumMismatch
+)
+
+func init() {
+	upstream.Register("source", NewSourceInstaller)
+}
+
+// sourceInstaller implements the upstream.Installer interface by building a library
+// from a local source archive with CMake or autotools.
+type sourceInstaller struct {
+	config map[string]string
+}
+
+// NewSourceInstaller creates a new source installer instance with provided configuration options.
+// Supported config keys:
+//   - "archive": source .tar.gz archive (required),
+//     a relative path is resolved against the directory of llpkg.cfg by config.ParseLLPkgConfig.
+//   - "sha256": hex-encoded sha256 of the archive (required).
+//   - "strip_components": leading path components to strip from archive entries, defaults to 1.
+//   - "build": "cmake" or "autotools", detected from the source tree if unspecified.
+//   - "options": space-separated extra arguments for the configure step, e.g. "-DENABLE_CJSON_UTILS=ON".
+//   - "pkg_config_name": name of the .pc file, defaults to the package name.
+//   - "libs", "libs_private", "cflags": used to synthesize a .pc file if the build doesn't install one.
+func NewSourceInstaller(config map[string]string) upstream.Installer {
+	return &sourceInstaller{
+		config: config,
+	}
+}
+
+func (s *sourceInstaller) Name() string {
+	return "source"
+}
+
+func (s *sourceInstaller) Config() map[string]string {
+	return s.config
+}
+
+// ConfigKeys implements upstream.ConfigDescriber.
+func (s *sourceInstaller) ConfigKeys() []upstream.ConfigKey {
+	return []upstream.ConfigKey{
+		{Name: "archive", Description: "source .tar.gz archive, relative to llpkg.cfg", Required: true, Path: true},
+		{Name: "sha256", Description: "hex-encoded sha256 of the archive", Required: true},
+		{Name: "strip_components", Description: "leading path components to strip from archive entries, defaults to 1"},
+		{Name: "build", Description: "cmake or autotools, detected from the source tree if unspecified"},
+		{Name: "options", Description: "space-separated extra arguments for the configure step"},
+		{Name: "pkg_config_name", Description: "name of the .pc file, defaults to the package name"},
+		{Name: "libs", Description: "space-separated libraries to link in a synthesized .pc file"},
+		{Name: "libs_private", Description: "space-separated extra linker flags of a synthesized .pc file only needed for static linking"},
+		{Name: "cflags", Description: "extra compiler flags of a synthesized .pc file"},
+	}
+}
+
+// ValidateConfig implements upstream.ConfigValidator, the required keys are checked by ConfigKeys.
+func (s *sourceInstaller) ValidateConfig(pkg upstream.Package) error {
+	if v := s.config["strip_components"]; v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			return fmt.Errorf("source: invalid strip_components: %s", v)
+		}
+	}
+	switch build := s.config["build"]; build {
+	case "", BuildCMake, BuildAutotools:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownBuildSystem, build)
+	}
+	return nil
+}
+
+// extract verifies and extracts the source archive into dir.
+func (s *sourceInstaller) extract(dir string) error {
+	archive := s.config["archive"]
+	if archive == "" {
+		return ErrMissingArchive
+	}
+	if s.config["sha256"] == "" {
+		return ErrMissingChecksum
+	}
+	if _, err := os.Stat(archive); err != nil {
+		return fmt.Errorf("%w: %v", upstream.ErrPackageNotFound, err)
+	}
+	if err := hashutils.Verify(archive, s.config["sha256"]); err != nil {
+		return err
+	}
+	strip := 1
+	if v := s.config["strip_components"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("source: invalid strip_components: %s", v)
+		}
+		strip = n
+	}
+	return file.ExtractTarGz(archive, dir, strip)
+}
+
+// buildSystem returns the configured build system, or detects it from srcDir.
+func (s *sourceInstaller) buildSystem(srcDir string) (string, error) {
+	switch build := s.config["build"]; build {
+	case BuildCMake, BuildAutotools:
+		return build, nil
+	case "":
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownBuildSystem, build)
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "CMakeLists.txt")); err == nil {
+		return BuildCMake, nil
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "configure")); err == nil {
+		return BuildAutotools, nil
+	}
+	return "", fmt.Errorf("%w: neither CMakeLists.txt nor configure found", ErrUnknownBuildSystem)
+}
+
+// run executes a build step in dir, build output goes to Stderr.
+func run(ctx context.Context, dir, name string, args ...string) error {
+	cmd := cmdbuilder.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("source: %s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// buildCMake configures, builds and installs a CMake project into prefix
+// with shared libraries, or position independent static libraries if static is set.
+func (s *sourceInstaller) buildCMake(ctx context.Context, srcDir, buildDir, prefix string, static bool) error {
+	args := []string{
+		"-S", srcDir,
+		"-B", buildDir,
+		"-DCMAKE_INSTALL_PREFIX=" + prefix,
+		"-DCMAKE_INSTALL_LIBDIR=lib",
+		"-DCMAKE_BUILD_TYPE=Release",
+	}
+	if static {
+		args = append(args, "-DBUILD_SHARED_LIBS=OFF", "-DCMAKE_POSITION_INDEPENDENT_CODE=ON")
+	} else {
+		args = append(args, "-DBUILD_SHARED_LIBS=ON")
+	}
+	args = append(args, strings.Fields(s.config["options"])...)
+	if err := run(ctx, srcDir, "cmake", args...); err != nil {
+		return err
+	}
+	if err := run(ctx, srcDir, "cmake", "--build", buildDir, "--parallel", strconv.Itoa(runtime.NumCPU())); err != nil {
+		return err
+	}
+	return run(ctx, srcDir, "cmake", "--install", buildDir)
+}
+
+// buildAutotools runs configure && make && make install
+// with shared libraries, or position independent static libraries if static is set.
+func (s *sourceInstaller) buildAutotools(ctx context.Context, srcDir, prefix string, static bool) error {
+	args := []string{
+		"--prefix=" + prefix,
+		"--libdir=" + filepath.Join(prefix, "lib"),
+	}
+	if static {
+		args = append(args, "--disable-shared", "--enable-static", "--with-pic")
+	} else {
+		args = append(args, "--enable-shared", "--disable-static")
+	}
+	args = append(args, strings.Fields(s.config["options"])...)
+	if err := run(ctx, srcDir, "./configure", args...); err != nil {
+		return err
+	}
+	if err := run(ctx, srcDir, "make", "-j"+strconv.Itoa(runtime.NumCPU())); err != nil {
+		return err
+	}
+	return run(ctx, srcDir, "make", "install")
+}
+
+// Install builds the source archive and installs it into outputDir.
+// .pc files installed by the build are exported to the root of outputDir like the conan installer does,
+// if the build doesn't install one for the package, it's synthesized from the include and lib directories.
+func (s *sourceInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	prefix, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	workDir, err := os.MkdirTemp("", "llpkg-source")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	srcDir := filepath.Join(workDir, "src")
+	if err := s.extract(srcDir); err != nil {
+		return nil, err
+	}
+	build, err := s.buildSystem(srcDir)
+	if err != nil {
+		return nil, err
+	}
+	switch build {
+	case BuildCMake:
+		err = s.buildCMake(ctx, srcDir, filepath.Join(workDir, "build"), prefix, pkg.Static())
+	case BuildAutotools:
+		err = s.buildAutotools(ctx, srcDir, prefix, pkg.Static())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := layout.Install(prefix, prefix); err != nil {
+		return nil, err
+	}
+	pkgConfigName := s.config["pkg_config_name"]
+	if pkgConfigName == "" {
+		pkgConfigName = pkg.Name
+	}
+	if _, ok := layout.PkgConfigName(prefix, pkgConfigName); ok {
+		return upstream.ScanInstallResult(pkg, pkgConfigName, outputDir)
+	}
+	err = layout.Synthesize(prefix, pc.File{
+		Name:        pkgConfigName,
+		Version:     pkg.Version,
+		Libs:        strings.Fields(s.config["libs"]),
+		LibsPrivate: strings.Fields(s.config["libs_private"]),
+		Cflags:      strings.Fields(s.config["cflags"]),
+	}, pkg.Static())
+	if err != nil {
+		return nil, err
+	}
+	return upstream.ScanInstallResult(pkg, pkgConfigName, outputDir)
+}
+
+// Search reports the configured package as the only available version if its source archive exists.
+func (s *sourceInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	archive := s.config["archive"]
+	if archive == "" {
+		return nil, ErrMissingArchive
+	}
+	if _, err := os.Stat(archive); err != nil {
+		return nil, upstream.ErrPackageNotFound
+	}
+	return []upstream.SearchResult{{Name: pkg.Name, Version: pkg.Version}}, nil
+}
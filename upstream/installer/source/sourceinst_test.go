@@ -0,0 +1,241 @@
+package source
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+var testPkg = upstream.Package{
+	Name:    "hello",
+	Version: "1.0.0",
+}
+
+// writeTarGz writes files into a gzip-compressed tarball and returns its sha256.
+func writeTarGz(t *testing.T, fileName string, files map[string]string) string {
+	f, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.New()
+	gw := gzip.NewWriter(io.MultiWriter(f, h))
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		mode := int64(0644)
+		if strings.HasSuffix(name, "configure") {
+			mode = 0755
+		}
+		tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gw.Close()
+	f.Close()
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var helloSource = map[string]string{
+	"hello-1.0.0/hello.h": "int hello(void);\n",
+	"hello-1.0.0/hello.c": "int hello(void) { return 42; }\n",
+}
+
+// autotoolsProject is a minimal configure script which behaves like an autoconf generated one.
+// It builds a static library when configured with --disable-shared.
+var autotoolsProject = map[string]string{
+	"hello-1.0.0/configure": `#!/bin/sh
+prefix=/usr/local
+lib=libhello.so
+build='cc -shared -fPIC -o libhello.so hello.c'
+for arg; do
+	case "$arg" in
+	--prefix=*) prefix="${arg#--prefix=}" ;;
+	--libdir=*) libdir="${arg#--libdir=}" ;;
+	--disable-shared)
+		lib=libhello.a
+		build='cc -c -fPIC hello.c && ar rcs libhello.a hello.o'
+		;;
+	esac
+done
+libdir="${libdir:-$prefix/lib}"
+cat > Makefile <<MAKEFILE
+all: $lib
+$lib: hello.c
+	$build
+install: all
+	mkdir -p $libdir/pkgconfig $prefix/include
+	cp $lib $libdir/
+	cp hello.h $prefix/include/
+	printf 'prefix=$prefix\nlibdir=\x24{prefix}/lib\nincludedir=\x24{prefix}/include\n\nName: hello\nDescription: hello\nVersion: 1.0.0\nLibs: -L\x24{libdir} -lhello\nCflags: -I\x24{includedir}\n' > $libdir/pkgconfig/hello.pc
+MAKEFILE
+`,
+}
+
+func requireTools(t *testing.T, tools ...string) {
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+}
+
+func checkInstalled(t *testing.T, outputDir string) {
+	b, err := os.ReadFile(filepath.Join(outputDir, "hello.pc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := pc.PrefixMatch.FindSubmatch(b); len(m) != 2 || string(m[1]) != outputDir {
+		t.Errorf("unexpected prefix: %s", string(b))
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "lib", "libhello.so")); err != nil {
+		t.Errorf("library not installed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "include", "hello.h")); err != nil {
+		t.Errorf("header not installed: %v", err)
+	}
+}
+
+func newProject(t *testing.T, files ...map[string]string) map[string]string {
+	archive := filepath.Join(t.TempDir(), "hello-1.0.0.tar.gz")
+	all := map[string]string{}
+	for _, f := range files {
+		for k, v := range f {
+			all[k] = v
+		}
+	}
+	return map[string]string{
+		"archive": archive,
+		"sha256":  writeTarGz(t, archive, all),
+	}
+}
+
+func TestSourceInstallAutotools(t *testing.T) {
+	requireTools(t, "cc", "make")
+	config := newProject(t, helloSource, autotoolsProject)
+	outputDir := t.TempDir()
+
+	result, err := NewSourceInstaller(config).Install(context.Background(), testPkg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PkgConfigName != "hello" || result.PCFile() == "" {
+		t.Errorf("unexpected install result: %+v", result)
+	}
+	checkInstalled(t, outputDir)
+}
+
+func TestSourceInstallStatic(t *testing.T) {
+	requireTools(t, "cc", "ar", "make")
+	config := newProject(t, helloSource, autotoolsProject)
+	outputDir := t.TempDir()
+
+	pkg := testPkg
+	pkg.Linkage = upstream.LinkageStatic
+	result, err := NewSourceInstaller(config).Install(context.Background(), pkg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "lib", "libhello.a")); err != nil {
+		t.Errorf("archive not installed: %v", err)
+	}
+	if err := result.CheckLinkage(pkg.Linkage); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSourceInstallCMake(t *testing.T) {
+	requireTools(t, "cc", "cmake")
+	config := newProject(t, helloSource, map[string]string{
+		"hello-1.0.0/CMakeLists.txt": `cmake_minimum_required(VERSION 3.10)
+project(hello C)
+add_library(hello hello.c)
+install(TARGETS hello)
+install(FILES hello.h DESTINATION include)
+`,
+	})
+	config["libs"] = "hello"
+	outputDir := t.TempDir()
+
+	result, err := NewSourceInstaller(config).Install(context.Background(), testPkg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PkgConfigName != "hello" || result.PCFile() == "" {
+		t.Errorf("unexpected install result: %+v", result)
+	}
+	checkInstalled(t, outputDir)
+}
+
+func TestSourceChecksum(t *testing.T) {
+	config := newProject(t, helloSource, autotoolsProject)
+	config["sha256"] = strings.Repeat("0", 64)
+	_, err := NewSourceInstaller(config).Install(context.Background(), testPkg, t.TempDir())
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	delete(config, "sha256")
+	_, err = NewSourceInstaller(config).Install(context.Background(), testPkg, t.TempDir())
+	if !errors.Is(err, ErrMissingChecksum) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSourceBuildSystem(t *testing.T) {
+	config := newProject(t, helloSource)
+	_, err := NewSourceInstaller(config).Install(context.Background(), testPkg, t.TempDir())
+	if !errors.Is(err, ErrUnknownBuildSystem) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	config["build"] = "meson"
+	_, err = NewSourceInstaller(config).Install(context.Background(), testPkg, t.TempDir())
+	if !errors.Is(err, ErrUnknownBuildSystem) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSourceSearch(t *testing.T) {
+	config := newProject(t, helloSource)
+	results, err := NewSourceInstaller(config).Search(context.Background(), testPkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Reference() != "hello/1.0.0" {
+		t.Errorf("unexpected results: %v", results)
+	}
+
+	config["archive"] = filepath.Join(t.TempDir(), "missing.tar.gz")
+	if _, err := NewSourceInstaller(config).Search(context.Background(), testPkg); !errors.Is(err, upstream.ErrPackageNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSourceInstallCancel(t *testing.T) {
+	config := newProject(t, helloSource, map[string]string{
+		"hello-1.0.0/configure": "#!/bin/sh\nsleep 60\n",
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewSourceInstaller(config).Install(ctx, testPkg, t.TempDir())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("build wasn't stopped on cancellation, took %s", elapsed)
+	}
+}
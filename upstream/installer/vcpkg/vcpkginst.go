@@ -0,0 +1,354 @@
+package vcpkg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/cmdbuilder"
+	"github.com/goplus/llpkgstore/upstream"
+	"github.com/goplus/llpkgstore/upstream/installer/internal/layout"
+)
+
+var (
+	// ErrPackageNotFound is upstream.ErrPackageNotFound.
+	//
+	// Deprecated: use upstream.ErrPackageNotFound.
+	ErrPackageNotFound = upstream.ErrPackageNotFound
+	// ErrPCFileNotFound is upstream.ErrPCFileNotFound.
+	//
+	// Deprecated: use upstream.ErrPCFileNotFound.
+	ErrPCFileNotFound  = upstream.ErrPCFileNotFound
+	ErrVersionMismatch = errors.New("installed version mismatch")
+)
+
+// tripletArch and tripletOS map GOARCH/GOOS to vcpkg triplet components.
+var (
+	tripletArch = map[string]string{
+		"amd64": "x64",
+		"386":   "x86",
+		"arm64": "arm64",
+		"arm":   "arm",
+	}
+	tripletOS = map[string]string{
+		"linux":   "linux",
+		"darwin":  "osx",
+		"windows": "windows",
+	}
+)
+
+// manifest is the subset of vcpkg.json we generate for manifest mode.
+type manifest struct {
+	Name      string       `json:"name"`
+	Version   string       `json:"version-string"`
+	Deps      []dependency `json:"dependencies"`
+	Overrides []override   `json:"overrides,omitempty"`
+	Baseline  string       `json:"builtin-baseline,omitempty"`
+}
+
+type dependency struct {
+	Name     string   `json:"name"`
+	Features []string `json:"features,omitempty"`
+}
+
+type override struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// defaultTriplet returns the dynamic-library triplet for platform,
+// so that vcpkg produces shared libraries like the conan installer does,
+// or the static-library triplet if static is set.
+func defaultTriplet(platform upstream.Platform, static bool) string {
+	arch, goos := tripletArch[platform.GOARCH], tripletOS[platform.GOOS]
+	if arch == "" || goos == "" {
+		return ""
+	}
+	// windows triplets are dynamic by default, the others are static
+	switch {
+	case platform.GOOS == "windows" && static:
+		return arch + "-" + goos + "-static"
+	case platform.GOOS == "windows" || static:
+		return arch + "-" + goos
+	}
+	return arch + "-" + goos + "-dynamic"
+}
+
+func init() {
+	upstream.Register("vcpkg", NewVcpkgInstaller)
+}
+
+// vcpkgInstaller implements the upstream.Installer interface using the vcpkg package manager.
+// It installs packages in manifest mode, which allows the version to be pinned
+// through overrides when a builtin baseline is configured.
+type vcpkgInstaller struct {
+	config map[string]string
+	// platform selects the default triplet, the zero value meaning the host, see Target.
+	platform upstream.Platform
+}
+
+// NewVcpkgInstaller creates a new vcpkg-based installer instance with provided configuration options.
+// Supported config keys:
+//   - "triplet": vcpkg triplet, defaults to the dynamic triplet of the current platform (e.g. x64-linux-dynamic),
+//     or the static one (e.g. x64-linux) for packages with static linkage.
+//   - "baseline": builtin-baseline commit of the vcpkg registry, required to pin the package version.
+//   - "features": space-separated port features to enable.
+//   - "overlay_ports": space-separated overlay port directories.
+//   - "pkg_config_name": name of the .pc file to report, defaults to the package name or lib<name>.
+func NewVcpkgInstaller(config map[string]string) upstream.Installer {
+	return &vcpkgInstaller{
+		config: config,
+	}
+}
+
+func (v *vcpkgInstaller) Name() string {
+	return "vcpkg"
+}
+
+func (v *vcpkgInstaller) Config() map[string]string {
+	return v.config
+}
+
+// ConfigKeys implements upstream.ConfigDescriber.
+func (v *vcpkgInstaller) ConfigKeys() []upstream.ConfigKey {
+	return []upstream.ConfigKey{
+		{Name: "triplet", Description: "vcpkg triplet, defaults to the dynamic or static triplet of the target platform"},
+		{Name: "baseline", Description: "builtin-baseline commit of the vcpkg registry, required to pin the package version"},
+		{Name: "features", Description: "space-separated port features to enable"},
+		{Name: "overlay_ports", Description: "space-separated overlay port directories"},
+		{Name: "pkg_config_name", Description: "name of the .pc file, defaults to the package name or lib<name>"},
+	}
+}
+
+func (v *vcpkgInstaller) triplet(pkg upstream.Package) string {
+	if triplet := v.config["triplet"]; triplet != "" {
+		return triplet
+	}
+	return defaultTriplet(v.targetPlatform(), pkg.Static())
+}
+
+// targetPlatform returns v.platform, or the host if it isn't set.
+func (v *vcpkgInstaller) targetPlatform() upstream.Platform {
+	if v.platform == (upstream.Platform{}) {
+		return upstream.HostPlatform()
+	}
+	return v.platform
+}
+
+// Target implements upstream.Targeter: the default triplet is the one of platform.
+func (v *vcpkgInstaller) Target(platform upstream.Platform) error {
+	if v.config["triplet"] == "" && defaultTriplet(platform, false) == "" {
+		return fmt.Errorf("vcpkg: no default triplet for %s", platform)
+	}
+	v.platform = platform
+	return nil
+}
+
+// writeManifest writes a vcpkg.json requiring pkg into dir.
+func (v *vcpkgInstaller) writeManifest(pkg upstream.Package, dir string) error {
+	m := manifest{
+		Name:    "llpkg-" + strings.ToLower(pkg.Name),
+		Version: "0",
+		Deps: []dependency{{
+			Name:     pkg.Name,
+			Features: strings.Fields(v.config["features"]),
+		}},
+	}
+	// version constraints only work with a baseline in vcpkg.
+	if baseline := v.config["baseline"]; baseline != "" {
+		m.Baseline = baseline
+		m.Overrides = []override{{Name: pkg.Name, Version: pkg.Version}}
+	}
+	b, err := json.MarshalIndent(&m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "vcpkg.json"), b, 0644)
+}
+
+// statusEntry is a package recorded in the vcpkg status database.
+type statusEntry struct {
+	version string
+	depends []string
+}
+
+// readStatus reads the installed packages from the vcpkg status database.
+// Feature paragraphs are merged into the paragraph of their package.
+func readStatus(installRoot string) (map[string]*statusEntry, error) {
+	f, err := os.Open(filepath.Join(installRoot, "vcpkg", "status"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ret := map[string]*statusEntry{}
+	var cur *statusEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), ":")
+		value = strings.TrimSpace(value)
+		switch key {
+		case "":
+			// paragraphs are separated by blank lines
+			cur = nil
+		case "Package":
+			cur = ret[value]
+			if cur == nil {
+				cur = &statusEntry{}
+				ret[value] = cur
+			}
+		case "Version":
+			if cur != nil {
+				cur.version = value
+			}
+		case "Depends":
+			if cur == nil {
+				continue
+			}
+			for _, dep := range strings.Split(value, ",") {
+				// strip qualifiers: name[feature]:triplet
+				dep, _, _ = strings.Cut(strings.TrimSpace(dep), ":")
+				dep, _, _ = strings.Cut(dep, "[")
+				if dep != "" && !slices.Contains(cur.depends, dep) {
+					cur.depends = append(cur.depends, dep)
+				}
+			}
+		}
+	}
+	return ret, scanner.Err()
+}
+
+// installedPackage reads the installed version and the dependencies of pkg from the vcpkg status database.
+func installedPackage(pkg upstream.Package, installRoot string) (string, []upstream.Package, error) {
+	status, err := readStatus(installRoot)
+	if err != nil {
+		return "", nil, err
+	}
+	entry := status[pkg.Name]
+	if entry == nil || entry.version == "" {
+		return "", nil, upstream.ErrPackageNotFound
+	}
+	var deps []upstream.Package
+	for _, dep := range entry.depends {
+		var version string
+		if e := status[dep]; e != nil {
+			version = e.version
+		}
+		deps = append(deps, upstream.Package{Name: dep, Version: version})
+	}
+	return entry.version, deps, nil
+}
+
+// Install executes vcpkg installation for the specified package into the output directory.
+// The installed triplet tree is copied into outputDir and its .pc files are exported
+// to the root of outputDir, matching the layout of the conan installer.
+func (v *vcpkgInstaller) Install(ctx context.Context, pkg upstream.Package, outputDir string) (*upstream.InstallResult, error) {
+	triplet := v.triplet(pkg)
+	if triplet == "" {
+		return nil, fmt.Errorf("vcpkg: no default triplet for %s", v.targetPlatform())
+	}
+	manifestDir, err := os.MkdirTemp("", "llpkg-vcpkg")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(manifestDir)
+
+	if err := v.writeManifest(pkg, manifestDir); err != nil {
+		return nil, err
+	}
+	installRoot := filepath.Join(manifestDir, "vcpkg_installed")
+
+	// Build the following command
+	// vcpkg install --x-manifest-root=%s --x-install-root=%s --triplet=%s
+	// vcpkg accepts the same --key=value form as conan.
+	builder := cmdbuilder.NewCmdBuilder(cmdbuilder.WithConanSerializer())
+
+	builder.SetName("vcpkg")
+	builder.SetSubcommand("install")
+	builder.SetArg("x-manifest-root", manifestDir)
+	builder.SetArg("x-install-root", installRoot)
+	builder.SetArg("triplet", triplet)
+
+	for _, overlay := range strings.Fields(v.config["overlay_ports"]) {
+		builder.SetArg("overlay-ports", overlay)
+	}
+
+	cmd := builder.CmdContext(ctx)
+	// vcpkg outputs progress to Stdout, keep Stdout clean for our callers.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	version, deps, err := installedPackage(pkg, installRoot)
+	if err != nil {
+		return nil, err
+	}
+	// without a baseline, vcpkg installs whatever version its registry provides.
+	if version != pkg.Version {
+		return nil, fmt.Errorf("%w: want %s, got %s", ErrVersionMismatch, pkg.Version, version)
+	}
+
+	if _, err := layout.Install(filepath.Join(installRoot, triplet), outputDir); err != nil {
+		return nil, err
+	}
+	// vcpkg ports often name their .pc file lib<name>.pc
+	pkgConfigName, ok := layout.PkgConfigName(outputDir, v.config["pkg_config_name"], pkg.Name, "lib"+pkg.Name)
+	if !ok {
+		return nil, upstream.ErrPCFileNotFound
+	}
+	result, err := upstream.ScanInstallResult(pkg, pkgConfigName, outputDir)
+	if err != nil {
+		return nil, err
+	}
+	result.Dependencies = deps
+	return result, nil
+}
+
+// Search checks the vcpkg registry for the specified package availability.
+// Returns the search results in name/version format and any encountered errors.
+func (v *vcpkgInstaller) Search(ctx context.Context, pkg upstream.Package) ([]upstream.SearchResult, error) {
+	// Build the following command
+	// vcpkg search %s
+	builder := cmdbuilder.NewCmdBuilder(cmdbuilder.WithConanSerializer())
+
+	builder.SetName("vcpkg")
+	builder.SetSubcommand("search")
+	builder.SetObj(pkg.Name)
+
+	cmd := builder.CmdContext(ctx)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		fmt.Println(string(out))
+		return nil, err
+	}
+
+	var ret []upstream.SearchResult
+
+	// output format: name version[#port-version] description
+	// features are listed as name[feature] and don't have a version.
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == pkg.Name {
+			ret = append(ret, upstream.ParseSearchResult(fields[0]+"/"+fields[1]))
+		}
+	}
+	if len(ret) == 0 {
+		return nil, upstream.ErrPackageNotFound
+	}
+	upstream.SortSearchResults(ret)
+	return ret, nil
+}
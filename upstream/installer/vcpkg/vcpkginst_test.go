@@ -0,0 +1,253 @@
+package vcpkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llpkgstore/internal/actions/pc"
+	"github.com/goplus/llpkgstore/upstream"
+)
+
+// fakeVcpkg emulates `vcpkg install` and `vcpkg search` for cjson.
+// The installed version is taken from FAKE_VCPKG_VERSION.
+const fakeVcpkg = `#!/bin/sh
+cmd=$1
+shift
+case "$cmd" in
+install)
+	for arg in "$@"; do
+		case "$arg" in
+		--x-manifest-root=*) manifest="${arg#*=}" ;;
+		--x-install-root=*) root="${arg#*=}" ;;
+		--triplet=*) triplet="${arg#*=}" ;;
+		esac
+	done
+	test -f "$manifest/vcpkg.json" || { echo "no manifest" >&2; exit 1; }
+	cp "$manifest/vcpkg.json" "$FAKE_VCPKG_MANIFEST"
+	prefix="$root/$triplet"
+	mkdir -p "$prefix/include/cjson" "$prefix/lib/pkgconfig" "$prefix/debug/lib/pkgconfig" "$root/vcpkg"
+	echo "int cJSON_Version(void);" > "$prefix/include/cjson/cJSON.h"
+	case "$triplet" in
+	*-dynamic) echo "ELF" > "$prefix/lib/libcjson.so" ;;
+	*) echo "!<arch>" > "$prefix/lib/libcjson.a" ;;
+	esac
+	cat > "$prefix/lib/pkgconfig/libcjson.pc" <<PC
+prefix=\${pcfiledir}/../..
+exec_prefix=\${prefix}
+libdir=\${exec_prefix}/lib
+includedir=\${prefix}/include
+
+Name: libcjson
+Description: Ultralightweight JSON parser in ANSI C
+Version: ${FAKE_VCPKG_VERSION}
+Libs: -L"\${libdir}" -lcjson
+Cflags: -I"\${includedir}"
+PC
+	printf 'Package: vcpkg-cmake\nVersion: 2024-04-23\nArchitecture: x64-linux\nStatus: install ok installed\n\nPackage: cjson\nVersion: %s\nDepends: vcpkg-cmake:x64-linux\nArchitecture: %s\nStatus: install ok installed\n\nPackage: cjson\nFeature: utils\nArchitecture: %s\nStatus: install ok installed\n' \
+		"$FAKE_VCPKG_VERSION" "$triplet" "$triplet" > "$root/vcpkg/status"
+	;;
+search)
+	if [ "$1" = "cjson" ]; then
+		echo "cjson                    1.7.18           Ultralightweight JSON parser in ANSI C"
+		echo "cjson[utils]                              Enable extra utility functions"
+	else
+		echo "No packages match the search criteria."
+	fi
+	;;
+*)
+	exit 1
+	;;
+esac
+`
+
+// setupFakeVcpkg puts a fake vcpkg executable in front of PATH.
+func setupFakeVcpkg(t *testing.T, version string) (manifestCopy string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake vcpkg requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "vcpkg"), []byte(fakeVcpkg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifestCopy = filepath.Join(t.TempDir(), "vcpkg.json")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_VCPKG_VERSION", version)
+	t.Setenv("FAKE_VCPKG_MANIFEST", manifestCopy)
+	return
+}
+
+func TestVcpkgInstaller(t *testing.T) {
+	manifestCopy := setupFakeVcpkg(t, "1.7.18")
+
+	v := &vcpkgInstaller{
+		config: map[string]string{
+			"triplet":  "x64-linux-dynamic",
+			"baseline": "c9919121dde6f61c0436adda94624636e041226b",
+			"features": "utils",
+		},
+	}
+
+	pkg := upstream.Package{
+		Name:    "cjson",
+		Version: "1.7.18",
+	}
+
+	if name := v.Name(); name != "vcpkg" {
+		t.Errorf("Unexpected name: %s", name)
+	}
+
+	tempDir := t.TempDir()
+
+	result, err := v.Install(context.Background(), pkg, tempDir)
+	if err != nil {
+		t.Fatalf("Install failed: %s", err)
+	}
+	pkgConfigName := result.PkgConfigName
+	if pkgConfigName != "libcjson" {
+		t.Errorf("Unexpected pkg-config name: %s", pkgConfigName)
+	}
+	if !slices.Equal(result.Dependencies, []upstream.Package{{Name: "vcpkg-cmake", Version: "2024-04-23"}}) {
+		t.Errorf("Unexpected dependencies: %v", result.Dependencies)
+	}
+	if !slices.Equal(result.IncludeDirs, []string{filepath.Join(tempDir, "include")}) {
+		t.Errorf("Unexpected include dirs: %v", result.IncludeDirs)
+	}
+
+	// check the generated manifest pins the version
+	b, err := os.ReadFile(manifestCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	json.Unmarshal(b, &m)
+	if m.Baseline == "" || !slices.Equal(m.Overrides, []override{{Name: "cjson", Version: "1.7.18"}}) {
+		t.Errorf("unexpected manifest: %s", string(b))
+	}
+	if len(m.Deps) != 1 || !slices.Equal(m.Deps[0].Features, []string{"utils"}) {
+		t.Errorf("unexpected manifest dependencies: %s", string(b))
+	}
+
+	// check the layout is the same as the conan installer
+	pcFile, err := os.ReadFile(filepath.Join(tempDir, pkgConfigName+".pc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := pc.PrefixMatch.FindSubmatch(pcFile)
+	if len(matches) != 2 || string(matches[1]) != tempDir {
+		t.Errorf("unexpected prefix: %s", string(pcFile))
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "include", "cjson", "cJSON.h")); err != nil {
+		t.Errorf("header not found: %s", err)
+	}
+
+	if _, err := exec.LookPath("pkg-config"); err == nil {
+		cmd := exec.Command("pkg-config", "--cflags", pkgConfigName)
+		pc.SetPath(cmd, tempDir)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("pkg-config failed: %s %s", err, string(out))
+		}
+		if !strings.Contains(string(out), filepath.Join(tempDir, "include")) {
+			t.Errorf("unexpected cflags: %s", string(out))
+		}
+	}
+
+	// check we can generate a template from the exported .pc file
+	templateDir := t.TempDir()
+	if err := pc.GenerateTemplateFromPC(filepath.Join(tempDir, pkgConfigName+".pc"), templateDir); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _ := os.ReadFile(filepath.Join(templateDir, pkgConfigName+".pc"+pc.PCTemplateSuffix))
+	if !strings.HasPrefix(string(tmpl), "prefix={{.Prefix}}\n") {
+		t.Errorf("unexpected template: %s", string(tmpl))
+	}
+}
+
+func TestVcpkgStatic(t *testing.T) {
+	setupFakeVcpkg(t, "1.7.18")
+	if defaultTriplet(upstream.HostPlatform(), true) == "" {
+		t.Skipf("no default triplet for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	v := &vcpkgInstaller{config: map[string]string{}}
+	pkg := upstream.Package{Name: "cjson", Version: "1.7.18", Linkage: upstream.LinkageStatic}
+	if triplet := v.triplet(pkg); strings.HasSuffix(triplet, "-dynamic") {
+		t.Errorf("unexpected triplet: %s", triplet)
+	}
+
+	tempDir := t.TempDir()
+	result, err := v.Install(context.Background(), pkg, tempDir)
+	if err != nil {
+		t.Fatalf("Install failed: %s", err)
+	}
+	if err := result.CheckLinkage(pkg.Linkage); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVcpkgTarget(t *testing.T) {
+	v := &vcpkgInstaller{config: map[string]string{}}
+	if err := v.Target(upstream.Platform{GOOS: "linux", GOARCH: "arm64"}); err != nil {
+		t.Fatal(err)
+	}
+	if triplet := v.triplet(upstream.Package{Name: "cjson"}); triplet != "arm64-linux-dynamic" {
+		t.Errorf("unexpected triplet: %s", triplet)
+	}
+	if triplet := v.triplet(upstream.Package{Name: "cjson", Linkage: upstream.LinkageStatic}); triplet != "arm64-linux" {
+		t.Errorf("unexpected static triplet: %s", triplet)
+	}
+
+	// a platform without a default triplet needs one to be configured
+	plan9 := upstream.Platform{GOOS: "plan9", GOARCH: "amd64"}
+	if err := v.Target(plan9); err == nil {
+		t.Error("unexpected success for a platform without a default triplet")
+	}
+	v = &vcpkgInstaller{config: map[string]string{"triplet": "x64-plan9"}}
+	if err := v.Target(plan9); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVcpkgVersionMismatch(t *testing.T) {
+	setupFakeVcpkg(t, "1.7.17")
+
+	v := &vcpkgInstaller{
+		config: map[string]string{
+			"triplet": "x64-linux-dynamic",
+		},
+	}
+
+	_, err := v.Install(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"}, t.TempDir())
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestVcpkgSearch(t *testing.T) {
+	setupFakeVcpkg(t, "1.7.18")
+
+	v := &vcpkgInstaller{
+		config: map[string]string{},
+	}
+
+	ver, err := v.Search(context.Background(), upstream.Package{Name: "cjson", Version: "1.7.18"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ver, []upstream.SearchResult{{Name: "cjson", Version: "1.7.18"}}) {
+		t.Errorf("unexpected search result: %s", ver)
+	}
+
+	_, err = v.Search(context.Background(), upstream.Package{Name: "cjson2", Version: "1.7.18"})
+	if !errors.Is(err, upstream.ErrPackageNotFound) {
+		t.Errorf("unexpected behavior: %v", err)
+	}
+}
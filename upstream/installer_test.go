@@ -0,0 +1,136 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// blockingInstaller is a LegacyInstaller whose operations block until release is closed.
+type blockingInstaller struct {
+	release chan struct{}
+}
+
+func (b *blockingInstaller) Name() string              { return "blocking" }
+func (b *blockingInstaller) Config() map[string]string { return nil }
+func (b *blockingInstaller) Install(pkg Package, outputDir string) (string, error) {
+	<-b.release
+	return pkg.Name, nil
+}
+func (b *blockingInstaller) Search(pkg Package) ([]string, error) {
+	<-b.release
+	return []string{pkg.Name + "/" + pkg.Version}, nil
+}
+
+func TestFromLegacy(t *testing.T) {
+	b := &blockingInstaller{release: make(chan struct{})}
+	installer := FromLegacy(b)
+	pkg := Package{Name: "cjson", Version: "1.7.18"}
+
+	close(b.release)
+	outputDir := t.TempDir()
+	os.WriteFile(filepath.Join(outputDir, "cjson.pc"), []byte("prefix="+outputDir), 0644)
+	result, err := installer.Install(context.Background(), pkg, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PkgConfigName != "cjson" || result.PCFile() != filepath.Join(outputDir, "cjson.pc") {
+		t.Errorf("unexpected install result: %+v", result)
+	}
+	results, err := installer.Search(context.Background(), pkg)
+	if err != nil || len(results) != 1 || results[0] != (SearchResult{Name: "cjson", Version: "1.7.18"}) {
+		t.Errorf("unexpected search result: %v %v", results, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := installer.Install(ctx, pkg, t.TempDir()); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUpstreamTimeout(t *testing.T) {
+	b := &blockingInstaller{release: make(chan struct{})}
+	defer close(b.release)
+
+	u := &Upstream{
+		Installer:     FromLegacy(b),
+		Pkg:           Package{Name: "cjson", Version: "1.7.18"},
+		SearchTimeout: 10 * time.Millisecond,
+	}
+	if _, err := u.Search(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	u.InstallTimeout = -1
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := u.Install(ctx, t.TempDir()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// deadlineInstaller records whether its installs have a deadline.
+type deadlineInstaller struct {
+	lockingInstaller
+	hasDeadline bool
+}
+
+func (d *deadlineInstaller) Install(ctx context.Context, pkg Package, outputDir string) (*InstallResult, error) {
+	_, d.hasDeadline = ctx.Deadline()
+	return &InstallResult{PkgConfigName: pkg.Name}, nil
+}
+
+func TestUpstreamInstallDeadline(t *testing.T) {
+	d := &deadlineInstaller{}
+	u := &Upstream{Installer: d, Pkg: Package{Name: "cjson", Version: "1.7.18"}}
+	// installs have no deadline by default
+	if _, err := u.Install(context.Background(), t.TempDir()); err != nil || d.hasDeadline {
+		t.Errorf("unexpected deadline: %v %v", d.hasDeadline, err)
+	}
+	u.InstallTimeout = time.Hour
+	if _, err := u.Install(context.Background(), t.TempDir()); err != nil || !d.hasDeadline {
+		t.Errorf("missing deadline: %v %v", d.hasDeadline, err)
+	}
+	u.InstallTimeout = -1
+	if _, err := u.Install(context.Background(), t.TempDir()); err != nil || d.hasDeadline {
+		t.Errorf("unexpected deadline: %v %v", d.hasDeadline, err)
+	}
+}
+
+func TestScanInstallResult(t *testing.T) {
+	outputDir := t.TempDir()
+	for _, name := range []string{
+		"cjson.pc",
+		"libcjson_utils.pc",
+		"conanrun.sh",
+		"include/cjson/cJSON.h",
+		"lib/libcjson.so",
+		"licenses/LICENSE",
+		"share/cjson/copyright",
+		"share/cjson/usage",
+	} {
+		os.MkdirAll(filepath.Join(outputDir, filepath.Dir(name)), 0777)
+		os.WriteFile(filepath.Join(outputDir, name), nil, 0644)
+	}
+
+	result, err := ScanInstallResult(Package{Name: "cjson", Version: "1.7.18"}, "cjson", outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &InstallResult{
+		PkgConfigName: "cjson",
+		PCFiles:       []string{filepath.Join(outputDir, "cjson.pc"), filepath.Join(outputDir, "libcjson_utils.pc")},
+		IncludeDirs:   []string{filepath.Join(outputDir, "include")},
+		LibDirs:       []string{filepath.Join(outputDir, "lib")},
+		Reference:     "cjson/1.7.18",
+		Licenses:      []string{filepath.Join(outputDir, "licenses", "LICENSE"), filepath.Join(outputDir, "share", "cjson", "copyright")},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result:\nwant %+v\ngot  %+v", expected, result)
+	}
+}
@@ -0,0 +1,66 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrLinkageMismatch is returned by InstallResult.CheckLinkage when the installed
+// libraries don't match the requested linkage.
+var ErrLinkageMismatch = errors.New("installed libraries don't match the linkage")
+
+// IsArchive reports whether name is a static library, e.g. libcjson.a or cjson.lib.
+//
+// On Windows .lib files are also import libraries of DLLs, CheckLinkage
+// tells them apart by looking for the DLLs themselves.
+func IsArchive(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".a" || ext == ".lib"
+}
+
+// IsSharedLibrary reports whether name is a shared library,
+// including versioned ones like libcjson.so.1.7.18 and libcjson.1.dylib.
+func IsSharedLibrary(name string) bool {
+	switch filepath.Ext(name) {
+	case ".so", ".dylib", ".dll":
+		return true
+	}
+	return strings.Contains(name, ".so.")
+}
+
+// CheckLinkage verifies the libraries in LibDirs and BinDirs can be linked with linkage.
+//
+// A static package must ship no shared library, otherwise the release would silently
+// depend on it, and at least one archive unless it is header-only (has no LibDirs).
+// Shared packages aren't checked, since they usually ship archives of their own as well.
+func (r *InstallResult) CheckLinkage(linkage string) error {
+	if linkage != LinkageStatic {
+		return nil
+	}
+	var archives, shared []string
+	for _, dir := range slices.Concat(r.LibDirs, r.BinDirs) {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			switch name := entry.Name(); {
+			case IsSharedLibrary(name):
+				shared = append(shared, filepath.Join(dir, name))
+			case IsArchive(name):
+				archives = append(archives, filepath.Join(dir, name))
+			}
+		}
+	}
+	if len(shared) > 0 {
+		return fmt.Errorf("%w %s: found shared libraries %v", ErrLinkageMismatch, linkage, shared)
+	}
+	if len(archives) == 0 && len(r.LibDirs) > 0 {
+		return fmt.Errorf("%w %s: no static library found", ErrLinkageMismatch, linkage)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package upstream
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckLinkage(t *testing.T) {
+	libDir := t.TempDir()
+	result := &InstallResult{LibDirs: []string{libDir}}
+
+	if err := result.CheckLinkage(LinkageStatic); !errors.Is(err, ErrLinkageMismatch) {
+		t.Errorf("unexpected error for empty lib dir: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(libDir, "libcjson.a"), nil, 0644)
+	if err := result.CheckLinkage(LinkageStatic); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(libDir, "libcjson.so.1.7.18"), nil, 0644)
+	if err := result.CheckLinkage(LinkageStatic); !errors.Is(err, ErrLinkageMismatch) {
+		t.Errorf("unexpected error for shared library: %v", err)
+	}
+	if err := result.CheckLinkage(LinkageShared); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	headerOnly := &InstallResult{}
+	if err := headerOnly.CheckLinkage(LinkageStatic); err != nil {
+		t.Errorf("unexpected error for header-only package: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrLockfileMissing  = errors.New("lockfile is missing")
+	ErrLockfileOutdated = errors.New("lockfile is out of date")
+)
+
+// Locker is implemented by installers which can pin the resolved dependency graph
+// of a package in a lockfile, so that installs are reproducible.
+// The lockfile lives next to llpkg.cfg.
+type Locker interface {
+	// LockfileName returns the file name of the lockfile, e.g. conan.lock.
+	LockfileName() string
+	// Lock resolves pkg and writes the lockfile to path.
+	Lock(ctx context.Context, pkg Package, path string) error
+	// UseLockfile makes subsequent operations resolve pkg strictly from the lockfile at path.
+	UseLockfile(path string)
+	// CheckLock returns an error wrapping ErrLockfileOutdated if the lockfile at path
+	// doesn't match pkg and the config of the installer any more.
+	CheckLock(ctx context.Context, pkg Package, path string) error
+}
+
+// lockfile returns the Locker of u and the path of its lockfile in dir.
+// A Locker whose LockfileName is empty, e.g. a Fallback, doesn't support lockfiles.
+func (u *Upstream) lockfile(dir string) (Locker, string, bool) {
+	locker, ok := u.Installer.(Locker)
+	if !ok || locker.LockfileName() == "" {
+		return nil, "", false
+	}
+	return locker, filepath.Join(dir, locker.LockfileName()), true
+}
+
+// UseLockfileIn makes the installer of u resolve from the lockfile in dir if it supports lockfiles
+// and the lockfile exists. It returns the path of the lockfile used, or an empty string if none.
+func (u *Upstream) UseLockfileIn(dir string) (string, error) {
+	locker, path, ok := u.lockfile(dir)
+	if !ok {
+		return "", nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	locker.UseLockfile(path)
+	return path, nil
+}
+
+// Lock writes the lockfile of u.Pkg into dir with the install deadline applied and returns its path.
+// It returns an error wrapping errors.ErrUnsupported if the installer isn't a Locker.
+func (u *Upstream) Lock(ctx context.Context, dir string) (string, error) {
+	locker, path, ok := u.lockfile(dir)
+	if !ok {
+		return "", fmt.Errorf("%s: locking: %w", u.Installer.Name(), errors.ErrUnsupported)
+	}
+	ctx, cancel := withTimeout(ctx, u.InstallTimeout, DefaultInstallTimeout)
+	defer cancel()
+	if err := locker.Lock(ctx, u.Pkg, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// CheckLock checks that the lockfile in dir exists and is up to date.
+// Installers which don't support lockfiles always pass.
+func (u *Upstream) CheckLock(ctx context.Context, dir string) error {
+	locker, path, ok := u.lockfile(dir)
+	if !ok {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrLockfileMissing, path)
+		}
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, u.InstallTimeout, DefaultInstallTimeout)
+	defer cancel()
+	return locker.CheckLock(ctx, u.Pkg, path)
+}
@@ -0,0 +1,82 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// lockingInstaller is a Locker whose lockfile is the version of the locked package.
+type lockingInstaller struct {
+	fakeInstaller
+	lockfile string
+}
+
+func (l *lockingInstaller) Install(ctx context.Context, pkg Package, outputDir string) (*InstallResult, error) {
+	return &InstallResult{PkgConfigName: pkg.Name}, nil
+}
+
+func (l *lockingInstaller) Search(ctx context.Context, pkg Package) ([]SearchResult, error) {
+	return nil, nil
+}
+
+func (l *lockingInstaller) LockfileName() string    { return "fake.lock" }
+func (l *lockingInstaller) UseLockfile(path string) { l.lockfile = path }
+
+func (l *lockingInstaller) Lock(ctx context.Context, pkg Package, path string) error {
+	return os.WriteFile(path, []byte(pkg.Version), 0644)
+}
+
+func (l *lockingInstaller) CheckLock(ctx context.Context, pkg Package, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if string(b) != pkg.Version {
+		return ErrLockfileOutdated
+	}
+	return nil
+}
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	locker := &lockingInstaller{}
+	u := &Upstream{Installer: locker, Pkg: Package{Name: "cjson", Version: "1.7.18"}}
+
+	if path, err := u.UseLockfileIn(dir); err != nil || path != "" || locker.lockfile != "" {
+		t.Errorf("unexpected lockfile: %s %v", path, err)
+	}
+	if err := u.CheckLock(context.Background(), dir); !errors.Is(err, ErrLockfileMissing) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	path, err := u.Lock(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "fake.lock") {
+		t.Errorf("unexpected lockfile path: %s", path)
+	}
+	if err := u.CheckLock(context.Background(), dir); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if used, _ := u.UseLockfileIn(dir); used != path || locker.lockfile != path {
+		t.Errorf("lockfile not used: %s", used)
+	}
+
+	u.Pkg.Version = "1.7.19"
+	if err := u.CheckLock(context.Background(), dir); !errors.Is(err, ErrLockfileOutdated) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// installers without lockfile support always pass.
+	u.Installer = FromLegacy(&fakeInstaller{})
+	if err := u.CheckLock(context.Background(), dir); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := u.Lock(context.Background(), dir); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package upstream
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Platform is a target platform as a GOOS/GOARCH pair.
+// An empty GOARCH matches any architecture of GOOS.
+type Platform struct {
+	GOOS   string
+	GOARCH string
+}
+
+// knownOS and knownArch list the GOOS and GOARCH values of the Go toolchain.
+var (
+	knownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "js",
+		"linux", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows",
+	}
+	knownArch = []string{
+		"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle",
+		"ppc64", "ppc64le", "riscv64", "s390x", "wasm",
+	}
+)
+
+// HostPlatform returns the platform llpkgstore runs on.
+func HostPlatform() Platform {
+	return Platform{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+}
+
+// ParsePlatform parses GOOS/GOARCH, or GOOS alone.
+func ParsePlatform(s string) (Platform, error) {
+	goos, goarch, hasArch := strings.Cut(s, "/")
+	p := Platform{GOOS: goos, GOARCH: goarch}
+	if !slices.Contains(knownOS, goos) {
+		return p, fmt.Errorf("invalid platform %q: unknown GOOS %q", s, goos)
+	}
+	if hasArch && !slices.Contains(knownArch, goarch) {
+		return p, fmt.Errorf("invalid platform %q: unknown GOARCH %q", s, goarch)
+	}
+	return p, nil
+}
+
+// String returns the platform as GOOS/GOARCH, or GOOS if GOARCH is empty.
+func (p Platform) String() string {
+	if p.GOARCH == "" {
+		return p.GOOS
+	}
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// Matches reports whether p, which may lack GOARCH, applies to target.
+func (p Platform) Matches(target Platform) bool {
+	return p.GOOS == target.GOOS && (p.GOARCH == "" || p.GOARCH == target.GOARCH)
+}
@@ -0,0 +1,34 @@
+package upstream
+
+import (
+	"context"
+	"io"
+)
+
+// Preparer is implemented by installers which need to set up their environment before use,
+// e.g. detecting the default conan profile and adding the configured remotes.
+// Prepare must be idempotent. Installers keeping what Prepare sets up until they're no longer used,
+// e.g. a private conan home, implement io.Closer as well to remove it, see Upstream.Close.
+type Preparer interface {
+	Prepare(ctx context.Context) error
+}
+
+// Prepare prepares the installer of u with the search deadline applied, if it's a Preparer.
+func (u *Upstream) Prepare(ctx context.Context) error {
+	preparer, ok := u.Installer.(Preparer)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, u.SearchTimeout, DefaultSearchTimeout)
+	defer cancel()
+	return preparer.Prepare(ctx)
+}
+
+// Close releases what the installer of u holds, e.g. the environment Prepare set up, if it's an io.Closer.
+// u must not be used afterwards.
+func (u *Upstream) Close() error {
+	if closer, ok := u.Installer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
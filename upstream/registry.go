@@ -0,0 +1,55 @@
+package upstream
+
+import (
+	"slices"
+	"sync"
+)
+
+// Factory creates an Installer from its installer-specific configuration,
+// i.e. the "config" field of upstream.installer in llpkg.cfg.
+type Factory func(config map[string]string) Installer
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes an installer available by the provided name.
+// Installer packages usually call it in their init function,
+// so linking a package into a binary is enough to enable its installer.
+//
+// If Register is called twice with the same name or if factory is nil, it panics.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("upstream: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("upstream: Register called twice for installer " + name)
+	}
+	registry[name] = factory
+}
+
+// Lookup returns the factory of the installer registered by name.
+func Lookup(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// List returns a sorted list of the names of the registered installers.
+func List() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,60 @@
+package upstream
+
+import (
+	"slices"
+	"testing"
+)
+
+type fakeInstaller struct {
+	config map[string]string
+}
+
+func (f *fakeInstaller) Name() string                                          { return "fake" }
+func (f *fakeInstaller) Config() map[string]string                             { return f.config }
+func (f *fakeInstaller) Install(pkg Package, outputDir string) (string, error) { return pkg.Name, nil }
+func (f *fakeInstaller) Search(pkg Package) ([]string, error)                  { return nil, nil }
+
+func newFakeInstaller(config map[string]string) Installer {
+	return FromLegacy(&fakeInstaller{config: config})
+}
+
+func recoverRegister(name string, factory Factory) (ret any) {
+	defer func() {
+		ret = recover()
+	}()
+	Register(name, factory)
+	return
+}
+
+func TestRegistry(t *testing.T) {
+	Register("registry-test-b", newFakeInstaller)
+	Register("registry-test-a", newFakeInstaller)
+
+	factory, ok := Lookup("registry-test-a")
+	if !ok {
+		t.Fatal("installer not found")
+	}
+	installer := factory(map[string]string{"k": "v"})
+	if installer.Config()["k"] != "v" {
+		t.Errorf("unexpected config: %v", installer.Config())
+	}
+
+	if _, ok := Lookup("registry-test-c"); ok {
+		t.Error("unexpected installer")
+	}
+
+	names := List()
+	if !slices.IsSorted(names) {
+		t.Errorf("unsorted names: %v", names)
+	}
+	if !slices.Contains(names, "registry-test-a") || !slices.Contains(names, "registry-test-b") {
+		t.Errorf("unexpected names: %v", names)
+	}
+
+	if recoverRegister("registry-test-a", newFakeInstaller) == nil {
+		t.Error("duplicate Register should panic")
+	}
+	if recoverRegister("registry-test-nil", nil) == nil {
+		t.Error("nil factory should panic")
+	}
+}
@@ -0,0 +1,117 @@
+package upstream
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// InstallResult describes what an Installer has put into the output directory.
+// All paths are rooted at the outputDir passed to Install.
+type InstallResult struct {
+	// Installer is the name of the installer which installed the package,
+	// the candidate which succeeded for a Fallback.
+	Installer string `json:"installer,omitempty"`
+	// Fallback reports whether a Fallback installed the package with another candidate than its first one,
+	// which a lockfile doesn't pin. Such installs aren't cached.
+	Fallback bool `json:"fallback,omitempty"`
+	// PkgConfigName is the name of the .pc file of the package itself, without the .pc suffix.
+	PkgConfigName string `json:"pkgConfigName"`
+	// PCFiles lists every generated .pc file in the root of outputDir,
+	// including those of dependencies and conan components.
+	PCFiles []string `json:"pcFiles,omitempty"`
+	// IncludeDirs, LibDirs and BinDirs are the installed header, library and executable directories.
+	IncludeDirs []string `json:"includeDirs,omitempty"`
+	LibDirs     []string `json:"libDirs,omitempty"`
+	BinDirs     []string `json:"binDirs,omitempty"`
+	// Reference is the resolved package reference, e.g. cjson/1.7.18.
+	Reference string `json:"reference,omitempty"`
+	// Revision is the resolved revision of the package if the upstream has one, e.g. the conan recipe revision.
+	Revision string `json:"revision,omitempty"`
+	// Dependencies lists the packages the package depends on.
+	Dependencies []Package `json:"dependencies,omitempty"`
+	// Licenses lists the license files shipped with the package.
+	Licenses []string `json:"licenses,omitempty"`
+	// GeneratedFiles lists the files the installer generated for itself which aren't part
+	// of the package and shouldn't be released, e.g. conan environment scripts.
+	GeneratedFiles []string `json:"generatedFiles,omitempty"`
+}
+
+// existingDirs returns the directories among names under root which exist.
+func existingDirs(root string, names ...string) []string {
+	var ret []string
+	for _, name := range names {
+		dir := filepath.Join(root, name)
+		if fs, err := os.Stat(dir); err == nil && fs.IsDir() {
+			ret = append(ret, dir)
+		}
+	}
+	return ret
+}
+
+// isLicenseFile reports whether rel, a slash-separated path relative to the prefix, is a license file.
+func isLicenseFile(rel string) bool {
+	dir, name := path.Split(rel)
+	switch {
+	case strings.HasPrefix(rel, "licenses/"):
+		// conan
+		return true
+	case strings.HasPrefix(dir, "share/") && name == "copyright":
+		// vcpkg: share/<port>/copyright
+		return strings.Count(rel, "/") == 2
+	case dir == "":
+		upper := strings.ToUpper(name)
+		return strings.HasPrefix(upper, "LICENSE") || strings.HasPrefix(upper, "COPYING")
+	}
+	return false
+}
+
+// ScanInstallResult builds an InstallResult for pkg by scanning outputDir, assuming
+// the conan layout: .pc files in the root and an installed prefix (include, lib, bin...).
+// Installers which know more about the installation, like the resolved revision, should fill it in afterwards.
+func ScanInstallResult(pkg Package, pkgConfigName, outputDir string) (*InstallResult, error) {
+	ret := &InstallResult{
+		PkgConfigName: pkgConfigName,
+		Reference:     pkg.Name + "/" + pkg.Version,
+		IncludeDirs:   existingDirs(outputDir, "include"),
+		LibDirs:       existingDirs(outputDir, "lib", "lib64"),
+		BinDirs:       existingDirs(outputDir, "bin"),
+	}
+	pcFiles, err := filepath.Glob(filepath.Join(outputDir, "*.pc"))
+	if err != nil {
+		return nil, err
+	}
+	ret.PCFiles = pcFiles
+
+	err = filepath.WalkDir(outputDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return err
+		}
+		if isLicenseFile(filepath.ToSlash(rel)) {
+			ret.Licenses = append(ret.Licenses, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ret.Licenses)
+	return ret, nil
+}
+
+// PCFile returns the path of the .pc file of the package itself.
+func (r *InstallResult) PCFile() string {
+	for _, f := range r.PCFiles {
+		if filepath.Base(f) == r.PkgConfigName+".pc" {
+			return f
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package upstream
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/goplus/llpkgstore/internal/actions/versions"
+)
+
+// SearchResult is a version of a package found by Installer.Search.
+type SearchResult struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Revision is the latest revision of the version if the upstream has one, e.g. the conan recipe revision.
+	Revision string `json:"revision,omitempty"`
+	// Remote is the remote repository the version was found in, if the upstream has several.
+	Remote string `json:"remote,omitempty"`
+}
+
+// Package returns the package of the result.
+func (r SearchResult) Package() Package {
+	return Package{Name: r.Name, Version: r.Version}
+}
+
+// Reference returns the result as name/version.
+func (r SearchResult) Reference() string {
+	return r.Name + "/" + r.Version
+}
+
+// ParseSearchResult parses a reference like name/version or name/version#revision.
+func ParseSearchResult(ref string) SearchResult {
+	ref, revision, _ := strings.Cut(ref, "#")
+	name, version, _ := strings.Cut(ref, "/")
+	return SearchResult{Name: name, Version: version, Revision: revision}
+}
+
+// SortSearchResults sorts results by name, then from the oldest to the latest C version.
+func SortSearchResults(results []SearchResult) {
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		if cmp := strings.Compare(a.Name, b.Name); cmp != 0 {
+			return cmp
+		}
+		return versions.Compare(a.Version, b.Version)
+	})
+}
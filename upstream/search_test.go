@@ -0,0 +1,31 @@
+package upstream
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchResult(t *testing.T) {
+	r := ParseSearchResult("cjson/1.7.18#6bd3d0a4b1b9ab2a9d6c8c5e0b5e6b9a")
+	if r.Name != "cjson" || r.Version != "1.7.18" || r.Revision != "6bd3d0a4b1b9ab2a9d6c8c5e0b5e6b9a" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if r.Reference() != "cjson/1.7.18" {
+		t.Errorf("unexpected reference: %s", r.Reference())
+	}
+
+	results := []SearchResult{
+		{Name: "cjson", Version: "1.7.18"},
+		{Name: "cjson", Version: "1.7.9"},
+		{Name: "cjson", Version: "1.7.10"},
+		{Name: "cjson", Version: "1.10.0"},
+	}
+	SortSearchResults(results)
+	var vers []string
+	for _, r := range results {
+		vers = append(vers, r.Version)
+	}
+	if !slices.Equal(vers, []string{"1.7.9", "1.7.10", "1.7.18", "1.10.0"}) {
+		t.Errorf("unexpected order: %v", vers)
+	}
+}
@@ -0,0 +1,30 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Targeter is implemented by installers which can install packages for another platform than the host,
+// e.g. conan and vcpkg, which select the binaries of the target platform by their settings or triplet.
+type Targeter interface {
+	// Target makes subsequent operations install packages for platform.
+	// It returns an error if the installer doesn't support platform.
+	Target(platform Platform) error
+}
+
+// Target configures the installer of u for u.TargetPlatform.
+// Installers install for the host by default, so it's a no-op for the host.
+// For another platform, it returns an error wrapping errors.ErrUnsupported if the installer isn't a Targeter,
+// as it would install binaries of the host instead.
+func (u *Upstream) Target() error {
+	platform := u.TargetPlatform()
+	if platform == HostPlatform() {
+		return nil
+	}
+	targeter, ok := u.Installer.(Targeter)
+	if !ok {
+		return fmt.Errorf("%s: installing for %s: %w", u.Installer.Name(), platform, errors.ErrUnsupported)
+	}
+	return targeter.Target(platform)
+}
@@ -0,0 +1,51 @@
+package upstream
+
+import (
+	"errors"
+	"testing"
+)
+
+// targetInstaller is a flakyInstaller which can install for other platforms.
+type targetInstaller struct {
+	flakyInstaller
+	platform Platform
+}
+
+func (t *targetInstaller) Target(platform Platform) error {
+	t.platform = platform
+	return nil
+}
+
+func TestUpstreamTarget(t *testing.T) {
+	arm64 := Platform{GOOS: "linux", GOARCH: "arm64"}
+	if HostPlatform() == arm64 {
+		arm64.GOARCH = "amd64"
+	}
+
+	// installing for the host needs no support
+	u := &Upstream{Installer: &flakyInstaller{name: "host"}}
+	if err := u.Target(); err != nil {
+		t.Error(err)
+	}
+	u.Platform = arm64
+	if err := u.Target(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	installer := &targetInstaller{flakyInstaller: flakyInstaller{name: "cross"}}
+	u.Installer = installer
+	if err := u.Target(); err != nil || installer.platform != arm64 {
+		t.Errorf("unexpected target: %v, %v", installer.platform, err)
+	}
+
+	// a fallback can't install for another platform if any candidate can't
+	u.Installer = NewFallback(Candidate{Installer: installer}, Candidate{Installer: &flakyInstaller{name: "mirror"}})
+	if err := u.Target(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	mirror := &targetInstaller{flakyInstaller: flakyInstaller{name: "mirror"}}
+	u.Installer = NewFallback(Candidate{Installer: installer}, Candidate{Installer: mirror})
+	if err := u.Target(); err != nil || mirror.platform != arm64 {
+		t.Errorf("unexpected target: %v, %v", mirror.platform, err)
+	}
+}
@@ -1,5 +1,18 @@
 package upstream
 
+import (
+	"context"
+	"time"
+)
+
+// Default per-operation deadlines used by Upstream.Install and Upstream.Search, zero meaning none.
+// Installs have no deadline by default as building from source may take long,
+// the whole command is bounded by the --timeout flag of llpkgstore instead.
+var (
+	DefaultInstallTimeout time.Duration
+	DefaultSearchTimeout  = 2 * time.Minute
+)
+
 // Upstream represents a binary package and its installation configuration.
 // It encapsulates an Installer responsible for the installation process and a Package
 // defining the target library's metadata. The Installer uses the Package's details
@@ -9,11 +22,74 @@ type Upstream struct {
 	Installer Installer
 	// Pkg defines the target library's metadata including name and version required for installation
 	Pkg Package
+	// InstallTimeout and SearchTimeout bound a single Install or Search call.
+	// Zero means DefaultInstallTimeout and DefaultSearchTimeout, a negative value disables the deadline.
+	InstallTimeout time.Duration
+	SearchTimeout  time.Duration
+	// Platform is the platform the package is installed for, the zero value meaning HostPlatform.
+	// The installer is configured for it by Target, which config.NewUpstreamForPlatform calls.
+	Platform Platform
 }
 
+// Linkages supported by Package.Linkage.
+const (
+	LinkageShared = "shared"
+	LinkageStatic = "static"
+)
+
 // Package defines the metadata required to identify and install a software library.
 // The Name and Version fields provide precise identification of the library.
 type Package struct {
-	Name    string
-	Version string
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Linkage selects the kind of libraries to install, LinkageShared or LinkageStatic.
+	// Empty means LinkageShared.
+	Linkage string `json:"linkage,omitempty"`
+}
+
+// Static reports whether the package should be installed as static libraries.
+func (p Package) Static() bool {
+	return p.Linkage == LinkageStatic
+}
+
+// TargetPlatform returns u.Platform, or HostPlatform if it isn't set.
+func (u *Upstream) TargetPlatform() Platform {
+	if u.Platform == (Platform{}) {
+		return HostPlatform()
+	}
+	return u.Platform
+}
+
+// withTimeout derives a context from ctx with the deadline d, falling back to def if d is zero.
+// There is no deadline if the resulting duration isn't positive.
+func withTimeout(ctx context.Context, d, def time.Duration) (context.Context, context.CancelFunc) {
+	if d == 0 {
+		d = def
+	}
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// Install installs u.Pkg into outputDir with the install deadline applied.
+// The result records the installer in its Installer field.
+func (u *Upstream) Install(ctx context.Context, outputDir string) (*InstallResult, error) {
+	ctx, cancel := withTimeout(ctx, u.InstallTimeout, DefaultInstallTimeout)
+	defer cancel()
+	result, err := u.Installer.Install(ctx, u.Pkg, outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if result.Installer == "" {
+		result.Installer = u.Installer.Name()
+	}
+	return result, nil
+}
+
+// Search searches u.Pkg with the search deadline applied.
+func (u *Upstream) Search(ctx context.Context) ([]SearchResult, error) {
+	ctx, cancel := withTimeout(ctx, u.SearchTimeout, DefaultSearchTimeout)
+	defer cancel()
+	return u.Installer.Search(ctx, u.Pkg)
 }